@@ -80,7 +80,7 @@ func (db *DB) createTables() error {
 		`CREATE TABLE IF NOT EXISTS orders (
 			id TEXT PRIMARY KEY,
 			customer_id TEXT NOT NULL,
-			total_amount REAL NOT NULL,
+			total_amount BIGINT NOT NULL,
 			status TEXT NOT NULL DEFAULT 'pending',
 			created_at TIMESTAMPTZ DEFAULT NOW(),
 			updated_at TIMESTAMPTZ DEFAULT NOW(),
@@ -91,16 +91,16 @@ func (db *DB) createTables() error {
 			order_id TEXT NOT NULL,
 			menu_item_id TEXT NOT NULL,
 			name TEXT NOT NULL,
-			price REAL NOT NULL,
+			price BIGINT NOT NULL,
 			quantity INTEGER NOT NULL,
-			total_price REAL NOT NULL,
+			total_price BIGINT NOT NULL,
 			FOREIGN KEY (order_id) REFERENCES orders(id),
 			FOREIGN KEY (menu_item_id) REFERENCES menu_items(id)
 		)`,
 		`CREATE TABLE IF NOT EXISTS payments (
 			id TEXT PRIMARY KEY,
 			order_id TEXT NOT NULL,
-			amount REAL NOT NULL,
+			amount BIGINT NOT NULL,
 			method TEXT NOT NULL,
 			status TEXT NOT NULL DEFAULT 'pending',
 			transaction_id TEXT,
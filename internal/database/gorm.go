@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"log"
 
 	"restaurant-system/internal/models"
@@ -15,8 +16,11 @@ func NewGorm(pgURL string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := convertMoneyColumns(db); err != nil {
+		return nil, err
+	}
 	if err := db.AutoMigrate(
-		&models.MenuCategory{}, &models.MenuItemGorm{}, &models.MenuVariant{}, &models.MenuAddon{},
+		&models.MenuCategory{}, &models.MenuVariant{}, &models.MenuAddon{},
 		&models.UserRole{}, &models.InventoryItem{}, &models.InventoryAdjustment{},
 		&models.StaffAssignment{}, &models.OrderAudit{}, &models.Discount{}, &models.DiscountUsage{},
 		&models.LoyaltyAccount{}, &models.LoyaltyTransaction{}, &models.Restaurant{},
@@ -29,3 +33,35 @@ func NewGorm(pgURL string) (*gorm.DB, error) {
 	log.Println("GORM AutoMigrate completed for menu models")
 	return db, nil
 }
+
+// gormMoneyColumns are the AutoMigrated money columns that may still hold major units
+var gormMoneyColumns = [][2]string{
+	{"payment_tips", "amount"}, {"discount_usages", "amount"},
+	{"restaurants", "rounding_increment"}, {"restaurants", "delivery_fee"},
+	{"telebirr_orders", "amount"}, {"telebirr_notifications", "total_amount"},
+	{"telebirr_c2_b_orders", "total_amount"}, {"telebirr_c2_b_notifications", "total_amount"},
+}
+
+// convertMoneyColumns moves money columns still in major units to minor units, as migration 005
+// does. It must run before AutoMigrate, which would cast them to bigint and round away the cents,
+// leaving 005 nothing to convert.
+func convertMoneyColumns(db *gorm.DB) error {
+	for _, c := range gormMoneyColumns {
+		var dataType string
+		if err := db.Raw(`SELECT data_type FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`, c[0], c[1]).Scan(&dataType).Error; err != nil {
+			return err
+		}
+		switch dataType {
+		case "real", "double precision", "numeric":
+		default:
+			continue
+		}
+		stmt := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE BIGINT USING ROUND(%q * 100)::BIGINT`, c[0], c[1], c[1])
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("convert %s.%s to minor units: %w", c[0], c[1], err)
+		}
+		log.Printf("converted %s.%s to minor units", c[0], c[1])
+	}
+	return nil
+}
@@ -71,3 +71,49 @@ func (h *AccountHandler) GetAccountBalance(c *gin.Context) {
 
 	c.JSON(http.StatusOK, balance)
 }
+
+// GetPreferences godoc
+// @Summary Get allergy and dietary preferences
+// @Description Allergies and dietary needs saved on the authenticated customer's account; orders containing a saved allergen must be acknowledged
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AccountPreferences
+// @Failure 404 {object} models.ErrorResponse
+// @Router /me/preferences [get]
+func (h *AccountHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.accountService.GetPreferences(c.GetString("account_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Update allergy and dietary preferences
+// @Description Replace the allergies and dietary needs saved on the authenticated customer's account
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AccountPreferences true "Preferences"
+// @Success 200 {object} models.AccountPreferences
+// @Failure 400 {object} models.ErrorResponse
+// @Router /me/preferences [put]
+func (h *AccountHandler) UpdatePreferences(c *gin.Context) {
+	var req models.AccountPreferences
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.accountService.UpdatePreferences(c.GetString("account_id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,12 +13,14 @@ import (
 )
 
 type EnterpriseAPI struct {
-	db *gorm.DB
-	ws interface{ Broadcast(v interface{}) }
+	db     *gorm.DB
+	ws     interface{ Broadcast(v interface{}) }
+	orders *services.OrderSQLService
 }
 
-func NewEnterpriseAPI(db *gorm.DB, ws interface{ Broadcast(v interface{}) }) *EnterpriseAPI {
-	return &EnterpriseAPI{db: db, ws: ws}
+// NewEnterpriseAPI serves the enterprise endpoints; order lifecycle endpoints use orders
+func NewEnterpriseAPI(db *gorm.DB, ws interface{ Broadcast(v interface{}) }, orders *services.OrderSQLService) *EnterpriseAPI {
+	return &EnterpriseAPI{db: db, ws: ws, orders: orders}
 }
 
 // GetAccount godoc
@@ -347,32 +350,41 @@ func (h *EnterpriseAPI) ListStaffAssignments(c *gin.Context) {
 
 // SplitOrder godoc
 // @Summary Split an order
-// @Description Split an order into multiple orders
+// @Description Split an order by item, by quantity within a line, or evenly between guests
 // @Tags enterprise
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Order ID"
-// @Success 200 {object} map[string]string
+// @Param request body models.SplitOrderRequest true "Split request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /orders/{id}/split [post]
 func (h *EnterpriseAPI) SplitOrder(c *gin.Context) {
-	// Placeholder - would implement order splitting logic
-	c.JSON(http.StatusOK, gin.H{"message": "order_split_initiated"})
+	splitOrder(c, h.orders, h.ws, "", c.Param("id"))
 }
 
 // MergeOrders godoc
 // @Summary Merge orders
-// @Description Merge multiple orders into one
+// @Description Merge open orders from the same session into this order
 // @Tags enterprise
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path string true "Order ID"
-// @Success 200 {object} map[string]string
+// @Param id path string true "Target order ID"
+// @Param request body models.MergeOrdersRequest true "Orders to merge"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /orders/{id}/merge [post]
 func (h *EnterpriseAPI) MergeOrders(c *gin.Context) {
-	// Placeholder - would implement order merging logic
-	c.JSON(http.StatusOK, gin.H{"message": "orders_merged"})
+	var req models.MergeOrdersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mergeOrders(c, h.orders, h.ws, "", c.Param("id"), req.OrderIDs)
 }
 
 // AddTipToPayment godoc
@@ -389,7 +401,7 @@ func (h *EnterpriseAPI) MergeOrders(c *gin.Context) {
 // @Router /payments/{id}/tip [post]
 func (h *EnterpriseAPI) AddTipToPayment(c *gin.Context) {
 	var req struct {
-		Amount float64 `json:"amount" binding:"required"`
+		Amount models.Money `json:"amount" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -546,29 +558,71 @@ func (h *EnterpriseAPI) EarnLoyaltyPoints(c *gin.Context) {
 
 // SalesReport godoc
 // @Summary Get sales report
-// @Description Get sales analytics and reports
+// @Description Order count and sales per currency, using the same filters as the order listing.
+// @Description Only completed orders are counted unless status is given.
 // @Tags enterprise
 // @Produce json
 // @Security BearerAuth
-// @Param range query string false "Date range"
+// @Param status query string false "Comma-separated order statuses"
+// @Param type query string false "Order type (dine_in, takeaway, delivery)"
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param from query string false "Created at or after (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Created before (RFC3339), or through the given day (YYYY-MM-DD)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /reports/sales [get]
 func (h *EnterpriseAPI) SalesReport(c *gin.Context) {
-	// Placeholder - would implement sales analytics
-	c.JSON(http.StatusOK, gin.H{"total_sales": 50000, "period": c.Query("range")})
+	f, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(f.Statuses) == 0 {
+		f.Statuses = []models.OrderStatus{models.OrderStatusCompleted}
+	}
+	// bill shares would count their parent's sales twice
+	f.ExcludeBillShares = true
+	totals, err := h.orders.SummarizeOrders(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"totals": totals, "from": f.From, "to": f.To})
 }
 
 // PopularItemsReport godoc
 // @Summary Get popular items report
-// @Description Get most popular menu items
+// @Description Quantity and revenue per menu item and currency, best sellers first, using the same
+// @Description filters as the sales report. A combo's price is allocated across its components.
 // @Tags enterprise
 // @Produce json
 // @Security BearerAuth
+// @Param status query string false "Comma-separated order statuses"
+// @Param type query string false "Order type (dine_in, takeaway, delivery)"
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param from query string false "Created at or after (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Created before (RFC3339), or through the given day (YYYY-MM-DD)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /reports/popular-items [get]
 func (h *EnterpriseAPI) PopularItemsReport(c *gin.Context) {
-	// Placeholder - would implement popular items analytics
-	c.JSON(http.StatusOK, gin.H{"popular_items": []string{"burger", "pizza"}})
+	f, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(f.Statuses) == 0 {
+		f.Statuses = []models.OrderStatus{models.OrderStatusCompleted}
+	}
+	f.ExcludeBillShares = true
+	items, err := h.orders.ItemSales(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"popular_items": items, "from": f.From, "to": f.To})
 }
 
 // TopCustomersReport godoc
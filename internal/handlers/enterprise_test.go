@@ -23,7 +23,7 @@ func setupTestDB() *gorm.DB {
 
 func TestCreateInventoryItem(t *testing.T) {
 	db := setupTestDB()
-	api := NewEnterpriseAPI(db, nil)
+	api := NewEnterpriseAPI(db, nil, nil)
 	
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -54,7 +54,7 @@ func TestCreateInventoryItem(t *testing.T) {
 
 func TestAssignRole(t *testing.T) {
 	db := setupTestDB()
-	api := NewEnterpriseAPI(db, nil)
+	api := NewEnterpriseAPI(db, nil, nil)
 	
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
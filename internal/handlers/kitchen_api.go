@@ -1,43 +1,103 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 )
 
 type KitchenAPI struct {
 	svc *services.OrderSQLService
+	hub *websocket.Hub
 }
 
-func NewKitchenAPI(svc *services.OrderSQLService) *KitchenAPI { return &KitchenAPI{svc: svc} }
+func NewKitchenAPI(svc *services.OrderSQLService, hub *websocket.Hub) *KitchenAPI {
+	return &KitchenAPI{svc: svc, hub: hub}
+}
+
+// stationBroadcaster delivers messages to the displays of one kitchen station
+type stationBroadcaster interface {
+	BroadcastToStation(stationID string, v interface{})
+}
+
+// broadcastTickets sends an order's fired, due tickets to their stations; only limits it to the
+// given ticket IDs. Held courses are sent when fired and future pickups appear in the polled
+// ticket list once due; failures are dropped for the same reason.
+func broadcastTickets(ctx context.Context, hub stationBroadcaster, svc *services.OrderSQLService, orderID, event string, only map[string]bool) {
+	tickets, err := svc.DueOrderTickets(ctx, orderID)
+	if err != nil {
+		return
+	}
+	for i := range tickets {
+		t := &tickets[i]
+		if only != nil && !only[t.ID] {
+			continue
+		}
+		hub.BroadcastToStation(t.StationID, struct {
+			Type   string                `json:"type"`
+			Ticket *models.KitchenTicket `json:"ticket"`
+		}{Type: event, Ticket: t})
+	}
+}
 
 // ListPending godoc
 // @Summary List pending kitchen orders
-// @Description Get all pending orders for kitchen
+// @Description Get a page of pending orders for kitchen. Scheduled pickups join the queue
+// @Description shortly before their pickup time; view=scheduled lists the ones still held back.
 // @Tags kitchen
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{}
+// @Param type query string false "Order type (dine_in, takeaway, delivery)"
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param view query string false "scheduled to list future pickups"
+// @Param cursor query string false "Page cursor"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} models.OrderPage
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /kitchen/orders [get]
 func (h *KitchenAPI) ListPending(c *gin.Context) {
-	res, err := h.svc.ListOrders(c.Request.Context())
+	f, err := orderFilterFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := pageFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// even-split bill shares carry no lines for the kitchen
+	f.Statuses = []models.OrderStatus{models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusPreparing}
+	f.ExcludeBillShares = true
+	// orders whose courses are all on hold stay off the board until one is fired
+	f.FiredOnly = true
+	fireBy := time.Now().Add(services.KitchenFireLead)
+	if c.Query("view") == "scheduled" {
+		f.ScheduledAfter = &fireBy
+	} else {
+		f.DueBy = &fireBy
+	}
+	res, err := h.svc.ListOrders(c.Request.Context(), f, page)
+	if err != nil {
+		c.JSON(listErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	// filter pending-like statuses in handler for simplicity
-	var pending []*models.Order
-	for _, o := range res {
-		if o.Status == models.OrderStatusPending || o.Status == models.OrderStatusConfirmed || o.Status == models.OrderStatusPreparing {
-			pending = append(pending, o)
+	for _, o := range res.Orders {
+		// tickets need the fired lines with their variants and add-ons
+		if o.Items, err = h.svc.GetFiredOrderItems(c.Request.Context(), o.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 	}
-	c.JSON(http.StatusOK, gin.H{"orders": pending})
+	c.JSON(http.StatusOK, res)
 }
 
 // UpdateStatus godoc
@@ -51,6 +111,7 @@ func (h *KitchenAPI) ListPending(c *gin.Context) {
 // @Param request body models.UpdateOrderStatusRequest true "Status update"
 // @Success 200 {object} models.Order
 // @@Failure 400 {object} models.ErrorRespons
+// @Failure 409 {object} models.ErrorResponse
 // @Router /kitchen/orders/{id}/status [put]
 func (h *KitchenAPI) UpdateStatus(c *gin.Context) {
 	id := c.Param("id")
@@ -59,10 +120,309 @@ func (h *KitchenAPI) UpdateStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.UpdateOrderStatus(c.Request.Context(), id, body.Status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !body.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status"})
+		return
+	}
+	t := services.OrderTransition{To: body.Status, UserID: c.GetString("account_id"), Reason: body.Reason}
+	if err := h.svc.UpdateOrderStatus(c.Request.Context(), id, t); err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	ord, _ := h.svc.GetOrder(c.Request.Context(), id)
+	if ord != nil {
+		pushETAs(c.Request.Context(), h.hub, h.svc, ord.RestaurantID)
+		ord, _ = h.svc.GetOrder(c.Request.Context(), id)
+	}
 	c.JSON(http.StatusOK, ord)
 }
+
+// ListStations godoc
+// @Summary List kitchen stations
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id query string false "Restaurant ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/stations [get]
+func (h *KitchenAPI) ListStations(c *gin.Context) {
+	stations, err := h.svc.ListStations(c.Request.Context(), c.Query("restaurant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stations": stations})
+}
+
+// CreateStation godoc
+// @Summary Create a kitchen station
+// @Tags kitchen
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateStationRequest true "Station"
+// @Success 201 {object} models.KitchenStation
+// @Failure 400 {object} models.ErrorResponse
+// @Router /kitchen/stations [post]
+func (h *KitchenAPI) CreateStation(c *gin.Context) {
+	var req models.CreateStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.svc.CreateStation(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, st)
+}
+
+// ListStationRoutes godoc
+// @Summary List the menu categories and items routed to a station
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Station ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/stations/{id}/routes [get]
+func (h *KitchenAPI) ListStationRoutes(c *gin.Context) {
+	routes, err := h.svc.ListStationRoutes(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"routes": routes})
+}
+
+// SetStationRoute godoc
+// @Summary Route a menu category or item to a station
+// @Description Replaces any existing route for the same category or item
+// @Tags kitchen
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Station ID"
+// @Param request body models.StationRouteRequest true "Route"
+// @Success 200 {object} models.StationRoute
+// @Failure 400 {object} models.ErrorResponse
+// @Router /kitchen/stations/{id}/routes [put]
+func (h *KitchenAPI) SetStationRoute(c *gin.Context) {
+	var req models.StationRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rt, err := h.svc.SetStationRoute(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+// DeleteStationRoute godoc
+// @Summary Remove a station route
+// @Tags kitchen
+// @Security BearerAuth
+// @Param id path string true "Station ID"
+// @Param route_id path string true "Route ID"
+// @Success 204
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/stations/{id}/routes/{route_id} [delete]
+func (h *KitchenAPI) DeleteStationRoute(c *gin.Context) {
+	if err := h.svc.DeleteStationRoute(c.Request.Context(), c.Param("id"), c.Param("route_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListTickets godoc
+// @Summary List a station's open tickets
+// @Description Queued and in-progress tickets for one station, oldest first. Without station_id, lists tickets no station matched.
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param station_id query string false "Station ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/tickets [get]
+func (h *KitchenAPI) ListTickets(c *gin.Context) {
+	tickets, err := h.svc.ListStationTickets(c.Request.Context(), c.Query("station_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if tickets == nil {
+		tickets = []models.KitchenTicket{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+}
+
+// UpdateTicketStatus godoc
+// @Summary Start or bump a station ticket
+// @Description Starting a ticket moves the order to preparing; bumping the last open ticket marks it ready
+// @Tags kitchen
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ticket ID"
+// @Param request body models.UpdateTicketStatusRequest true "Status"
+// @Success 200 {object} models.KitchenTicket
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /kitchen/tickets/{id}/status [put]
+func (h *KitchenAPI) UpdateTicketStatus(c *gin.Context) {
+	var body models.UpdateTicketStatusRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !body.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown ticket status"})
+		return
+	}
+	t, ready, err := h.svc.UpdateTicketStatus(c.Request.Context(), c.Param("id"), body.Status, c.GetString("account_id"))
+	h.respondTicket(c, t, ready, "ticket_updated", err)
+}
+
+// BumpTicket godoc
+// @Summary Bump a station ticket
+// @Description Mark a ticket done at its station; bumping the last open ticket marks the order ready
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} models.KitchenTicket
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /kitchen/tickets/{id}/bump [post]
+func (h *KitchenAPI) BumpTicket(c *gin.Context) {
+	t, ready, err := h.svc.BumpTicket(c.Request.Context(), c.Param("id"), c.GetString("account_id"))
+	h.respondTicket(c, t, ready, "ticket_bumped", err)
+}
+
+// RecallTicket godoc
+// @Summary Recall a bumped ticket
+// @Description Bring a bumped ticket back to its station as in progress; a ready order goes back to preparing
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} models.KitchenTicket
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /kitchen/tickets/{id}/recall [post]
+func (h *KitchenAPI) RecallTicket(c *gin.Context) {
+	t, err := h.svc.RecallTicket(c.Request.Context(), c.Param("id"), c.GetString("account_id"))
+	h.respondTicket(c, t, false, "ticket_recalled", err)
+}
+
+// respondTicket sends a moved ticket to its station and the order to everyone else
+func (h *KitchenAPI) respondTicket(c *gin.Context, t *models.KitchenTicket, ready bool, event string, err error) {
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if h.hub != nil {
+		h.hub.BroadcastToStation(t.StationID, struct {
+			Type   string                `json:"type"`
+			Ticket *models.KitchenTicket `json:"ticket"`
+		}{Type: event, Ticket: t})
+		if ord, err := h.svc.GetOrder(c.Request.Context(), t.OrderID); err == nil {
+			pushETAs(c.Request.Context(), h.hub, h.svc, ord.RestaurantID)
+			h.hub.Broadcast(struct {
+				Type  string        `json:"type"`
+				Order *models.Order `json:"order"`
+			}{Type: "order_updated", Order: ord})
+			if ready {
+				h.hub.BroadcastToExpo(struct {
+					Type  string        `json:"type"`
+					Order *models.Order `json:"order"`
+				}{Type: "expo_order_ready", Order: ord})
+			}
+		}
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// Expo godoc
+// @Summary Expo view of orders and their station tickets
+// @Description Orders still in the kitchen or waiting at the pass, with every station ticket; ready is true once all tickets are bumped
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param type query string false "Order type (dine_in, takeaway, delivery)"
+// @Param cursor query string false "Page cursor"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/expo [get]
+func (h *KitchenAPI) Expo(c *gin.Context) {
+	f, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := pageFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.Statuses = []models.OrderStatus{models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusPreparing, models.OrderStatusReady}
+	f.ExcludeBillShares = true
+	fireBy := time.Now().Add(services.KitchenFireLead)
+	f.DueBy = &fireBy
+	orders, next, err := h.svc.ExpoOrders(c.Request.Context(), f, page)
+	if err != nil {
+		c.JSON(listErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": orders, "next_cursor": next})
+}
+
+// Metrics godoc
+// @Summary Kitchen performance
+// @Description Average ticket and prep times, tickets bumped after their ETA, recalls and hourly throughput per station for tickets bumped in the range. Defaults to the last 24 hours.
+// @Tags kitchen
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param from query string false "Start (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "End (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {object} models.KitchenMetrics
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kitchen/metrics [get]
+func (h *KitchenAPI) Metrics(c *gin.Context) {
+	to := time.Now()
+	if t, err := parseQueryTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	} else if t != nil {
+		to = *t
+	}
+	from := to.Add(-24 * time.Hour)
+	if t, err := parseQueryTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	} else if t != nil {
+		from = *t
+	}
+	m, err := h.svc.KitchenMetrics(c.Request.Context(), c.Query("restaurant_id"), from, to)
+	if errors.Is(err, services.ErrMetricsRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
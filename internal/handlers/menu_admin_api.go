@@ -36,8 +36,12 @@ func (h *MenuAdminAPI) CreateItem(c *gin.Context) {
 	if body.ID == "" {
 		body.ID = uuid.New().String()
 	}
-	if err := h.svc.CreateItem(c.Request.Context(), &body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateItem(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, body)
@@ -45,7 +49,7 @@ func (h *MenuAdminAPI) CreateItem(c *gin.Context) {
 
 // UpdateItem godoc
 // @Summary Update menu item (admin)
-// @Description Update menu item details (admin route)
+// @Description Update the menu item fields sent; the others keep their values (admin route)
 // @Tags menu-admin
 // @Accept json
 // @Produce json
@@ -56,18 +60,24 @@ func (h *MenuAdminAPI) CreateItem(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/item/{id} [put]
 func (h *MenuAdminAPI) UpdateItem(c *gin.Context) {
-	id := c.Param("id")
-	var body models.MenuItem
-	if err := c.ShouldBindJSON(&body); err != nil {
+	body, err := c.GetRawData()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	body.ID = id
-	if err := h.svc.UpdateItem(c.Request.Context(), &body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	var it *models.MenuItem
+	_, err = h.svc.EditEntryDraft(ctx, services.MenuEntryItem, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		var err error
+		// only the fields sent are changed
+		it, err = d.PatchItem(ctx, id, body)
+		return err
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, body)
+	c.JSON(http.StatusOK, it)
 }
 
 // DeleteItem godoc
@@ -81,9 +91,12 @@ func (h *MenuAdminAPI) UpdateItem(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/item/{id} [delete]
 func (h *MenuAdminAPI) DeleteItem(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.svc.DeleteItem(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryItem, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteItem(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -16,23 +20,159 @@ func NewMenuAPI(svc *services.MenuSQLService) *MenuAPI { return &MenuAPI{svc: sv
 
 // GetQRMenu godoc
 // @Summary Get QR menu
-// @Description Get menu for a specific restaurant table via QR code
+// @Description Get a branch's menu, at its prices and in its currency, for one of its tables via QR code.
+// @Description Text is shown in the first requested language it is translated to, falling back from
+// @Description regional to base languages and finally to the menu's own text.
 // @Tags menu
 // @Produce json
 // @Param restaurant_id path string true "Restaurant ID"
 // @Param table_id path string true "Table ID"
-// @Param lang query string false "Language code"
+// @Param lang query string false "Language code, tried before Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param allergens query string false "Comma-separated allergens to flag or hide"
+// @Param allergen_mode query string false "flag (default) or hide"
+// @Param dietary query string false "Comma-separated dietary tags every item must carry"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /restaurant/{restaurant_id}/table/{table_id}/menu [get]
 func (h *MenuAPI) GetQRMenu(c *gin.Context) {
 	restaurantID := c.Param("restaurant_id")
 	tableID := c.Param("table_id")
-	lang := c.Query("lang")
-	cats, err := h.svc.GetQRMenu(c.Request.Context(), restaurantID, tableID, lang)
+	tags, err := menuTagFilterFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"categories": cats})
+	menu, err := h.svc.GetQRMenu(c.Request.Context(), restaurantID, tableID, menuLocales(c), tags)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrMenuNotFound) || errors.Is(err, services.ErrTableNotInRestaurant) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, menu)
+}
+
+// menuLocales is the locale chain a menu reader asked for: the lang query, then Accept-Language
+func menuLocales(c *gin.Context) []string {
+	prefs := append([]string{c.Query("lang")}, models.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+	return models.LocaleChain(prefs...)
+}
+
+// SearchMenu godoc
+// @Summary Search QR menu
+// @Description Search a branch's menu by item name, description or category in any language, forgiving
+// @Description typos, with facets for category, price, dietary tags and availability. Favorites and
+// @Description well-rated items rank higher; an empty q lists the whole menu by popularity.
+// @Tags menu
+// @Produce json
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param table_id path string true "Table ID"
+// @Param q query string false "Search text"
+// @Param category query string false "Comma-separated category IDs"
+// @Param min_price query number false "Lowest price"
+// @Param max_price query number false "Highest price"
+// @Param available query bool false "Only available (true) or unavailable (false) items"
+// @Param dietary query string false "Comma-separated dietary tags every item must carry"
+// @Param allergens query string false "Comma-separated allergens to flag or hide"
+// @Param allergen_mode query string false "flag (default) or hide"
+// @Param limit query int false "Hits to return (default 20, max 100)"
+// @Param lang query string false "Language code, tried before Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Success 200 {object} services.MenuSearchResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /restaurant/{restaurant_id}/table/{table_id}/menu/search [get]
+func (h *MenuAPI) SearchMenu(c *gin.Context) {
+	search, err := menuSearchFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.svc.SearchMenu(c.Request.Context(), c.Param("restaurant_id"), c.Param("table_id"), menuLocales(c), search)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrMenuNotFound) || errors.Is(err, services.ErrTableNotInRestaurant) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, res)
+}
+
+func menuSearchFromQuery(c *gin.Context) (services.MenuSearch, error) {
+	s := services.MenuSearch{Query: c.Query("q"), CategoryIDs: splitList(c.Query("category"))}
+	var err error
+	if s.Tags, err = menuTagFilterFromQuery(c); err != nil {
+		return s, err
+	}
+	if s.MinPrice, err = queryPrice(c, "min_price"); err != nil {
+		return s, err
+	}
+	if s.MaxPrice, err = queryPrice(c, "max_price"); err != nil {
+		return s, err
+	}
+	if v := c.Query("available"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, errors.New("available must be true or false")
+		}
+		s.Available = &b
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return s, errors.New("limit must be a positive integer")
+		}
+		s.Limit = n
+	}
+	return s, nil
+}
+
+func queryPrice(c *gin.Context, name string) (*float64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return nil, errors.New(name + " must be a non-negative number")
+	}
+	return &f, nil
+}
+
+func menuTagFilterFromQuery(c *gin.Context) (services.MenuTagFilter, error) {
+	var f services.MenuTagFilter
+	for _, v := range splitList(c.Query("allergens")) {
+		f.Allergens = append(f.Allergens, models.Allergen(v))
+	}
+	for _, v := range splitList(c.Query("dietary")) {
+		f.Dietary = append(f.Dietary, models.DietaryTag(v))
+	}
+	switch c.Query("allergen_mode") {
+	case "", "flag":
+	case "hide":
+		f.HideAllergens = true
+	default:
+		return f, errors.New("allergen_mode must be flag or hide")
+	}
+	return f, models.ValidateTags(f.Allergens, f.Dietary)
+}
+
+// splitList splits a comma-separated query value, dropping blanks
+func splitList(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
 }
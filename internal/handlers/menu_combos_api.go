@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateCombo godoc
+// @Summary Create combo
+// @Description Create a combo meal from slots, each filled with one of its menu item choices
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Combo true "Combo"
+// @Success 201 {object} models.Combo
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/combos [post]
+func (h *MenuManagementAPI) CreateCombo(c *gin.Context) {
+	var body models.Combo
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.ID == "" {
+		body.ID = uuid.New().String()
+	}
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateCombo(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// GetCombo godoc
+// @Summary Get combo
+// @Tags menu
+// @Produce json
+// @Param id path string true "Combo ID"
+// @Success 200 {object} models.Combo
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/combos/{id} [get]
+func (h *MenuManagementAPI) GetCombo(c *gin.Context) {
+	combo, err := h.svc.GetCombo(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, combo)
+}
+
+// ListCombos godoc
+// @Summary List combos
+// @Description List a restaurant's combos; set available=true for only those on sale
+// @Tags menu
+// @Produce json
+// @Param restaurant_id query string true "Restaurant ID"
+// @Param available query bool false "Only combos on sale"
+// @Success 200 {array} models.Combo
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/combos [get]
+func (h *MenuManagementAPI) ListCombos(c *gin.Context) {
+	rid := c.Query("restaurant_id")
+	if rid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "restaurant_id required"})
+		return
+	}
+	list, err := h.svc.ListCombos(c.Request.Context(), rid, c.Query("available") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateCombo godoc
+// @Summary Update combo
+// @Description Replace a combo's details and slots; orders already placed keep what they were sold
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Combo ID"
+// @Param request body models.Combo true "Combo"
+// @Success 200 {object} models.Combo
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/combos/{id} [put]
+func (h *MenuManagementAPI) UpdateCombo(c *gin.Context) {
+	var body models.Combo
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.ID = c.Param("id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryCombo, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateCombo(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// DeleteCombo godoc
+// @Summary Delete combo
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Combo ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/combos/{id} [delete]
+func (h *MenuManagementAPI) DeleteCombo(c *gin.Context) {
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryCombo, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteCombo(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
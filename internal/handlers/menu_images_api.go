@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuImagesAPI struct {
+	svc *services.MenuImageService
+	ws  interface{ Broadcast(v interface{}) }
+}
+
+func NewMenuImagesAPI(svc *services.MenuImageService, ws interface{ Broadcast(v interface{}) }) *MenuImagesAPI {
+	return &MenuImagesAPI{svc: svc, ws: ws}
+}
+
+// readImageUpload reads the "image" file of a multipart upload, refusing oversized files
+func readImageUpload(c *gin.Context) ([]byte, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxMenuImageBytes+1<<20)
+	fh, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image file required"})
+		return nil, false
+	}
+	if fh.Size > services.MaxMenuImageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image larger than " + strconv.Itoa(services.MaxMenuImageBytes>>20) + " MB"})
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return data, true
+}
+
+// UploadItemImage godoc
+// @Summary Upload menu item image
+// @Description Upload a JPEG, PNG or GIF of up to 10 MB. It is stored as thumbnail, card and full sizes
+// @Description with metadata removed, and the full size becomes the item's image_url.
+// @Tags menu
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param image formData file true "Image"
+// @Success 200 {object} models.ImageVariants
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Router /menu/items/{id}/image [post]
+func (h *MenuImagesAPI) UploadItemImage(c *gin.Context) {
+	data, ok := readImageUpload(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	images, err := h.svc.SetItemImage(c.Request.Context(), id, data)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.ws.Broadcast(gin.H{"type": "menu.image", "item_id": id, "image_variants": images})
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteItemImage godoc
+// @Summary Delete menu item image
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/items/{id}/image [delete]
+func (h *MenuImagesAPI) DeleteItemImage(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.DeleteItemImage(c.Request.Context(), id); err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.ws.Broadcast(gin.H{"type": "menu.image", "item_id": id, "image_variants": models.ImageVariants{}})
+	c.Status(http.StatusNoContent)
+}
+
+// UploadCategoryImage godoc
+// @Summary Upload menu category image
+// @Description Upload a JPEG, PNG or GIF of up to 10 MB, stored like an item image
+// @Tags menu
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param image formData file true "Image"
+// @Success 200 {object} models.ImageVariants
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Router /menu/categories/{id}/image [post]
+func (h *MenuImagesAPI) UploadCategoryImage(c *gin.Context) {
+	data, ok := readImageUpload(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	images, err := h.svc.SetCategoryImage(c.Request.Context(), id, data)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.ws.Broadcast(gin.H{"type": "menu.image", "category_id": id, "image_variants": images})
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteCategoryImage godoc
+// @Summary Delete menu category image
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/categories/{id}/image [delete]
+func (h *MenuImagesAPI) DeleteCategoryImage(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.DeleteCategoryImage(c.Request.Context(), id); err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.ws.Broadcast(gin.H{"type": "menu.image", "category_id": id, "image_variants": models.ImageVariants{}})
+	c.Status(http.StatusNoContent)
+}
@@ -1,28 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"restaurant-system/internal/auth"
 	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
+// MenuManagementAPI edits the same menu the QR menu and ordering read. Changes to a branch's
+// menu are made in its working draft and go live when the draft is published; availability
+// and images change at once, and publishing keeps them as they are live.
 type MenuManagementAPI struct {
-	gorm *gorm.DB
-	ws   interface{ Broadcast(v interface{}) }
+	svc *services.MenuSQLService
+	ws  interface{ Broadcast(v interface{}) }
 }
 
-func NewMenuManagementAPI(gdb *gorm.DB, ws interface{ Broadcast(v interface{}) }) *MenuManagementAPI {
-	return &MenuManagementAPI{gorm: gdb, ws: ws}
+func NewMenuManagementAPI(svc *services.MenuSQLService, ws interface{ Broadcast(v interface{}) }) *MenuManagementAPI {
+	return &MenuManagementAPI{svc: svc, ws: ws}
+}
+
+func menuErrorStatus(err error) int {
+	if errors.Is(err, services.ErrMenuNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
 }
 
 // CreateCategory godoc
 // @Summary Create menu category
-// @Description Create a new menu category
+// @Description Create a menu category in its branch's working draft
 // @Tags menu
 // @Accept json
 // @Produce json
@@ -40,8 +51,12 @@ func (h *MenuManagementAPI) CreateCategory(c *gin.Context) {
 	if body.ID == "" {
 		body.ID = uuid.New().String()
 	}
-	if err := h.gorm.Create(&body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateMenuCategory(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, body)
@@ -57,9 +72,9 @@ func (h *MenuManagementAPI) CreateCategory(c *gin.Context) {
 // @Failure 404 {object} models.ErrorResponse
 // @Router /menu/categories/{id} [get]
 func (h *MenuManagementAPI) GetCategory(c *gin.Context) {
-	var cat models.MenuCategory
-	if err := h.gorm.First(&cat, "id = ?", c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	cat, err := h.svc.GetMenuCategory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, cat)
@@ -75,12 +90,8 @@ func (h *MenuManagementAPI) GetCategory(c *gin.Context) {
 // @Failure 500 {object} models.ErrorResponse
 // @Router /menu/categories [get]
 func (h *MenuManagementAPI) ListCategories(c *gin.Context) {
-	var cats []models.MenuCategory
-	q := h.gorm.Model(&models.MenuCategory{})
-	if rid := c.Query("restaurant_id"); rid != "" {
-		q = q.Where("restaurant_id = ?", rid)
-	}
-	if err := q.Order("name asc").Find(&cats).Error; err != nil {
+	cats, err := h.svc.ListMenuCategories(c.Request.Context(), c.Query("restaurant_id"))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -89,7 +100,7 @@ func (h *MenuManagementAPI) ListCategories(c *gin.Context) {
 
 // UpdateCategory godoc
 // @Summary Update menu category
-// @Description Update category details
+// @Description Rename a category or change its schedule; its items and station routes follow the new name
 // @Tags menu
 // @Accept json
 // @Produce json
@@ -105,17 +116,23 @@ func (h *MenuManagementAPI) UpdateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	body.ID = c.Param("id")
-	if err := h.gorm.Model(&models.MenuCategory{ID: body.ID}).Updates(body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	var cat *models.MenuCategory
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryCategory, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		var err error
+		cat, err = d.UpdateMenuCategory(ctx, id, body.Name, body.ScheduleID)
+		return err
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, body)
+	c.JSON(http.StatusOK, cat)
 }
 
 // DeleteCategory godoc
 // @Summary Delete menu category
-// @Description Delete a category
+// @Description Delete a category that no longer has items
 // @Tags menu
 // @Produce json
 // @Security BearerAuth
@@ -124,8 +141,12 @@ func (h *MenuManagementAPI) UpdateCategory(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/categories/{id} [delete]
 func (h *MenuManagementAPI) DeleteCategory(c *gin.Context) {
-	if err := h.gorm.Delete(&models.MenuCategory{}, "id = ?", c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryCategory, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteMenuCategory(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -133,17 +154,17 @@ func (h *MenuManagementAPI) DeleteCategory(c *gin.Context) {
 
 // CreateItem godoc
 // @Summary Create menu item
-// @Description Create a new menu item
+// @Description Create a menu item in its branch's working draft
 // @Tags menu
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body models.MenuItemGorm true "Menu item request"
-// @Success 201 {object} models.MenuItemGorm
+// @Param request body models.MenuItem true "Menu item request"
+// @Success 201 {object} models.MenuItem
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/items [post]
 func (h *MenuManagementAPI) CreateItem(c *gin.Context) {
-	var body models.MenuItemGorm
+	var body models.MenuItem
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -151,8 +172,12 @@ func (h *MenuManagementAPI) CreateItem(c *gin.Context) {
 	if body.ID == "" {
 		body.ID = uuid.New().String()
 	}
-	if err := h.gorm.Create(&body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateItem(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, body)
@@ -164,13 +189,13 @@ func (h *MenuManagementAPI) CreateItem(c *gin.Context) {
 // @Tags menu
 // @Produce json
 // @Param id path string true "Item ID"
-// @Success 200 {object} models.MenuItemGorm
+// @Success 200 {object} models.MenuItem
 // @Failure 404 {object} models.ErrorResponse
 // @Router /menu/items/{id} [get]
 func (h *MenuManagementAPI) GetItem(c *gin.Context) {
-	var it models.MenuItemGorm
-	if err := h.gorm.First(&it, "id = ?", c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	it, err := h.svc.GetItem(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, it)
@@ -187,15 +212,8 @@ func (h *MenuManagementAPI) GetItem(c *gin.Context) {
 // @Failure 500 {object} models.ErrorResponse
 // @Router /menu/items [get]
 func (h *MenuManagementAPI) ListItems(c *gin.Context) {
-	var items []models.MenuItemGorm
-	q := h.gorm.Model(&models.MenuItemGorm{})
-	if rid := c.Query("restaurant_id"); rid != "" {
-		q = q.Where("restaurant_id = ?", rid)
-	}
-	if cid := c.Query("category_id"); cid != "" {
-		q = q.Where("category_id = ?", cid)
-	}
-	if err := q.Order("name asc").Find(&items).Error; err != nil {
+	items, err := h.svc.ListItems(c.Request.Context(), c.Query("restaurant_id"), c.Query("category_id"))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -204,28 +222,37 @@ func (h *MenuManagementAPI) ListItems(c *gin.Context) {
 
 // UpdateItem godoc
 // @Summary Update menu item
-// @Description Update menu item details
+// @Description Update the menu item fields sent in the working draft of the item's branch; the others keep
+// @Description their values. Shared items are changed live. A live item's availability and image are set with their
+// @Description own endpoints; publishing keeps them.
 // @Tags menu
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Item ID"
-// @Param request body models.MenuItemGorm true "Item update"
-// @Success 200 {object} models.MenuItemGorm
+// @Param request body models.MenuItem true "Item update"
+// @Success 200 {object} models.MenuItem
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/items/{id} [put]
 func (h *MenuManagementAPI) UpdateItem(c *gin.Context) {
-	var body models.MenuItemGorm
-	if err := c.ShouldBindJSON(&body); err != nil {
+	body, err := c.GetRawData()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	body.ID = c.Param("id")
-	if err := h.gorm.Model(&models.MenuItemGorm{ID: body.ID}).Updates(body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	var it *models.MenuItem
+	_, err = h.svc.EditEntryDraft(ctx, services.MenuEntryItem, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		var err error
+		// only the fields sent are changed
+		it, err = d.PatchItem(ctx, id, body)
+		return err
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, body)
+	c.JSON(http.StatusOK, it)
 }
 
 // UpdateAvailability godoc
@@ -250,8 +277,8 @@ func (h *MenuManagementAPI) UpdateAvailability(c *gin.Context) {
 		return
 	}
 	id := c.Param("id")
-	if err := h.gorm.Model(&models.MenuItemGorm{}).Where("id = ?", id).Update("available", body.Available).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.svc.SetItemAvailability(c.Request.Context(), id, body.Available); err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	// broadcast availability change
@@ -270,8 +297,12 @@ func (h *MenuManagementAPI) UpdateAvailability(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/items/{id} [delete]
 func (h *MenuManagementAPI) DeleteItem(c *gin.Context) {
-	if err := h.gorm.Delete(&models.MenuItemGorm{}, "id = ?", c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryItem, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteItem(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -299,8 +330,12 @@ func (h *MenuManagementAPI) CreateVariant(c *gin.Context) {
 	if body.ID == "" {
 		body.ID = uuid.New().String()
 	}
-	if err := h.gorm.Create(&body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryItem, body.ItemID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateVariant(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, body)
@@ -325,8 +360,12 @@ func (h *MenuManagementAPI) UpdateVariant(c *gin.Context) {
 		return
 	}
 	body.ID = c.Param("id")
-	if err := h.gorm.Model(&models.MenuVariant{ID: body.ID}).Updates(body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryVariant, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateVariant(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, body)
@@ -343,8 +382,12 @@ func (h *MenuManagementAPI) UpdateVariant(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/variants/{id} [delete]
 func (h *MenuManagementAPI) DeleteVariant(c *gin.Context) {
-	if err := h.gorm.Delete(&models.MenuVariant{}, "id = ?", c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryVariant, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteVariant(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -372,8 +415,12 @@ func (h *MenuManagementAPI) CreateAddon(c *gin.Context) {
 	if body.ID == "" {
 		body.ID = uuid.New().String()
 	}
-	if err := h.gorm.Create(&body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryItem, body.ItemID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateAddon(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, body)
@@ -398,8 +445,12 @@ func (h *MenuManagementAPI) UpdateAddon(c *gin.Context) {
 		return
 	}
 	body.ID = c.Param("id")
-	if err := h.gorm.Model(&models.MenuAddon{ID: body.ID}).Updates(body).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryAddon, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateAddon(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, body)
@@ -416,13 +467,98 @@ func (h *MenuManagementAPI) UpdateAddon(c *gin.Context) {
 // @@Failure 400 {object} models.ErrorRespons
 // @Router /menu/addons/{id} [delete]
 func (h *MenuManagementAPI) DeleteAddon(c *gin.Context) {
-	if err := h.gorm.Delete(&models.MenuAddon{}, "id = ?", c.Param("id")).Error; err != nil {
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryAddon, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteAddon(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SetItemOverride godoc
+// @Summary Set branch price and availability
+// @Description Override a menu item's price and/or availability at one branch; omitted fields follow the item.
+// @Description The price is set in the branch's working draft; the availability also changes at once, like the item's own.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param restaurant_id path string true "Branch (restaurant) ID"
+// @Param request body object{price=number,available=bool} true "Override"
+// @Success 200 {object} models.MenuItemOverride
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/items/{id}/branches/{restaurant_id} [put]
+func (h *MenuManagementAPI) SetItemOverride(c *gin.Context) {
+	var body models.MenuItemOverride
+	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	body.MenuItemID, body.RestaurantID = c.Param("id"), c.Param("restaurant_id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.SetItemOverride(ctx, &body)
+	})
+	if err == nil {
+		err = h.svc.SetBranchAvailability(ctx, body.RestaurantID, body.MenuItemID, body.Available)
+	}
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if body.Available != nil {
+		h.ws.Broadcast(gin.H{"type": "menu.availability", "item_id": body.MenuItemID, "restaurant_id": body.RestaurantID, "available": *body.Available})
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// DeleteItemOverride godoc
+// @Summary Clear branch price and availability
+// @Description Return a branch to the menu item's own price and availability in its working draft
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param restaurant_id path string true "Branch (restaurant) ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/items/{id}/branches/{restaurant_id} [delete]
+func (h *MenuManagementAPI) DeleteItemOverride(c *gin.Context) {
+	ctx, restaurantID, id := c.Request.Context(), c.Param("restaurant_id"), c.Param("id")
+	_, err := h.svc.EditMenuDraft(ctx, restaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteItemOverride(ctx, restaurantID, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
 
+// ListItemOverrides godoc
+// @Summary List branch overrides
+// @Description List a branch's menu price and availability overrides
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Branch (restaurant) ID"
+// @Success 200 {array} models.MenuItemOverride
+// @Failure 500 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/overrides [get]
+func (h *MenuManagementAPI) ListItemOverrides(c *gin.Context) {
+	overrides, err := h.svc.ListItemOverrides(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, overrides)
+}
+
 // Role middleware wrappers
 func RequireAdminOrManager() gin.HandlerFunc { return auth.RequireAnyRole("admin", "manager") }
 func RequireStaff() gin.HandlerFunc {
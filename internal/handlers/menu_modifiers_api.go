@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListModifierGroups godoc
+// @Summary List modifier groups
+// @Description List an item's modifier groups with their options and nested groups
+// @Tags menu
+// @Produce json
+// @Param id path string true "Item ID"
+// @Success 200 {array} models.ModifierGroup
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/items/{id}/modifier-groups [get]
+func (h *MenuManagementAPI) ListModifierGroups(c *gin.Context) {
+	groups, err := h.svc.ListModifierGroups(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// CreateModifierGroup godoc
+// @Summary Create modifier group
+// @Description Add a modifier group to an item; set parent_option_id to nest it under one of the item's options
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Item ID"
+// @Param request body models.ModifierGroup true "Modifier group"
+// @Success 201 {object} models.ModifierGroup
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/items/{id}/modifier-groups [post]
+func (h *MenuManagementAPI) CreateModifierGroup(c *gin.Context) {
+	var body models.ModifierGroup
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.ItemID = c.Param("id")
+	if body.ID == "" {
+		body.ID = uuid.New().String()
+	}
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryItem, body.ItemID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateModifierGroup(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// UpdateModifierGroup godoc
+// @Summary Update modifier group
+// @Description Change a group's name, selection rules and position
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.ModifierGroup true "Modifier group"
+// @Success 200 {object} models.ModifierGroup
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/modifier-groups/{id} [put]
+func (h *MenuManagementAPI) UpdateModifierGroup(c *gin.Context) {
+	var body models.ModifierGroup
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.ID = c.Param("id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryModifierGroup, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateModifierGroup(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// DeleteModifierGroup godoc
+// @Summary Delete modifier group
+// @Description Delete a group with its options and the groups nested under them
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/modifier-groups/{id} [delete]
+func (h *MenuManagementAPI) DeleteModifierGroup(c *gin.Context) {
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryModifierGroup, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteModifierGroup(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateModifierOption godoc
+// @Summary Create modifier option
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.ModifierOption true "Modifier option"
+// @Success 201 {object} models.ModifierOption
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/modifier-groups/{id}/options [post]
+func (h *MenuManagementAPI) CreateModifierOption(c *gin.Context) {
+	var body models.ModifierOption
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.GroupID = c.Param("id")
+	if body.ID == "" {
+		body.ID = uuid.New().String()
+	}
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryModifierGroup, body.GroupID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateModifierOption(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// UpdateModifierOption godoc
+// @Summary Update modifier option
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Option ID"
+// @Param request body models.ModifierOption true "Modifier option"
+// @Success 200 {object} models.ModifierOption
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/modifier-options/{id} [put]
+func (h *MenuManagementAPI) UpdateModifierOption(c *gin.Context) {
+	var body models.ModifierOption
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.ID = c.Param("id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryModifierOption, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateModifierOption(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// DeleteModifierOption godoc
+// @Summary Delete modifier option
+// @Description Delete an option and the groups nested under it
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Option ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/modifier-options/{id} [delete]
+func (h *MenuManagementAPI) DeleteModifierOption(c *gin.Context) {
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntryModifierOption, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteModifierOption(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
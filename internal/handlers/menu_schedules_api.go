@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateSchedule godoc
+// @Summary Create menu schedule
+// @Description Create a daypart (e.g. breakfast) that categories and items can be limited to
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MenuSchedule true "Schedule"
+// @Success 201 {object} models.MenuSchedule
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/schedules [post]
+func (h *MenuManagementAPI) CreateSchedule(c *gin.Context) {
+	var body models.MenuSchedule
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.ID == "" {
+		body.ID = uuid.New().String()
+	}
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.CreateSchedule(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// ListSchedules godoc
+// @Summary List menu schedules
+// @Description List a restaurant's menu schedules
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id query string true "Restaurant ID"
+// @Success 200 {array} models.MenuSchedule
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/schedules [get]
+func (h *MenuManagementAPI) ListSchedules(c *gin.Context) {
+	rid := c.Query("restaurant_id")
+	if rid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "restaurant_id required"})
+		return
+	}
+	list, err := h.svc.ListSchedules(c.Request.Context(), rid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateSchedule godoc
+// @Summary Update menu schedule
+// @Description Replace a schedule's name, windows, dates and holiday rule
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schedule ID"
+// @Param request body models.MenuSchedule true "Schedule"
+// @Success 200 {object} models.MenuSchedule
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/schedules/{id} [put]
+func (h *MenuManagementAPI) UpdateSchedule(c *gin.Context) {
+	var body models.MenuSchedule
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.ID = c.Param("id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntrySchedule, body.ID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.UpdateSchedule(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// DeleteSchedule godoc
+// @Summary Delete menu schedule
+// @Description Delete a schedule no category or item uses
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Schedule ID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/schedules/{id} [delete]
+func (h *MenuManagementAPI) DeleteSchedule(c *gin.Context) {
+	ctx, id := c.Request.Context(), c.Param("id")
+	_, err := h.svc.EditEntryDraft(ctx, services.MenuEntrySchedule, id, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteSchedule(ctx, id)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddHoliday godoc
+// @Summary Add restaurant holiday
+// @Description Mark a date as a holiday for the restaurant's menu schedules
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param request body object{date=string,name=string} true "Holiday"
+// @Success 201 {object} models.MenuHoliday
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/holidays [post]
+func (h *MenuManagementAPI) AddHoliday(c *gin.Context) {
+	var body models.MenuHoliday
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.RestaurantID = c.Param("restaurant_id")
+	ctx := c.Request.Context()
+	_, err := h.svc.EditMenuDraft(ctx, body.RestaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.AddHoliday(ctx, &body)
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// ListHolidays godoc
+// @Summary List restaurant holidays
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Success 200 {array} models.MenuHoliday
+// @Router /menu/branches/{restaurant_id}/holidays [get]
+func (h *MenuManagementAPI) ListHolidays(c *gin.Context) {
+	list, err := h.svc.ListHolidays(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteHoliday godoc
+// @Summary Delete restaurant holiday
+// @Tags menu
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/holidays/{date} [delete]
+func (h *MenuManagementAPI) DeleteHoliday(c *gin.Context) {
+	ctx, restaurantID := c.Request.Context(), c.Param("restaurant_id")
+	_, err := h.svc.EditMenuDraft(ctx, restaurantID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteHoliday(ctx, restaurantID, c.Param("date"))
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PreviewMenu godoc
+// @Summary Preview branch menu
+// @Description Show the branch's menu as guests will see it at a given time, schedules and overrides applied
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param at query string false "RFC3339 time (default now)"
+// @Param lang query string false "Language code, tried before Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param allergens query string false "Comma-separated allergens to flag or hide"
+// @Param allergen_mode query string false "flag (default) or hide"
+// @Param dietary query string false "Comma-separated dietary tags every item must carry"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/preview [get]
+func (h *MenuManagementAPI) PreviewMenu(c *gin.Context) {
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at: expected RFC3339"})
+			return
+		}
+		at = t
+	}
+	tags, err := menuTagFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	menu, err := h.svc.PreviewMenu(c.Request.Context(), c.Param("restaurant_id"), at, menuLocales(c), tags)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, menu)
+}
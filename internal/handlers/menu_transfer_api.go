@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"restaurant-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// menuTransferCSV reports whether an import or export is CSV rather than JSON
+func menuTransferCSV(c *gin.Context) bool {
+	if f := c.Query("format"); f != "" {
+		return f == "csv"
+	}
+	return strings.Contains(c.ContentType(), "csv")
+}
+
+// ImportMenu godoc
+// @Summary Import menu
+// @Description Create or update a branch's categories, items, variants and add-ons in its working draft from
+// @Description CSV or JSON rows, matched by name. All rows are applied or, when any row fails, none are.
+// @Description dry_run=true only reports what would change and the per-row errors.
+// @Tags menu
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param format query string false "csv or json; defaults from Content-Type"
+// @Param dry_run query bool false "Validate without applying"
+// @Param request body []models.MenuRow true "Rows"
+// @Success 200 {object} models.MenuImportResult
+// @Failure 400 {object} models.MenuImportResult
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/import [post]
+func (h *MenuManagementAPI) ImportMenu(c *gin.Context) {
+	var rows []models.MenuRow
+	if menuTransferCSV(c) {
+		var err error
+		if rows, err = models.ReadMenuCSV(c.Request.Body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&rows); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no rows to import"})
+		return
+	}
+	res, err := h.svc.ImportMenuDraft(c.Request.Context(), c.Param("restaurant_id"), c.GetString("account_id"), rows, c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if len(res.Errors) > 0 && !res.DryRun {
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ExportMenu godoc
+// @Summary Export menu
+// @Description Export a branch's categories and own items with their variants and add-ons, in the
+// @Description rows ImportMenu reads, to copy a menu to another branch
+// @Tags menu
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param format query string false "csv or json (default)"
+// @Success 200 {array} models.MenuRow
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/export [get]
+func (h *MenuManagementAPI) ExportMenu(c *gin.Context) {
+	rid := c.Param("restaurant_id")
+	rows, err := h.svc.ExportMenu(c.Request.Context(), rid)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+	var buf bytes.Buffer
+	if err := models.WriteMenuCSV(&buf, rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="menu-`+rid+`.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
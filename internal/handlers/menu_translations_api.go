@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTranslations godoc
+// @Summary Set menu translations
+// @Description Create or replace translations of categories, items, variants, add-ons and modifier
+// @Description groups and options in the working draft of their branch. All are saved or, when one is
+// @Description invalid, none.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body []models.MenuTranslation true "Translations"
+// @Success 200 {array} models.MenuTranslation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/translations [put]
+func (h *MenuManagementAPI) SetTranslations(c *gin.Context) {
+	var body []models.MenuTranslation
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.svc.SetTranslationsDraft(c.Request.Context(), c.GetString("account_id"), body)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListTranslations godoc
+// @Summary List translations of a menu entry
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param entity_type path string true "category, item, variant, addon, modifier_group or modifier_option"
+// @Param entity_id path string true "Entry ID"
+// @Success 200 {array} models.MenuTranslation
+// @Failure 500 {object} models.ErrorResponse
+// @Router /menu/translations/{entity_type}/{entity_id} [get]
+func (h *MenuManagementAPI) ListTranslations(c *gin.Context) {
+	list, err := h.svc.ListTranslations(c.Request.Context(), models.TranslatableEntity(c.Param("entity_type")), c.Param("entity_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteTranslation godoc
+// @Summary Delete a menu translation
+// @Tags menu
+// @Security BearerAuth
+// @Param entity_type path string true "Entry type"
+// @Param entity_id path string true "Entry ID"
+// @Param field path string true "name or description"
+// @Param locale path string true "Locale"
+// @Success 204 "Deleted"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/translations/{entity_type}/{entity_id}/{field}/{locale} [delete]
+func (h *MenuManagementAPI) DeleteTranslation(c *gin.Context) {
+	ctx, entityType, entityID := c.Request.Context(), models.TranslatableEntity(c.Param("entity_type")), c.Param("entity_id")
+	_, err := h.svc.EditEntryDraft(ctx, services.TranslatedEntry(entityType), entityID, c.GetString("account_id"), func(d *services.MenuSQLService) error {
+		return d.DeleteTranslation(ctx, entityType, entityID, c.Param("field"), c.Param("locale"))
+	})
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MissingTranslations godoc
+// @Summary List missing translations
+// @Description List the text on a branch's menu that has no translation in exactly the given locale
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param locale query string true "Locale"
+// @Success 200 {array} models.MissingTranslation
+// @Failure 400 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/translations/missing [get]
+func (h *MenuManagementAPI) MissingTranslations(c *gin.Context) {
+	locale := c.Query("locale")
+	if len(models.LocaleChain(locale)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locale required"})
+		return
+	}
+	list, err := h.svc.MissingTranslations(c.Request.Context(), c.Param("restaurant_id"), locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
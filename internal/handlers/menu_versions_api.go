@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateMenuDraft godoc
+// @Summary Create menu draft
+// @Description Start a draft menu version from the branch's live menu, or from one of its versions
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Param request body object{based_on=string,note=string} false "Draft"
+// @Success 201 {object} models.MenuVersion
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/versions [post]
+func (h *MenuManagementAPI) CreateMenuDraft(c *gin.Context) {
+	var body struct {
+		BasedOn string `json:"based_on"`
+		Note    string `json:"note"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	v, err := h.svc.CreateMenuDraft(c.Request.Context(), c.Param("restaurant_id"), body.BasedOn, body.Note, c.GetString("account_id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+// ListMenuVersions godoc
+// @Summary List menu versions
+// @Description List the branch's menu versions, newest first, without their rows
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id path string true "Restaurant ID"
+// @Success 200 {array} models.MenuVersion
+// @Failure 500 {object} models.ErrorResponse
+// @Router /menu/branches/{restaurant_id}/versions [get]
+func (h *MenuManagementAPI) ListMenuVersions(c *gin.Context) {
+	list, err := h.svc.ListMenuVersions(c.Request.Context(), c.Param("restaurant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetMenuVersion godoc
+// @Summary Get menu version
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Version ID"
+// @Success 200 {object} models.MenuVersion
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id} [get]
+func (h *MenuManagementAPI) GetMenuVersion(c *gin.Context) {
+	v, err := h.svc.GetMenuVersion(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// UpdateMenuDraft godoc
+// @Summary Update menu draft
+// @Description Set a draft's menu to rows, which are the same as a menu import's, and replace its note.
+// @Description The draft's modifier groups, combos, branch prices and translations are kept.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Version ID"
+// @Param request body object{rows=[]models.MenuRow,note=string} true "Draft"
+// @Success 200 {object} models.MenuVersion
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id} [put]
+func (h *MenuManagementAPI) UpdateMenuDraft(c *gin.Context) {
+	var body struct {
+		Rows []models.MenuRow `json:"rows" binding:"required"`
+		Note string           `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v, err := h.svc.UpdateMenuDraft(c.Request.Context(), c.Param("id"), body.Rows, body.Note)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// DeleteMenuVersion godoc
+// @Summary Delete menu version
+// @Description Discard a draft or cancel a scheduled version
+// @Tags menu
+// @Security BearerAuth
+// @Param id path string true "Version ID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id} [delete]
+func (h *MenuManagementAPI) DeleteMenuVersion(c *gin.Context) {
+	if err := h.svc.DeleteMenuVersion(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PreviewMenuVersion godoc
+// @Summary Preview menu version
+// @Description Show the branch's menu as guests would see it with the version published; rows that
+// @Description do not apply are reported instead
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Version ID"
+// @Param at query string false "RFC3339 time (default now)"
+// @Param lang query string false "Language code, tried before Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param allergens query string false "Comma-separated allergens to flag or hide"
+// @Param allergen_mode query string false "flag (default) or hide"
+// @Param dietary query string false "Comma-separated dietary tags every item must carry"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.MenuImportResult
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id}/preview [get]
+func (h *MenuManagementAPI) PreviewMenuVersion(c *gin.Context) {
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at: expected RFC3339"})
+			return
+		}
+		at = t
+	}
+	tags, err := menuTagFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	menu, res, err := h.svc.PreviewMenuVersion(c.Request.Context(), c.Param("id"), at, menuLocales(c), tags)
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if menu == nil {
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+	c.JSON(http.StatusOK, menu)
+}
+
+// PublishMenuVersion godoc
+// @Summary Publish menu version
+// @Description Make a draft the branch's live menu in one step, or schedule it with publish_at.
+// @Description The branch's whole menu, from schedules to translations, is brought in line with the version.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Version ID"
+// @Param request body object{publish_at=string} false "When to publish (RFC3339, default now)"
+// @Success 200 {object} models.MenuVersion
+// @Failure 400 {object} models.MenuImportResult
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id}/publish [post]
+func (h *MenuManagementAPI) PublishMenuVersion(c *gin.Context) {
+	var body struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	v, res, err := h.svc.PublishMenuVersion(c.Request.Context(), c.Param("id"), body.PublishAt)
+	h.respondPublished(c, v, res, err)
+}
+
+// RollbackMenu godoc
+// @Summary Roll back menu
+// @Description Publish a copy of a previously published version as the branch's new version
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Version ID to roll back to"
+// @Success 200 {object} models.MenuVersion
+// @Failure 400 {object} models.MenuImportResult
+// @Failure 404 {object} models.ErrorResponse
+// @Router /menu/versions/{id}/rollback [post]
+func (h *MenuManagementAPI) RollbackMenu(c *gin.Context) {
+	v, res, err := h.svc.RollbackMenu(c.Request.Context(), c.Param("id"), c.GetString("account_id"))
+	h.respondPublished(c, v, res, err)
+}
+
+func (h *MenuManagementAPI) respondPublished(c *gin.Context, v *models.MenuVersion, res *models.MenuImportResult, err error) {
+	if err != nil {
+		c.JSON(menuErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if v == nil {
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+	if v.Status == models.MenuVersionPublished {
+		h.broadcastPublished(*v)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+func (h *MenuManagementAPI) broadcastPublished(v models.MenuVersion) {
+	h.ws.Broadcast(gin.H{"type": "menu.published", "restaurant_id": v.RestaurantID, "version_id": v.ID, "number": v.Number})
+}
+
+// PublishScheduledMenus publishes scheduled menu versions as they come due, checking every interval
+func (h *MenuManagementAPI) PublishScheduledMenus(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for now := range ticker.C {
+			published, err := h.svc.PublishDueMenuVersions(context.Background(), now)
+			if err != nil {
+				log.Printf("scheduled menu publishing: %v", err)
+			}
+			for _, v := range published {
+				h.broadcastPublished(v)
+			}
+		}
+	}()
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"restaurant-system/internal/models"
@@ -22,13 +26,15 @@ func NewOrderAPI(svc *services.OrderSQLService, hub *websocket.Hub) *OrderAPI {
 
 // CreateOrder godoc
 // @Summary Create a new order
-// @Description Create a new order with items for a customer
+// @Description Create a new order with items for a customer. If lines contain allergens the customer
+// @Description declared, 409 lists them under allergen_warnings; resend with acknowledge_allergens to place it.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param request body models.CreateOrderRequest true "Order request"
 // @Success 201 {object} models.Order
 // @@Failure 400 {object} models.ErrorRespons
+// @Failure 409 {object} map[string]interface{}
 // @Router /orders [post]
 func (h *OrderAPI) CreateOrder(c *gin.Context) {
 	var req models.CreateOrderRequest
@@ -36,30 +42,13 @@ func (h *OrderAPI) CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	items := make([]services.CreateOrderItemReq, 0, len(req.Items))
-	for _, it := range req.Items {
-		items = append(items, services.CreateOrderItemReq{MenuItemID: it.MenuItemID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions})
-	}
-	// pass optional session id when creating order
-	if req.SessionID != "" {
-		ord, err := h.svc.CreateOrder(c.Request.Context(), req.CustomerID, items, req.SessionID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		if h.hub != nil {
-			h.hub.Broadcast(struct {
-				Type  string        `json:"type"`
-				Order *models.Order `json:"order"`
-			}{Type: "order_created", Order: ord})
-		}
-		c.JSON(http.StatusCreated, ord)
-		return
-	}
-
-	ord, err := h.svc.CreateOrder(c.Request.Context(), req.CustomerID, items)
+	ord, err := h.svc.CreateOrder(c.Request.Context(), services.NewOrder{
+		CustomerID: req.CustomerID, SessionID: req.SessionID, RestaurantID: req.RestaurantID,
+		DiscountCode: req.DiscountCode, Type: req.Type, TableID: req.TableID, PickupAt: req.PickupAt,
+		DeliveryAddress: req.DeliveryAddress, Items: toServiceItems(req.Items), AcknowledgeAllergens: req.AcknowledgeAllergens,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(orderErrorStatus(err), orderErrorBody(err))
 		return
 	}
 	// broadcast to kitchen and customer
@@ -68,48 +57,64 @@ func (h *OrderAPI) CreateOrder(c *gin.Context) {
 			Type  string        `json:"type"`
 			Order *models.Order `json:"order"`
 		}{Type: "order_created", Order: ord})
+		broadcastTickets(c.Request.Context(), h.hub, h.svc, ord.ID, "ticket_created", nil)
 	}
 	c.JSON(http.StatusCreated, ord)
 }
 
+func toServiceItems(in []models.CreateOrderItem) []services.CreateOrderItemReq {
+	items := make([]services.CreateOrderItemReq, 0, len(in))
+	for _, it := range in {
+		items = append(items, services.CreateOrderItemReq{
+			MenuItemID: it.MenuItemID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions,
+			VariantID: it.VariantID, AddonIDs: it.AddonIDs, Modifiers: it.Modifiers, Course: it.Course,
+			ComboID: it.ComboID, ComboChoices: it.ComboChoices,
+		})
+	}
+	return items
+}
+
 // POST /api/v1/orders/sync
+// Replays are safe: every order carries a client_order_id and the response reports each one as
+// created, duplicate or rejected so the device can drop what the server already has.
 func (h *OrderAPI) SyncOrders(c *gin.Context) {
-	var payload struct {
-		CustomerID string                     `json:"customer_id" binding:"required"`
-		SessionID  string                     `json:"session_id,omitempty"`
-		Orders     [][]models.CreateOrderItem `json:"orders" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&payload); err != nil {
+	var req models.SyncOrdersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// convert to service types
-	var batches [][]services.CreateOrderItemReq
-	for _, ord := range payload.Orders {
-		var items []services.CreateOrderItemReq
-		for _, it := range ord {
-			items = append(items, services.CreateOrderItemReq{MenuItemID: it.MenuItemID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions})
-		}
-		batches = append(batches, items)
+	batch := make([]services.NewOrder, 0, len(req.Orders))
+	for _, o := range req.Orders {
+		batch = append(batch, services.NewOrder{
+			CustomerID: req.CustomerID, SessionID: req.SessionID, RestaurantID: req.RestaurantID,
+			DiscountCode: o.DiscountCode, ClientOrderID: o.ClientOrderID, ClientCreatedAt: o.CreatedAt,
+			Type: o.Type, TableID: o.TableID, PickupAt: o.PickupAt, Items: toServiceItems(o.Items),
+			AcknowledgeAllergens: o.AcknowledgeAllergens,
+		})
 	}
-	created, err := h.svc.SyncOrders(c.Request.Context(), payload.CustomerID, batches, payload.SessionID)
+	results, err := h.svc.SyncOrders(c.Request.Context(), batch)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	// broadcast newly created orders
 	if h.hub != nil {
-		for _, o := range created {
+		for _, r := range results {
+			if r.Status != models.SyncCreated {
+				continue
+			}
 			h.hub.Broadcast(struct {
 				Type  string        `json:"type"`
 				Order *models.Order `json:"order"`
-			}{Type: "order_created", Order: o})
+			}{Type: "order_created", Order: r.Order})
+			broadcastTickets(c.Request.Context(), h.hub, h.svc, r.Order.ID, "ticket_created", nil)
 		}
 	}
-	c.JSON(http.StatusCreated, gin.H{"orders": created})
+	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
 // PUT /api/v1/orders/:id/eta
+// Overrides the estimated ETA until the override is cleared with DELETE.
 func (h *OrderAPI) SetETA(c *gin.Context) {
 	id := c.Param("id")
 	var body struct {
@@ -133,6 +138,39 @@ func (h *OrderAPI) SetETA(c *gin.Context) {
 	c.JSON(http.StatusOK, ord)
 }
 
+// DELETE /api/v1/orders/:id/eta
+// Drops a manual ETA so the order is estimated from the kitchen queue again.
+func (h *OrderAPI) ClearETA(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.ClearOrderETA(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ord, err := h.svc.GetOrder(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	pushETAs(c.Request.Context(), h.hub, h.svc, ord.RestaurantID)
+	ord, _ = h.svc.GetOrder(c.Request.Context(), id)
+	c.JSON(http.StatusOK, ord)
+}
+
+// pushETAs re-estimates the restaurant's kitchen queue and announces every ETA that moved.
+// Estimates are best effort, so failures do not fail the request that changed the queue.
+func pushETAs(ctx context.Context, hub *websocket.Hub, svc *services.OrderSQLService, restaurantID string) {
+	changed, err := svc.RefreshETAs(ctx, restaurantID)
+	if err != nil || hub == nil {
+		return
+	}
+	for _, ord := range changed {
+		hub.Broadcast(struct {
+			Type  string        `json:"type"`
+			Order *models.Order `json:"order"`
+		}{Type: "order_eta_updated", Order: ord})
+	}
+}
+
 // GET /api/v1/orders/customer/:customer_id
 func (h *OrderAPI) ListOrdersByCustomer(c *gin.Context) {
 	cid := c.Param("customer_id")
@@ -145,11 +183,20 @@ func (h *OrderAPI) ListOrdersByCustomer(c *gin.Context) {
 }
 
 // POST /api/v1/orders/:id/reorder
+// A dine-in order is repeated at the session_id or table_id sent, or as takeaway without one.
+// Allergen warnings are answered with 409 as on CreateOrder; resend with acknowledge_allergens.
 func (h *OrderAPI) Reorder(c *gin.Context) {
 	id := c.Param("id")
-	ord, err := h.svc.Reorder(c.Request.Context(), id)
+	var req models.ReorderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ord, err := h.svc.Reorder(c.Request.Context(), id, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(orderErrorStatus(err), orderErrorBody(err))
 		return
 	}
 	c.JSON(http.StatusCreated, ord)
@@ -174,21 +221,143 @@ func (h *OrderAPI) GetOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, ord)
 }
 
+// GetReceipt godoc
+// @Summary Get order receipt
+// @Description Printable receipt lines for an order, including variants and add-ons
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.Receipt
+// @Failure 404 {object} models.ErrorResponse
+// @Router /orders/{id}/receipt [get]
+func (h *OrderAPI) GetReceipt(c *gin.Context) {
+	r, err := h.svc.GetReceipt(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
 // ListOrders godoc
-// @Summary List all orders
-// @Description Get a list of all orders
+// @Summary List orders
+// @Description Get a page of orders, newest first, with optional filters. Pass next_cursor back as cursor for the next page.
 // @Tags orders
 // @Produce json
-// @Success 200 {object} map[string]interface{}
+// @Param status query string false "Comma-separated order statuses"
+// @Param type query string false "Order type (dine_in, takeaway, delivery)"
+// @Param customer_id query string false "Customer ID"
+// @Param session_id query string false "Session ID"
+// @Param restaurant_id query string false "Restaurant ID"
+// @Param number query int false "Daily order number"
+// @Param business_date query string false "Business day of the order number (YYYY-MM-DD)"
+// @Param from query string false "Created at or after (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Created before (RFC3339), or through the given day (YYYY-MM-DD)"
+// @Param cursor query string false "Page cursor"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} models.OrderPage
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /orders [get]
 func (h *OrderAPI) ListOrders(c *gin.Context) {
-	res, err := h.svc.ListOrders(c.Request.Context())
+	f, err := orderFilterFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"orders": res})
+	page, err := pageFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.svc.ListOrders(c.Request.Context(), f, page)
+	if err != nil {
+		c.JSON(listErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// orderFilterFromQuery reads the listing filters shared by the order, kitchen and report endpoints
+func orderFilterFromQuery(c *gin.Context) (services.OrderFilter, error) {
+	f := services.OrderFilter{
+		CustomerID:   c.Query("customer_id"),
+		SessionID:    c.Query("session_id"),
+		RestaurantID: c.Query("restaurant_id"),
+	}
+	if v := c.Query("status"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			st := models.OrderStatus(strings.TrimSpace(part))
+			if !st.IsValid() {
+				return f, errors.New("unknown status " + string(st))
+			}
+			f.Statuses = append(f.Statuses, st)
+		}
+	}
+	if t := models.OrderType(c.Query("type")); t != "" {
+		if !t.IsValid() {
+			return f, errors.New("unknown order type")
+		}
+		f.Type = t
+	}
+	if v := c.Query("number"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return f, errors.New("number must be a positive integer")
+		}
+		f.OrderNumber = n
+	}
+	if v := c.Query("business_date"); v != "" {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return f, errors.New("business_date must be YYYY-MM-DD")
+		}
+		f.BusinessDate = v
+	}
+	var err error
+	if f.From, err = parseQueryTime(c.Query("from"), false); err != nil {
+		return f, errors.New("invalid from: " + err.Error())
+	}
+	if f.To, err = parseQueryTime(c.Query("to"), true); err != nil {
+		return f, errors.New("invalid to: " + err.Error())
+	}
+	return f, nil
+}
+
+// parseQueryTime accepts RFC3339 or a bare date; a bare end date covers the whole day.
+func parseQueryTime(v string, end bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, errors.New("expected RFC3339 or YYYY-MM-DD")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func pageFromQuery(c *gin.Context) (services.PageRequest, error) {
+	p := services.PageRequest{Cursor: c.Query("cursor")}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, errors.New("limit must be a positive integer")
+		}
+		p.Limit = n
+	}
+	return p, nil
+}
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 // UpdateOrderStatus godoc
@@ -201,6 +370,8 @@ func (h *OrderAPI) ListOrders(c *gin.Context) {
 // @Param request body models.UpdateOrderStatusRequest true "Status update request"
 // @Success 200 {object} models.Order
 // @@Failure 400 {object} models.ErrorRespons
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /orders/{id}/status [put]
 func (h *OrderAPI) UpdateOrderStatus(c *gin.Context) {
 	id := c.Param("id")
@@ -209,11 +380,21 @@ func (h *OrderAPI) UpdateOrderStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.UpdateOrderStatus(c.Request.Context(), id, body.Status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !body.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status"})
+		return
+	}
+	t := services.OrderTransition{To: body.Status, UserID: c.GetString("account_id"), Reason: body.Reason}
+	if err := h.svc.UpdateOrderStatus(c.Request.Context(), id, t); err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	ord, _ := h.svc.GetOrder(c.Request.Context(), id)
+	if ord != nil {
+		// the queue changed, so other orders' ETAs may have too
+		pushETAs(c.Request.Context(), h.hub, h.svc, ord.RestaurantID)
+		ord, _ = h.svc.GetOrder(c.Request.Context(), id)
+	}
 	if h.hub != nil {
 		h.hub.Broadcast(struct {
 			Type  string        `json:"type"`
@@ -222,3 +403,178 @@ func (h *OrderAPI) UpdateOrderStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, ord)
 }
+
+// AddOrderItems godoc
+// @Summary Add items to an order
+// @Description Add lines to an open order; totals are recomputed and the kitchen is notified
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.AddOrderItemsRequest true "Items"
+// @Success 200 {object} models.Order
+// @Failure 409 {object} models.ErrorResponse
+// @Router /orders/{id}/items [post]
+func (h *OrderAPI) AddOrderItems(c *gin.Context) {
+	var req models.AddOrderItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ord, change, err := h.svc.AddOrderItems(c.Request.Context(), c.Param("id"), toServiceItems(req.Items), req.AcknowledgeAllergens, c.GetString("account_id"))
+	h.respondOrderChange(c, ord, change, err)
+}
+
+// RemoveOrderItem godoc
+// @Summary Remove an order line
+// @Description Delete a line with a reason code before the kitchen starts preparing
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param item_id path string true "Order item ID"
+// @Param request body models.OrderLineChangeRequest true "Reason"
+// @Success 200 {object} models.Order
+// @Failure 409 {object} models.ErrorResponse
+// @Router /orders/{id}/items/{item_id} [delete]
+func (h *OrderAPI) RemoveOrderItem(c *gin.Context) {
+	lc, ok := bindLineChange(c)
+	if !ok {
+		return
+	}
+	ord, change, err := h.svc.RemoveOrderItem(c.Request.Context(), c.Param("id"), c.Param("item_id"), lc)
+	h.respondOrderChange(c, ord, change, err)
+}
+
+// VoidOrderItem godoc
+// @Summary Void an order line
+// @Description Void a line with a reason code; once preparation has started only a manager can void
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param item_id path string true "Order item ID"
+// @Param request body models.OrderLineChangeRequest true "Reason"
+// @Success 200 {object} models.Order
+// @Failure 403 {object} models.ErrorResponse
+// @Router /orders/{id}/items/{item_id}/void [post]
+func (h *OrderAPI) VoidOrderItem(c *gin.Context) {
+	lc, ok := bindLineChange(c)
+	if !ok {
+		return
+	}
+	ord, change, err := h.svc.VoidOrderItem(c.Request.Context(), c.Param("id"), c.Param("item_id"), lc)
+	h.respondOrderChange(c, ord, change, err)
+}
+
+// bindLineChange reads the reason for a removal or void; managers approve their own voids
+func bindLineChange(c *gin.Context) (services.LineChange, bool) {
+	var req models.OrderLineChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return services.LineChange{}, false
+	}
+	if !req.Reason.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown reason code"})
+		return services.LineChange{}, false
+	}
+	lc := services.LineChange{Reason: req.Reason, Note: req.Note, UserID: c.GetString("account_id")}
+	if role := c.GetString("role"); role == "manager" || role == "admin" {
+		lc.ApprovedBy = lc.UserID
+	}
+	return lc, true
+}
+
+func (h *OrderAPI) respondOrderChange(c *gin.Context, ord *models.Order, change *models.OrderChange, err error) {
+	if err != nil {
+		c.JSON(orderErrorStatus(err), orderErrorBody(err))
+		return
+	}
+	pushETAs(c.Request.Context(), h.hub, h.svc, ord.RestaurantID)
+	if h.hub != nil {
+		h.hub.Broadcast(struct {
+			Type   string              `json:"type"`
+			Order  *models.Order       `json:"order"`
+			Change *models.OrderChange `json:"change"`
+		}{Type: "order_updated", Order: ord, Change: change})
+		event := "ticket_updated"
+		if change.Action == "items_added" {
+			event = "ticket_created"
+		}
+		touched := map[string]bool{}
+		for _, it := range change.Items {
+			touched[it.TicketID] = true
+		}
+		broadcastTickets(c.Request.Context(), h.hub, h.svc, ord.ID, event, touched)
+	}
+	c.JSON(http.StatusOK, ord)
+}
+
+// orderErrorStatus maps order service errors onto HTTP status codes
+func orderErrorStatus(err error) int {
+	var terr *services.TransitionError
+	var tkerr *services.TicketTransitionError
+	var aw *services.AllergenWarning
+	switch {
+	case errors.As(err, &terr), errors.As(err, &tkerr), errors.As(err, &aw), errors.Is(err, services.ErrOrderNotOpen), errors.Is(err, services.ErrOrderLocked),
+		errors.Is(err, services.ErrCourseHeld), errors.Is(err, services.ErrCourseStarted):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrApprovalRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrTicketNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// orderErrorBody is the JSON body for an order service error; allergen warnings carry the
+// conflicting lines for the customer to acknowledge
+func orderErrorBody(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var aw *services.AllergenWarning
+	if errors.As(err, &aw) {
+		body["allergen_warnings"] = aw.Conflicts
+	}
+	return body
+}
+
+// splitOrder backs both the enterprise and staff split endpoints; staff ones are limited to
+// their branch
+func splitOrder(c *gin.Context, svc *services.OrderSQLService, ws interface{ Broadcast(v interface{}) }, restaurantID, orderID string) {
+	var req models.SplitOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	derived, err := svc.SplitOrder(c.Request.Context(), restaurantID, orderID, req, c.GetString("account_id"))
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	original, _ := svc.GetOrder(c.Request.Context(), orderID)
+	if ws != nil {
+		ws.Broadcast(gin.H{"type": "order_split", "order": original, "derived_orders": derived})
+	}
+	c.JSON(http.StatusOK, gin.H{"order": original, "derived_orders": derived})
+}
+
+// mergeOrders backs both the enterprise and staff merge endpoints; staff ones are limited to
+// their branch
+func mergeOrders(c *gin.Context, svc *services.OrderSQLService, ws interface{ Broadcast(v interface{}) }, restaurantID, targetID string, sourceIDs []string) {
+	merged, err := svc.MergeOrders(c.Request.Context(), restaurantID, targetID, sourceIDs, c.GetString("account_id"))
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	var mergedIDs []string
+	for _, id := range sourceIDs {
+		if id != targetID {
+			mergedIDs = append(mergedIDs, id)
+		}
+	}
+	if ws != nil {
+		ws.Broadcast(gin.H{"type": "order_merged", "order": merged, "merged_order_ids": mergedIDs})
+	}
+	c.JSON(http.StatusOK, merged)
+}
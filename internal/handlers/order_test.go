@@ -34,7 +34,7 @@ func TestCreateOrder(t *testing.T) {
 	}
 	db.Create(&inventory)
 
-	api := NewEnterpriseAPI(db, &mockWebSocket{})
+	api := NewEnterpriseAPI(db, &mockWebSocket{}, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -71,9 +71,9 @@ func (h *EnterpriseAPI) CreateOrder(c *gin.Context) {
 		CustomerID   string `json:"customer_id" binding:"required"`
 		RestaurantID string `json:"restaurant_id" binding:"required"`
 		Items        []struct {
-			MenuItemID string  `json:"menu_item_id" binding:"required"`
-			Quantity   int     `json:"quantity" binding:"required"`
-			Price      float64 `json:"price" binding:"required"`
+			MenuItemID string       `json:"menu_item_id" binding:"required"`
+			Quantity   int          `json:"quantity" binding:"required"`
+			Price      models.Money `json:"price" binding:"required"`
 		} `json:"items" binding:"required"`
 	}
 
@@ -96,9 +96,9 @@ func (h *EnterpriseAPI) CreateOrder(c *gin.Context) {
 		Status:     "pending",
 	}
 
-	var total float64
+	var total models.Money
 	for _, item := range req.Items {
-		total += item.Price * float64(item.Quantity)
+		total += item.Price.Times(item.Quantity)
 
 		// Decrement inventory
 		if err := tx.Model(&models.InventoryItem{}).
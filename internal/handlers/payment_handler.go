@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 )
 
@@ -35,7 +36,7 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	p, err := h.svc.CreatePayment(c.Request.Context(), restaurantID, body.OrderID, body.AmountCents, body.Provider)
+	p, err := h.svc.CreatePayment(c.Request.Context(), restaurantID, body.OrderID, models.Money(body.AmountCents), body.Provider)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -85,8 +86,8 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 func (h *PaymentHandler) RequestRefund(c *gin.Context) {
 	id := c.Param("id")
 	var body struct {
-		Amount float64 `json:"amount" binding:"required,gt=0"`
-		Reason string  `json:"reason,omitempty"`
+		Amount models.Money `json:"amount" binding:"required,gt=0"`
+		Reason string       `json:"reason,omitempty"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -112,8 +113,8 @@ func (h *PaymentHandler) RequestRefund(c *gin.Context) {
 // @Router /payments/partial [post]
 func (h *PaymentHandler) ApplyPartialPayment(c *gin.Context) {
 	var body struct {
-		OrderID string  `json:"order_id" binding:"required"`
-		Amount  float64 `json:"amount" binding:"required,gt=0"`
+		OrderID string       `json:"order_id" binding:"required"`
+		Amount  models.Money `json:"amount" binding:"required,gt=0"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -3,13 +3,21 @@ package handlers
 import (
 	"net/http"
 
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
-// StaffAPI bundles staff-facing placeholder handlers
-type StaffAPI struct{}
+// StaffAPI bundles staff-facing handlers; some are still placeholders
+type StaffAPI struct {
+	orders *services.OrderSQLService
+	ws     interface{ Broadcast(v interface{}) }
+}
 
-func NewStaffAPI() *StaffAPI { return &StaffAPI{} }
+func NewStaffAPI(orders *services.OrderSQLService, ws interface{ Broadcast(v interface{}) }) *StaffAPI {
+	return &StaffAPI{orders: orders, ws: ws}
+}
 
 // UpdateTableState godoc
 // @Summary Update table state
@@ -58,31 +66,106 @@ func (h *StaffAPI) AssignChefToOrder(c *gin.Context) {
 
 // SplitOrder godoc
 // @Summary Split an order
-// @Description Split an order into multiple orders
+// @Description Split one of the branch's orders by item, by quantity within a line, or evenly between guests
 // @Tags staff
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param branchId path string true "Branch ID"
 // @Param orderId path string true "Order ID"
-// @Success 501 {object} map[string]string
+// @Param request body models.SplitOrderRequest true "Split request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /staff/branches/{branchId}/orders/{orderId}/split [post]
 func (h *StaffAPI) SplitOrder(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not_implemented"})
+	splitOrder(c, h.orders, h.ws, c.Param("branchId"), c.Param("orderId"))
 }
 
 // MergeOrders godoc
 // @Summary Merge orders
-// @Description Merge multiple orders into one
+// @Description Merge open orders of the branch from the same session into one; the first order is kept unless target_order_id is set
 // @Tags staff
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param branchId path string true "Branch ID"
-// @Success 501 {object} map[string]string
+// @Param request body models.MergeOrdersRequest true "Orders to merge"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /staff/branches/{branchId}/orders/merge [post]
 func (h *StaffAPI) MergeOrders(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not_implemented"})
+	var req models.MergeOrdersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target := req.TargetOrderID
+	if target == "" {
+		target = req.OrderIDs[0]
+	}
+	mergeOrders(c, h.orders, h.ws, c.Param("branchId"), target, req.OrderIDs)
+}
+
+// FireCourse godoc
+// @Summary Fire a course
+// @Description Send a held course of a dine-in order to its kitchen stations
+// @Tags staff
+// @Produce json
+// @Security BearerAuth
+// @Param branchId path string true "Branch ID"
+// @Param orderId path string true "Order ID"
+// @Param course path string true "Course (starter, main, dessert)"
+// @Success 200 {array} models.KitchenTicket
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /staff/branches/{branchId}/orders/{orderId}/courses/{course}/fire [post]
+func (h *StaffAPI) FireCourse(c *gin.Context) {
+	tickets, err := h.orders.FireCourse(c.Request.Context(), c.Param("orderId"), models.Course(c.Param("course")), c.GetString("account_id"))
+	h.respondCourse(c, tickets, "ticket_created", err)
+}
+
+// HoldCourse godoc
+// @Summary Hold a course
+// @Description Take a fired course back off the kitchen stations before any of it has been started
+// @Tags staff
+// @Produce json
+// @Security BearerAuth
+// @Param branchId path string true "Branch ID"
+// @Param orderId path string true "Order ID"
+// @Param course path string true "Course (starter, main, dessert)"
+// @Success 200 {array} models.KitchenTicket
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /staff/branches/{branchId}/orders/{orderId}/courses/{course}/hold [post]
+func (h *StaffAPI) HoldCourse(c *gin.Context) {
+	tickets, err := h.orders.HoldCourse(c.Request.Context(), c.Param("orderId"), models.Course(c.Param("course")), c.GetString("account_id"))
+	h.respondCourse(c, tickets, "ticket_held", err)
+}
+
+func (h *StaffAPI) respondCourse(c *gin.Context, tickets []models.KitchenTicket, event string, err error) {
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if hub, ok := h.ws.(stationBroadcaster); ok {
+		for i := range tickets {
+			hub.BroadcastToStation(tickets[i].StationID, struct {
+				Type   string                `json:"type"`
+				Ticket *models.KitchenTicket `json:"ticket"`
+			}{Type: event, Ticket: &tickets[i]})
+		}
+	}
+	if h.ws != nil {
+		ord, _ := h.orders.GetOrder(c.Request.Context(), c.Param("orderId"))
+		h.ws.Broadcast(gin.H{"type": "order_updated", "order": ord})
+	}
+	c.JSON(http.StatusOK, tickets)
 }
 
 // AddTip godoc
@@ -28,10 +28,10 @@ func NewTelebirrB2BHandler(telebirrService *services.TelebirrService, orderServi
 }
 
 type CreatePaymentRequest struct {
-	OrderID string  `json:"order_id" binding:"required"`
-	Amount  float64 `json:"amount" binding:"required"`
-	Subject string  `json:"subject" binding:"required"`
-	Body    string  `json:"body"`
+	OrderID string       `json:"order_id" binding:"required"`
+	Amount  models.Money `json:"amount" binding:"required"`
+	Subject string       `json:"subject" binding:"required"`
+	Body    string       `json:"body"`
 }
 
 type CreatePaymentResponse struct {
@@ -283,9 +283,9 @@ func (h *TelebirrB2BHandler) GetOrderPayments(c *gin.Context) {
 // @Router /payments/telebirr/b2b/refund [post]
 func (h *TelebirrB2BHandler) RefundB2BPayment(c *gin.Context) {
 	var req struct {
-		PrepayID     string  `json:"prepay_id" binding:"required"`
-		RefundAmount float64 `json:"refund_amount" binding:"required"`
-		RefundReason string  `json:"refund_reason"`
+		PrepayID     string       `json:"prepay_id" binding:"required"`
+		RefundAmount models.Money `json:"refund_amount" binding:"required"`
+		RefundReason string       `json:"refund_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
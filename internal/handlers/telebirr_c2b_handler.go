@@ -29,10 +29,10 @@ func NewTelebirrC2BHandler(c2bService *services.TelebirrC2BService, orderService
 }
 
 type CreateC2BPaymentRequest struct {
-	OrderID string  `json:"order_id" binding:"required"`
-	Amount  float64 `json:"amount" binding:"required"`
-	Subject string  `json:"subject" binding:"required"`
-	Body    string  `json:"body"`
+	OrderID string       `json:"order_id" binding:"required"`
+	Amount  models.Money `json:"amount" binding:"required"`
+	Subject string       `json:"subject" binding:"required"`
+	Body    string       `json:"body"`
 }
 
 type CreateC2BPaymentResponse struct {
@@ -234,9 +234,9 @@ func (h *TelebirrC2BHandler) GetOrderC2BPayments(c *gin.Context) {
 // POST /api/v1/payments/telebirr/c2b/refund
 func (h *TelebirrC2BHandler) RefundC2BPayment(c *gin.Context) {
 	var req struct {
-		OutTradeNo   string  `json:"out_trade_no" binding:"required"`
-		RefundAmount float64 `json:"refund_amount" binding:"required"`
-		RefundReason string  `json:"refund_reason"`
+		OutTradeNo   string       `json:"out_trade_no" binding:"required"`
+		RefundAmount models.Money `json:"refund_amount" binding:"required"`
+		RefundReason string       `json:"refund_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
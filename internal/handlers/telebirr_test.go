@@ -22,7 +22,7 @@ func (m *mockOrderService) GetOrder(orderID string) (*models.Order, error) {
 	return &models.Order{
 		ID:          orderID,
 		CustomerID:  "customer123",
-		TotalAmount: 100.50,
+		TotalAmount: 10050,
 		Status:      "pending",
 	}, nil
 }
@@ -61,7 +61,7 @@ func TestCreateB2BPayment(t *testing.T) {
 
 	req := CreatePaymentRequest{
 		OrderID: "order123",
-		Amount:  100.50,
+		Amount:  10050,
 		Subject: "Test Payment",
 		Body:    "Test payment for order",
 	}
@@ -87,7 +87,7 @@ func TestGetPaymentStatus(t *testing.T) {
 		OrderID:      "order123",
 		PrepayID:     "prepay123",
 		MerchOrderID: "merch123",
-		Amount:       100.50,
+		Amount:       10050,
 		Status:       "pending",
 	}
 	db.Create(&testOrder)
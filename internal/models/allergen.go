@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// Allergen is one of the allergens a menu must declare
+type Allergen string
+
+const (
+	AllergenGluten      Allergen = "gluten"
+	AllergenCrustaceans Allergen = "crustaceans"
+	AllergenEggs        Allergen = "eggs"
+	AllergenFish        Allergen = "fish"
+	AllergenPeanuts     Allergen = "peanuts"
+	AllergenSoy         Allergen = "soy"
+	AllergenMilk        Allergen = "milk"
+	AllergenTreeNuts    Allergen = "tree_nuts"
+	AllergenCelery      Allergen = "celery"
+	AllergenMustard     Allergen = "mustard"
+	AllergenSesame      Allergen = "sesame"
+	AllergenSulphites   Allergen = "sulphites"
+	AllergenLupin       Allergen = "lupin"
+	AllergenMolluscs    Allergen = "molluscs"
+)
+
+func (a Allergen) IsValid() bool {
+	switch a {
+	case AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenPeanuts, AllergenSoy, AllergenMilk,
+		AllergenTreeNuts, AllergenCelery, AllergenMustard, AllergenSesame, AllergenSulphites, AllergenLupin, AllergenMolluscs:
+		return true
+	}
+	return false
+}
+
+// DietaryTag is a dietary claim a menu item, variant or add-on meets
+type DietaryTag string
+
+const (
+	DietVegetarian DietaryTag = "vegetarian"
+	DietVegan      DietaryTag = "vegan"
+	DietGlutenFree DietaryTag = "gluten_free"
+	DietDairyFree  DietaryTag = "dairy_free"
+	DietHalal      DietaryTag = "halal"
+	DietFasting    DietaryTag = "fasting"
+)
+
+func (d DietaryTag) IsValid() bool {
+	switch d {
+	case DietVegetarian, DietVegan, DietGlutenFree, DietDairyFree, DietHalal, DietFasting:
+		return true
+	}
+	return false
+}
+
+// Allergens is a set of allergens, stored as a JSON array like order line add-ons
+type Allergens []Allergen
+
+func (a Allergens) Validate() error {
+	for _, x := range a {
+		if !x.IsValid() {
+			return errors.New("unknown allergen " + string(x))
+		}
+	}
+	return nil
+}
+
+func (a Allergens) Contains(x Allergen) bool {
+	for _, y := range a {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+// Union returns the sorted allergens found in either set
+func (a Allergens) Union(b Allergens) Allergens {
+	seen := map[Allergen]bool{}
+	var out Allergens
+	for _, x := range append(append(Allergens{}, a...), b...) {
+		if !seen[x] {
+			seen[x] = true
+			out = append(out, x)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Intersect returns the sorted allergens found in both sets
+func (a Allergens) Intersect(b Allergens) Allergens {
+	var out Allergens
+	for _, x := range a.Union(nil) {
+		if b.Contains(x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func (a Allergens) Value() (driver.Value, error) {
+	return jsonListValue(a)
+}
+
+func (a *Allergens) Scan(src interface{}) error {
+	return scanJSONList(src, a)
+}
+
+// DietaryTags is a set of dietary tags, stored as a JSON array
+type DietaryTags []DietaryTag
+
+func (d DietaryTags) Validate() error {
+	for _, x := range d {
+		if !x.IsValid() {
+			return errors.New("unknown dietary tag " + string(x))
+		}
+	}
+	return nil
+}
+
+// HasAll reports whether every tag in want is present
+func (d DietaryTags) HasAll(want DietaryTags) bool {
+	for _, w := range want {
+		found := false
+		for _, x := range d {
+			if x == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (d DietaryTags) Value() (driver.Value, error) {
+	return jsonListValue(d)
+}
+
+func (d *DietaryTags) Scan(src interface{}) error {
+	return scanJSONList(src, d)
+}
+
+// ValidateTags checks the allergen and dietary tags of a menu item, variant or add-on
+func ValidateTags(a Allergens, d DietaryTags) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	return d.Validate()
+}
+
+func jsonListValue(v interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "null" {
+		return "[]", nil
+	}
+	return string(b), nil
+}
+
+func scanJSONList(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if len(v) == 0 {
+			return nil
+		}
+		return json.Unmarshal(v, dst)
+	case string:
+		if v == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(v), dst)
+	}
+	return errors.New("unsupported tag list value")
+}
+
+// AllergenConflict is an order line containing allergens the customer declared
+type AllergenConflict struct {
+	MenuItemID string    `json:"menu_item_id"`
+	Name       string    `json:"name"`
+	Allergens  Allergens `json:"allergens"`
+}
+
+// AccountPreferences are the allergies and dietary needs a customer saved on their account
+type AccountPreferences struct {
+	Allergens Allergens   `json:"allergens"`
+	Dietary   DietaryTags `json:"dietary"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllergenSets(t *testing.T) {
+	a := Allergens{AllergenMilk, AllergenGluten}
+	b := Allergens{AllergenPeanuts, AllergenMilk}
+
+	assert.Equal(t, Allergens{AllergenGluten, AllergenMilk, AllergenPeanuts}, a.Union(b))
+	assert.Equal(t, Allergens{AllergenMilk}, a.Intersect(b))
+	assert.Empty(t, a.Intersect(nil))
+	assert.Error(t, Allergens{"shellfish"}.Validate())
+}
+
+func TestTagListStorage(t *testing.T) {
+	v, err := Allergens(nil).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", v)
+
+	var d DietaryTags
+	assert.NoError(t, d.Scan([]byte(`["vegan","halal"]`)))
+	assert.Equal(t, DietaryTags{DietVegan, DietHalal}, d)
+	assert.True(t, d.HasAll(DietaryTags{DietHalal}))
+	assert.False(t, d.HasAll(DietaryTags{DietGlutenFree}))
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Combo is a meal sold at a bundle price, such as burger + fries + drink. Each slot is filled
+// with one of its choices; a choice's upcharge is added to the combo price.
+type Combo struct {
+	ID           string      `json:"id"`
+	RestaurantID string      `json:"restaurant_id"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description,omitempty"`
+	Price        float64     `json:"price"`
+	Available    bool        `json:"available"`
+	Slots        []ComboSlot `json:"slots"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// ComboSlot is one part of a combo, e.g. "Drink"
+type ComboSlot struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Position int           `json:"position"`
+	Choices  []ComboChoice `json:"choices"`
+}
+
+// ComboChoice is a menu item a slot can be filled with. The default fills the slot when the
+// guest does not choose.
+type ComboChoice struct {
+	MenuItemID string  `json:"menu_item_id"`
+	Name       string  `json:"name,omitempty"`
+	Upcharge   float64 `json:"upcharge,omitempty"`
+	IsDefault  bool    `json:"is_default,omitempty"`
+}
+
+func (c Combo) Validate() error {
+	if c.RestaurantID == "" || c.Name == "" {
+		return errors.New("restaurant_id and name required")
+	}
+	if c.Price <= 0 {
+		return errors.New("price must be positive")
+	}
+	if len(c.Slots) == 0 {
+		return errors.New("a combo needs at least one slot")
+	}
+	for _, s := range c.Slots {
+		if s.Name == "" || len(s.Choices) == 0 {
+			return errors.New("every slot needs a name and at least one choice")
+		}
+		defaults := 0
+		seen := map[string]bool{}
+		for _, ch := range s.Choices {
+			if ch.MenuItemID == "" || seen[ch.MenuItemID] {
+				return errors.New("slot " + s.Name + " has a missing or repeated menu item")
+			}
+			seen[ch.MenuItemID] = true
+			if ch.Upcharge < 0 {
+				return errors.New("upcharges cannot be negative")
+			}
+			if ch.IsDefault {
+				defaults++
+			}
+		}
+		if defaults > 1 {
+			return errors.New("slot " + s.Name + " has more than one default")
+		}
+	}
+	return nil
+}
+
+// ComboSelection fills one combo slot on an order, with the modifiers chosen for that item
+type ComboSelection struct {
+	SlotID     string              `json:"slot_id"`
+	MenuItemID string              `json:"menu_item_id"`
+	Modifiers  []ModifierSelection `json:"modifiers,omitempty"`
+}
+
+// ItemSales is what one menu item sold in one currency. Revenue counts a combo component's
+// share of its combo's price.
+type ItemSales struct {
+	MenuItemID string `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Currency   string `json:"currency"`
+	Quantity   int    `json:"quantity"`
+	Revenue    Money  `json:"revenue"`
+}
@@ -57,6 +57,13 @@ type OrderAudit struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Discount types; percentage values are percents of the order subtotal,
+// fixed values are amounts in major units of the order's currency.
+const (
+	DiscountTypePercentage = "percentage"
+	DiscountTypeFixed      = "fixed"
+)
+
 type Discount struct {
 	ID           string         `json:"id" gorm:"primaryKey;type:text"`
 	Code         string         `json:"code" gorm:"uniqueIndex;type:text;not null"`
@@ -77,7 +84,7 @@ type DiscountUsage struct {
 	DiscountID string    `json:"discount_id" gorm:"index;type:text;not null"`
 	AccountID  string    `json:"account_id" gorm:"index;type:text;not null"`
 	OrderID    string    `json:"order_id" gorm:"type:text"`
-	Amount     float64   `json:"amount" gorm:"not null"`
+	Amount     Money     `json:"amount" gorm:"not null"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
@@ -99,18 +106,37 @@ type LoyaltyTransaction struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Restaurant holds per-branch settings. TaxRate and ServiceChargeRate are
+// percentages, e.g. 15 for 15%. KitchenCapacity is how many orders the kitchen
+// works on at once, used for ETA estimates.
 type Restaurant struct {
-	ID        string         `json:"id" gorm:"primaryKey;type:text"`
-	Name      string         `json:"name" gorm:"type:text;not null"`
-	Timezone  string         `json:"timezone" gorm:"type:text;default:'UTC'"`
-	Currency  string         `json:"currency" gorm:"type:text;default:'USD'"`
-	TaxRate   float64        `json:"tax_rate" gorm:"default:0"`
-	Address   string         `json:"address" gorm:"type:text"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID                string         `json:"id" gorm:"primaryKey;type:text"`
+	Name              string         `json:"name" gorm:"type:text;not null"`
+	Timezone          string         `json:"timezone" gorm:"type:text;default:'UTC'"`
+	Currency          string         `json:"currency" gorm:"type:text;default:'USD'"`
+	TaxRate           float64        `json:"tax_rate" gorm:"default:0"`
+	TaxInclusive      bool           `json:"tax_inclusive" gorm:"default:false"`
+	ServiceChargeRate float64        `json:"service_charge_rate" gorm:"default:0"`
+	RoundingIncrement Money          `json:"rounding_increment" gorm:"default:0"`
+	RoundingMode      RoundingMode   `json:"rounding_mode" gorm:"type:text"`
+	DeliveryFee       Money          `json:"delivery_fee" gorm:"default:0"`
+	KitchenCapacity   int            `json:"kitchen_capacity" gorm:"default:4"`
+	Address           string         `json:"address" gorm:"type:text"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// RoundingMode controls how an order's grand total is rounded to RoundingIncrement.
+type RoundingMode string
+
+const (
+	RoundingNone    RoundingMode = ""
+	RoundingNearest RoundingMode = "nearest"
+	RoundingUp      RoundingMode = "up"
+	RoundingDown    RoundingMode = "down"
+)
+
 type TableState struct {
 	ID           string    `json:"id" gorm:"primaryKey;type:text"`
 	TableID      string    `json:"table_id" gorm:"index;type:text;not null"`
@@ -137,6 +163,6 @@ type WaitlistEntry struct {
 type PaymentTip struct {
 	ID        string    `json:"id" gorm:"primaryKey;type:text"`
 	PaymentID string    `json:"payment_id" gorm:"index;type:text;not null"`
-	Amount    float64   `json:"amount" gorm:"not null"`
+	Amount    Money     `json:"amount" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// KitchenStation is a preparation area such as grill, cold or bar. Lines are routed to a station
+// by menu item first, then by menu category, then to the restaurant's default station.
+type KitchenStation struct {
+	ID           string    `json:"id" db:"id"`
+	RestaurantID string    `json:"restaurant_id,omitempty" db:"restaurant_id"`
+	Name         string    `json:"name" db:"name"`
+	IsDefault    bool      `json:"is_default" db:"is_default"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// StationRoute assigns either a menu category or a single menu item to a station
+type StationRoute struct {
+	ID         string `json:"id" db:"id"`
+	StationID  string `json:"station_id" db:"station_id"`
+	Category   string `json:"category,omitempty" db:"category"`
+	MenuItemID string `json:"menu_item_id,omitempty" db:"menu_item_id"`
+}
+
+type TicketStatus string
+
+const (
+	TicketQueued     TicketStatus = "queued"
+	TicketInProgress TicketStatus = "in_progress"
+	TicketBumped     TicketStatus = "bumped"
+)
+
+func (s TicketStatus) IsValid() bool {
+	switch s {
+	case TicketQueued, TicketInProgress, TicketBumped:
+		return true
+	}
+	return false
+}
+
+// KitchenTicket is the part of an order's course one station prepares. Lines added to an order
+// later arrive on a new ticket. StationID is empty when no station matched and there is no
+// default. FiredAt is when the station received the ticket; a held course has none and stays
+// off the station until it is fired. A recalled ticket returns to in progress with BumpedAt cleared.
+type KitchenTicket struct {
+	ID          string       `json:"id" db:"id"`
+	OrderID     string       `json:"order_id" db:"order_id"`
+	OrderNumber int          `json:"order_number,omitempty" db:"order_number"`
+	StationID   string       `json:"station_id,omitempty" db:"station_id"`
+	Course      Course       `json:"course,omitempty" db:"course"`
+	Status      TicketStatus `json:"status" db:"status"`
+	Items       []OrderItem  `json:"items"`
+	FiredAt     *time.Time   `json:"fired_at,omitempty" db:"fired_at"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time   `json:"started_at,omitempty" db:"started_at"`
+	BumpedAt    *time.Time   `json:"bumped_at,omitempty" db:"bumped_at"`
+	RecalledAt  *time.Time   `json:"recalled_at,omitempty" db:"recalled_at"`
+	RecallCount int          `json:"recall_count,omitempty" db:"recall_count"`
+	// Allergens are those of every line on the ticket; AllergenAlerts are the ones the customer
+	// declared, for the station to highlight
+	Allergens      Allergens `json:"allergens,omitempty"`
+	AllergenAlerts Allergens `json:"allergen_alerts,omitempty"`
+}
+
+// ExpoOrder is an order with all of its station tickets; Ready is set once every ticket is bumped.
+type ExpoOrder struct {
+	Order   *Order          `json:"order"`
+	Tickets []KitchenTicket `json:"tickets"`
+	Ready   bool            `json:"ready"`
+}
+
+type CreateStationRequest struct {
+	RestaurantID string `json:"restaurant_id,omitempty"`
+	Name         string `json:"name" binding:"required"`
+	IsDefault    bool   `json:"is_default,omitempty"`
+}
+
+// StationRouteRequest must set exactly one of Category and MenuItemID
+type StationRouteRequest struct {
+	Category   string `json:"category,omitempty"`
+	MenuItemID string `json:"menu_item_id,omitempty"`
+}
+
+type UpdateTicketStatusRequest struct {
+	Status TicketStatus `json:"status" binding:"required"`
+}
+
+// StationMetrics are the ticket times of one station over a period. Ticket time runs from the
+// station receiving a ticket to its final bump; prep time from the start to the bump.
+type StationMetrics struct {
+	StationID        string  `json:"station_id,omitempty"`
+	StationName      string  `json:"station_name,omitempty"`
+	Tickets          int     `json:"tickets"`
+	AvgTicketSeconds float64 `json:"avg_ticket_seconds"`
+	AvgPrepSeconds   float64 `json:"avg_prep_seconds"`
+	LateTickets      int     `json:"late_tickets"`
+	Recalls          int     `json:"recalls"`
+}
+
+// HourlyThroughput counts the tickets a station bumped in one hour
+type HourlyThroughput struct {
+	Hour      time.Time `json:"hour"`
+	StationID string    `json:"station_id,omitempty"`
+	Tickets   int       `json:"tickets"`
+}
+
+// KitchenMetrics reports kitchen performance for tickets bumped between From and To. A ticket
+// is late when it was bumped after its order's estimated ready time.
+type KitchenMetrics struct {
+	From       time.Time          `json:"from"`
+	To         time.Time          `json:"to"`
+	Total      StationMetrics     `json:"total"`
+	Stations   []StationMetrics   `json:"stations"`
+	Throughput []HourlyThroughput `json:"throughput"`
+}
@@ -6,43 +6,48 @@ import (
 	"gorm.io/gorm"
 )
 
-// Menu Categories
+// Menu Categories; menu items are the menu_items rows of models.MenuItem
 type MenuCategory struct {
 	ID           string         `json:"id" gorm:"primaryKey;type:text"`
 	RestaurantID string         `json:"restaurant_id" gorm:"index;type:text;not null"`
 	Name         string         `json:"name" gorm:"type:text;not null"`
+	ScheduleID   string         `json:"schedule_id,omitempty" gorm:"type:text"`
+	ImageURL     string         `json:"image_url,omitempty" gorm:"type:text"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	// ImageVariants are the sizes of the uploaded image
+	ImageVariants ImageVariants `json:"image_variants,omitempty" gorm:"type:text"`
 }
 
-// Menu Items
-type MenuItemGorm struct {
-	ID           string         `json:"id" gorm:"primaryKey;type:text"`
-	CategoryID   string         `json:"category_id" gorm:"index;type:text;not null"`
-	RestaurantID string         `json:"restaurant_id" gorm:"index;type:text;not null"`
-	Name         string         `json:"name" gorm:"type:text;not null"`
-	Description  string         `json:"description" gorm:"type:text"`
-	Price        float64        `json:"price" gorm:"not null"`
-	Available    bool           `json:"available" gorm:"default:true"`
-	ImageURL     string         `json:"image_url" gorm:"type:text"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-}
-
-// Menu Variants
+// Menu Variants; allergens add to the item's, dietary tags are the claims the variant still meets.
+// An untagged variant keeps the item's claims, and the QR menu's dietary filter hides variants
+// that break them.
 type MenuVariant struct {
-	ID         string  `json:"id" gorm:"primaryKey;type:text"`
-	ItemID     string  `json:"item_id" gorm:"index;type:text;not null"`
-	Name       string  `json:"name" gorm:"type:text;not null"`
-	PriceDelta float64 `json:"price_delta" gorm:"not null"`
+	ID         string      `json:"id" gorm:"primaryKey;type:text"`
+	ItemID     string      `json:"item_id" gorm:"index;type:text;not null"`
+	Name       string      `json:"name" gorm:"type:text;not null"`
+	PriceDelta float64     `json:"price_delta" gorm:"not null"`
+	Allergens  Allergens   `json:"allergens,omitempty" gorm:"type:text"`
+	Dietary    DietaryTags `json:"dietary,omitempty" gorm:"type:text"`
 }
 
-// Menu Add-ons
+// Menu Add-ons; tagged like variants
 type MenuAddon struct {
-	ID         string  `json:"id" gorm:"primaryKey;type:text"`
-	ItemID     string  `json:"item_id" gorm:"index;type:text;not null"`
-	Name       string  `json:"name" gorm:"type:text;not null"`
-	PriceDelta float64 `json:"price_delta" gorm:"not null"`
+	ID         string      `json:"id" gorm:"primaryKey;type:text"`
+	ItemID     string      `json:"item_id" gorm:"index;type:text;not null"`
+	Name       string      `json:"name" gorm:"type:text;not null"`
+	PriceDelta float64     `json:"price_delta" gorm:"not null"`
+	Allergens  Allergens   `json:"allergens,omitempty" gorm:"type:text"`
+	Dietary    DietaryTags `json:"dietary,omitempty" gorm:"type:text"`
+}
+
+// MenuItemOverride is a branch's own price or availability for a menu item it offers; a nil
+// field follows the item.
+type MenuItemOverride struct {
+	RestaurantID string    `json:"restaurant_id"`
+	MenuItemID   string    `json:"menu_item_id"`
+	Price        *float64  `json:"price,omitempty"`
+	Available    *bool     `json:"available,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
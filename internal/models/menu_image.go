@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Sizes an uploaded menu image is stored in
+const (
+	ImageThumbnail = "thumbnail"
+	ImageCard      = "card"
+	ImageFull      = "full"
+)
+
+// ImageVariants holds the URL of each size of an uploaded image, by size name. The full size
+// is also the entry's ImageURL.
+type ImageVariants map[string]string
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string(v))
+	return string(b), err
+}
+
+func (v *ImageVariants) Scan(src interface{}) error {
+	return scanJSONList(src, v)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// HolidayRule says how a menu schedule treats the restaurant's holidays
+type HolidayRule string
+
+const (
+	// HolidaysAsUsual runs the schedule on holidays like any other day
+	HolidaysAsUsual HolidayRule = ""
+	// HolidaysClosed takes the menu off on holidays
+	HolidaysClosed HolidayRule = "closed"
+	// HolidaysOnly serves the menu on holidays only
+	HolidaysOnly HolidayRule = "only"
+)
+
+func (r HolidayRule) IsValid() bool {
+	switch r {
+	case HolidaysAsUsual, HolidaysClosed, HolidaysOnly:
+		return true
+	}
+	return false
+}
+
+const (
+	scheduleDate  = "2006-01-02"
+	scheduleClock = "15:04"
+)
+
+// ScheduleWindow is a daily time window on some days of the week (0 is Sunday; none means
+// every day). Start and End are "15:04" in the restaurant's time zone; an End at or before
+// Start runs past midnight, and the hours after midnight belong to the day the window opened.
+type ScheduleWindow struct {
+	Days  []time.Weekday `json:"days,omitempty"`
+	Start string         `json:"start"`
+	End   string         `json:"end"`
+}
+
+func clockMinutes(v string) (int, error) {
+	t, err := time.Parse(scheduleClock, v)
+	if err != nil {
+		return 0, errors.New("time " + v + " must be HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w ScheduleWindow) Validate() error {
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return errors.New("days must be 0 (Sunday) to 6 (Saturday)")
+		}
+	}
+	if _, err := clockMinutes(w.Start); err != nil {
+		return err
+	}
+	_, err := clockMinutes(w.End)
+	return err
+}
+
+func (w ScheduleWindow) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, x := range w.Days {
+		if x == d {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether local falls in the window; the window must be valid
+func (w ScheduleWindow) contains(local time.Time) bool {
+	start, _ := clockMinutes(w.Start)
+	end, _ := clockMinutes(w.End)
+	now := local.Hour()*60 + local.Minute()
+	if start < end {
+		return w.onDay(local.Weekday()) && now >= start && now < end
+	}
+	return (now >= start && w.onDay(local.Weekday())) || (now < end && w.onDay((local.Weekday()+6)%7))
+}
+
+// ScheduleWindows is stored as a JSON array
+type ScheduleWindows []ScheduleWindow
+
+func (w ScheduleWindows) Value() (driver.Value, error) {
+	return jsonListValue(w)
+}
+
+func (w *ScheduleWindows) Scan(src interface{}) error {
+	return scanJSONList(src, w)
+}
+
+// MenuSchedule is a daypart such as breakfast or late night. Categories and items that carry
+// it are on the menu only while it is open: inside StartDate..EndDate (inclusive, either may be
+// empty), within one of its Windows (none means all day), and as Holidays allows.
+type MenuSchedule struct {
+	ID           string          `json:"id"`
+	RestaurantID string          `json:"restaurant_id"`
+	Name         string          `json:"name"`
+	Windows      ScheduleWindows `json:"windows"`
+	StartDate    string          `json:"start_date,omitempty"`
+	EndDate      string          `json:"end_date,omitempty"`
+	Holidays     HolidayRule     `json:"holidays,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+func (s MenuSchedule) Validate() error {
+	if s.RestaurantID == "" || s.Name == "" {
+		return errors.New("restaurant_id and name required")
+	}
+	for _, w := range s.Windows {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, d := range []string{s.StartDate, s.EndDate} {
+		if _, err := time.Parse(scheduleDate, d); d != "" && err != nil {
+			return errors.New("date " + d + " must be YYYY-MM-DD")
+		}
+	}
+	if s.StartDate != "" && s.EndDate != "" && s.EndDate < s.StartDate {
+		return errors.New("end_date is before start_date")
+	}
+	if !s.Holidays.IsValid() {
+		return errors.New("holidays must be closed or only")
+	}
+	return nil
+}
+
+// OpenAt reports whether the schedule is open at local, a time in the restaurant's zone.
+// holiday says whether local's date is one of the restaurant's holidays.
+func (s MenuSchedule) OpenAt(local time.Time, holiday bool) bool {
+	day := local.Format(scheduleDate)
+	if (s.StartDate != "" && day < s.StartDate) || (s.EndDate != "" && day > s.EndDate) {
+		return false
+	}
+	if (s.Holidays == HolidaysClosed && holiday) || (s.Holidays == HolidaysOnly && !holiday) {
+		return false
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// MenuHoliday is a date, in the restaurant's time zone, that holiday-aware schedules treat specially
+type MenuHoliday struct {
+	RestaurantID string `json:"restaurant_id"`
+	Date         string `json:"date"`
+	Name         string `json:"name,omitempty"`
+}
+
+func (h MenuHoliday) Validate() error {
+	if h.RestaurantID == "" {
+		return errors.New("restaurant_id required")
+	}
+	if _, err := time.Parse(scheduleDate, h.Date); err != nil {
+		return errors.New("date must be YYYY-MM-DD")
+	}
+	return nil
+}
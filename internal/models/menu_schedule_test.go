@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMenuScheduleWindows(t *testing.T) {
+	at := func(v string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", v)
+		assert.NoError(t, err)
+		return ts
+	}
+	breakfast := MenuSchedule{RestaurantID: "r1", Name: "Breakfast", Windows: ScheduleWindows{
+		{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: "07:00", End: "11:00"},
+	}}
+	assert.NoError(t, breakfast.Validate())
+	assert.True(t, breakfast.OpenAt(at("2026-10-12 07:00"), false)) // Monday
+	assert.False(t, breakfast.OpenAt(at("2026-10-12 11:00"), false))
+	assert.False(t, breakfast.OpenAt(at("2026-10-11 08:00"), false)) // Sunday
+
+	lateNight := MenuSchedule{RestaurantID: "r1", Name: "Late night", Windows: ScheduleWindows{
+		{Days: []time.Weekday{time.Friday, time.Saturday}, Start: "22:00", End: "02:00"},
+	}}
+	assert.True(t, lateNight.OpenAt(at("2026-10-16 23:30"), false))  // Friday
+	assert.True(t, lateNight.OpenAt(at("2026-10-18 01:30"), false))  // early Sunday, Saturday's window
+	assert.False(t, lateNight.OpenAt(at("2026-10-19 01:30"), false)) // early Monday
+}
+
+func TestMenuScheduleDatesAndHolidays(t *testing.T) {
+	noon := time.Date(2026, 12, 25, 12, 0, 0, 0, time.UTC)
+	festive := MenuSchedule{RestaurantID: "r1", Name: "Festive", StartDate: "2026-12-01", EndDate: "2026-12-31", Holidays: HolidaysOnly}
+	assert.NoError(t, festive.Validate())
+	assert.True(t, festive.OpenAt(noon, true))
+	assert.False(t, festive.OpenAt(noon, false))
+	assert.False(t, festive.OpenAt(noon.AddDate(0, 1, 0), true))
+
+	lunch := MenuSchedule{RestaurantID: "r1", Name: "Lunch", Holidays: HolidaysClosed}
+	assert.True(t, lunch.OpenAt(noon, false))
+	assert.False(t, lunch.OpenAt(noon, true))
+
+	assert.Error(t, MenuSchedule{RestaurantID: "r1", Name: "x", Windows: ScheduleWindows{{Start: "7am", End: "11:00"}}}.Validate())
+	assert.Error(t, MenuSchedule{RestaurantID: "r1", Name: "x", StartDate: "2026-12-31", EndDate: "2026-12-01"}.Validate())
+}
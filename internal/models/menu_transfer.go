@@ -0,0 +1,189 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MenuRowType says what a menu import or export row describes
+type MenuRowType string
+
+const (
+	MenuRowCategory MenuRowType = "category"
+	MenuRowItem     MenuRowType = "item"
+	MenuRowVariant  MenuRowType = "variant"
+	MenuRowAddon    MenuRowType = "addon"
+)
+
+// MenuRow is one record of a menu import or export. Items name their category, which is
+// created if the menu lacks it; variants and add-ons name their item, which must be on the
+// menu or earlier in the file. Price is a variant or add-on's price delta.
+type MenuRow struct {
+	Type          MenuRowType `json:"type"`
+	Category      string      `json:"category,omitempty"`
+	Item          string      `json:"item,omitempty"`
+	Name          string      `json:"name"`
+	Description   string      `json:"description,omitempty"`
+	Price         float64     `json:"price"`
+	Available     *bool       `json:"available,omitempty"`
+	NameAm        string      `json:"name_am,omitempty"`
+	DescriptionAm string      `json:"description_am,omitempty"`
+	Allergens     Allergens   `json:"allergens,omitempty"`
+	Dietary       DietaryTags `json:"dietary,omitempty"`
+	ImageURL      string      `json:"image_url,omitempty"`
+	SpecialNotes  string      `json:"special_notes,omitempty"`
+	// cellErr is a CSV cell that could not be read, reported when the row is validated
+	cellErr string
+}
+
+func (r MenuRow) Validate() error {
+	if r.cellErr != "" {
+		return errors.New(r.cellErr)
+	}
+	if r.Name == "" {
+		return errors.New("name required")
+	}
+	switch r.Type {
+	case MenuRowCategory:
+	case MenuRowItem:
+		if r.Category == "" {
+			return errors.New("category required")
+		}
+		if r.Price <= 0 {
+			return errors.New("price must be positive")
+		}
+	case MenuRowVariant, MenuRowAddon:
+		if r.Item == "" {
+			return errors.New("item required")
+		}
+	default:
+		return errors.New("type must be category, item, variant or addon")
+	}
+	return ValidateTags(r.Allergens, r.Dietary)
+}
+
+// MenuImportError is a problem with one import row; rows count from 1, after any CSV header
+type MenuImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// MenuImportResult reports what an import created and updated. Nothing is applied when
+// there are errors or on a dry run.
+type MenuImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Applied bool              `json:"applied"`
+	Created map[string]int    `json:"created"`
+	Updated map[string]int    `json:"updated"`
+	Errors  []MenuImportError `json:"errors"`
+}
+
+// menuCSVColumns is the CSV header; allergens and dietary tags are comma-separated in one cell
+var menuCSVColumns = []string{"type", "category", "item", "name", "description", "price", "available", "name_am", "description_am", "allergens", "dietary", "image_url", "special_notes"}
+
+// ReadMenuCSV parses a CSV menu whose header names its columns, in any order. A cell that
+// cannot be read fails its row's validation, so rows keep their numbers.
+func ReadMenuCSV(r io.Reader) ([]MenuRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["type"]; !ok {
+		return nil, errors.New("header must include a type column")
+	}
+
+	var rows []MenuRow
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		row := MenuRow{
+			Type: MenuRowType(get("type")), Category: get("category"), Item: get("item"), Name: get("name"), Description: get("description"),
+			NameAm: get("name_am"), DescriptionAm: get("description_am"), ImageURL: get("image_url"), SpecialNotes: get("special_notes"),
+		}
+		if v := get("price"); v != "" {
+			if row.Price, err = strconv.ParseFloat(v, 64); err != nil {
+				row.cellErr = "price is not a number"
+			}
+		}
+		if v := get("available"); v != "" {
+			if b, err := strconv.ParseBool(v); err != nil {
+				row.cellErr = "available must be true or false"
+			} else {
+				row.Available = &b
+			}
+		}
+		for _, a := range splitCell(get("allergens")) {
+			row.Allergens = append(row.Allergens, Allergen(a))
+		}
+		for _, d := range splitCell(get("dietary")) {
+			row.Dietary = append(row.Dietary, DietaryTag(d))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// WriteMenuCSV writes rows in the layout ReadMenuCSV reads
+func WriteMenuCSV(w io.Writer, rows []MenuRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(menuCSVColumns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		price, available := "", ""
+		if r.Type != MenuRowCategory {
+			price = strconv.FormatFloat(r.Price, 'f', -1, 64)
+		}
+		if r.Available != nil {
+			available = strconv.FormatBool(*r.Available)
+		}
+		allergens := make([]string, len(r.Allergens))
+		for i, a := range r.Allergens {
+			allergens[i] = string(a)
+		}
+		dietary := make([]string, len(r.Dietary))
+		for i, d := range r.Dietary {
+			dietary[i] = string(d)
+		}
+		if err := cw.Write([]string{string(r.Type), r.Category, r.Item, r.Name, r.Description, price, available, r.NameAm, r.DescriptionAm,
+			strings.Join(allergens, ","), strings.Join(dietary, ","), r.ImageURL, r.SpecialNotes}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func splitCell(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMenuCSVRoundTrip(t *testing.T) {
+	yes := true
+	rows := []MenuRow{
+		{Type: MenuRowCategory, Name: "Burgers"},
+		{Type: MenuRowItem, Category: "Burgers", Name: "Cheeseburger", Description: "Beef, cheddar, pickles", Price: 7.5, Available: &yes,
+			NameAm: "ቺዝበርገር", Allergens: Allergens{AllergenGluten, AllergenMilk}, Dietary: DietaryTags{DietHalal}},
+		{Type: MenuRowVariant, Item: "Cheeseburger", Name: "Double", Price: 3},
+		{Type: MenuRowAddon, Item: "Cheeseburger", Name: "Bacon", Price: 1.25},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, WriteMenuCSV(&buf, rows))
+	got, err := ReadMenuCSV(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, rows, got)
+}
+
+func TestReadMenuCSVRowErrors(t *testing.T) {
+	rows, err := ReadMenuCSV(strings.NewReader("name,type,price\nFries,item,abc\nCola,drink,2\n,category,\n"))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.EqualError(t, rows[0].Validate(), "price is not a number")
+	assert.EqualError(t, rows[1].Validate(), "type must be category, item, variant or addon")
+	assert.EqualError(t, rows[2].Validate(), "name required")
+
+	_, err = ReadMenuCSV(strings.NewReader("name,price\nFries,2\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// MenuVersionStatus is where a menu version is in its life: drafts are edited, a scheduled
+// version waits for PublishAt, and publishing one archives the version it replaces.
+type MenuVersionStatus string
+
+const (
+	MenuVersionDraft     MenuVersionStatus = "draft"
+	MenuVersionScheduled MenuVersionStatus = "scheduled"
+	MenuVersionPublished MenuVersionStatus = "published"
+	MenuVersionArchived  MenuVersionStatus = "archived"
+)
+
+// MenuVersion is a numbered snapshot of a restaurant's own menu. At most one version per
+// restaurant is published, and it is what the live menu was last set to.
+type MenuVersion struct {
+	ID           string            `json:"id"`
+	RestaurantID string            `json:"restaurant_id"`
+	Number       int               `json:"number"`
+	Status       MenuVersionStatus `json:"status"`
+	Note         string            `json:"note,omitempty"`
+	// BasedOn is the version the draft was copied from; empty when copied from the live menu
+	BasedOn     string     `json:"based_on,omitempty"`
+	Rows        MenuRows   `json:"rows,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// Snapshot is the whole menu the version publishes: schedules, categories, items and their
+	// options, modifier groups, combos, branch prices and translations. Rows are the same menu
+	// as import rows. Versions saved before snapshots were kept have only rows.
+	Snapshot MenuSnapshot `json:"snapshot,omitempty"`
+}
+
+// MenuRows is a menu snapshot, stored as a JSON array
+type MenuRows []MenuRow
+
+func (r MenuRows) Value() (driver.Value, error) {
+	return jsonListValue(r)
+}
+
+func (r *MenuRows) Scan(src interface{}) error {
+	return scanJSONList(src, r)
+}
+
+// MenuSnapshot holds a restaurant's menu tables as stored: each table's rows by column name,
+// kept as a JSON object
+type MenuSnapshot map[string][]map[string]interface{}
+
+func (s MenuSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *MenuSnapshot) Scan(src interface{}) error {
+	return scanJSONList(src, s)
+}
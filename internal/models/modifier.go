@@ -0,0 +1,77 @@
+package models
+
+import "errors"
+
+// ModifierGroup is a set of choices offered on a menu item, such as "Sauce (choose 1)" or
+// "Toppings (up to 3, first 2 free)". A group with a ParentOptionID is nested: it is only
+// offered once that option is chosen, e.g. a steak's doneness. ItemID is always the menu item
+// the group belongs to, nested or not.
+//
+// MinSelect 0 makes the group optional and MaxSelect 0 leaves it unlimited. The first
+// FreeQuantity choices, in the order the guest made them, are not charged. When a guest makes
+// no choice in a group its default options are used.
+type ModifierGroup struct {
+	ID             string           `json:"id"`
+	ItemID         string           `json:"item_id"`
+	ParentOptionID string           `json:"parent_option_id,omitempty"`
+	Name           string           `json:"name"`
+	MinSelect      int              `json:"min_select"`
+	MaxSelect      int              `json:"max_select"`
+	FreeQuantity   int              `json:"free_quantity,omitempty"`
+	Position       int              `json:"position"`
+	Options        []ModifierOption `json:"options"`
+}
+
+func (g ModifierGroup) Validate() error {
+	if g.Name == "" {
+		return errors.New("name required")
+	}
+	if g.MinSelect < 0 || g.MaxSelect < 0 || g.FreeQuantity < 0 {
+		return errors.New("min_select, max_select and free_quantity cannot be negative")
+	}
+	if g.MaxSelect > 0 && g.MaxSelect < g.MinSelect {
+		return errors.New("max_select is below min_select")
+	}
+	return nil
+}
+
+// ModifierOption is one choice in a modifier group; Groups are the groups nested under it.
+// Allergens and dietary tags work as on variants and add-ons.
+type ModifierOption struct {
+	ID         string          `json:"id"`
+	GroupID    string          `json:"group_id"`
+	Name       string          `json:"name"`
+	PriceDelta float64         `json:"price_delta"`
+	IsDefault  bool            `json:"is_default,omitempty"`
+	Allergens  Allergens       `json:"allergens,omitempty"`
+	Dietary    DietaryTags     `json:"dietary,omitempty"`
+	Position   int             `json:"position"`
+	Groups     []ModifierGroup `json:"groups,omitempty"`
+}
+
+// ModifierSelection is an option chosen on an order line, with the choices made in the
+// groups nested under it
+type ModifierSelection struct {
+	OptionID  string              `json:"option_id"`
+	Modifiers []ModifierSelection `json:"modifiers,omitempty"`
+}
+
+// OrderItemModifier is a snapshot of a chosen modifier as it was priced when the line was
+// ordered. Nested choices carry the option they were made under as ParentOptionID.
+type OrderItemModifier struct {
+	GroupID        string `json:"group_id"`
+	GroupName      string `json:"group_name"`
+	OptionID       string `json:"option_id"`
+	Name           string `json:"name"`
+	PriceDelta     Money  `json:"price_delta"`
+	Free           bool   `json:"free,omitempty"`
+	ParentOptionID string `json:"parent_option_id,omitempty"`
+}
+
+// Charge is what the modifier adds to the line's unit price
+func (m OrderItemModifier) Charge() Money {
+	if m.Free {
+		return 0
+	}
+	return m.PriceDelta
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for orders that are not tied to a restaurant.
+const DefaultCurrency = "ETB"
+
+// Money is an amount in minor units (cents) of the currency held by the record it belongs to.
+// It is stored as an integer in the database and encoded in JSON as a decimal in major
+// units (12.50), which is also the format payment gateways expect.
+type Money int64
+
+// MoneyFromFloat converts a major-unit amount, rounding to the nearest cent.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// ParseMoney parses a decimal such as "12.5" or "-3.05" without going through float64.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errors.New("invalid amount")
+	}
+	if len(frac) > 2 {
+		return 0, errors.New("amount has more than two decimal places")
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	var units int64
+	if whole != "" {
+		var err error
+		if units, err = strconv.ParseInt(whole, 10, 64); err != nil || units < 0 {
+			return 0, errors.New("invalid amount")
+		}
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, errors.New("invalid amount")
+	}
+	m := Money(units*100 + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// String formats m in major units with two decimals, e.g. "12.50".
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	cents := strconv.FormatInt(int64(m%100), 10)
+	if len(cents) == 1 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatInt(int64(m/100), 10) + "." + cents
+}
+
+// Float64 returns m in major units; only use it for display or ratios, never for sums.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Times multiplies a unit price by a quantity.
+func (m Money) Times(qty int) Money {
+	return m * Money(qty)
+}
+
+// Percent returns rate percent of m, rounded to the nearest cent.
+func (m Money) Percent(rate float64) Money {
+	return Money(math.Round(float64(m) * rate / 100))
+}
+
+// Split divides m into n shares that add back up to m exactly; the first shares
+// absorb the remainder one cent each.
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	share, rem := m/Money(n), m%Money(n)
+	out := make([]Money, n)
+	for i := range out {
+		out[i] = share
+		if Money(i) < rem {
+			out[i]++
+		}
+	}
+	return out
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or string in major units.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	for in, want := range map[string]Money{"12.5": 1250, "0.07": 7, "-3.05": -305, "100": 10000, ".5": 50} {
+		got, err := ParseMoney(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParseMoney("1.234")
+	assert.Error(t, err)
+	_, err = ParseMoney("abc")
+	assert.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Amount: 10050})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":100.50}`, string(b))
+
+	var in struct {
+		Amount Money `json:"amount"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":19.99}`), &in))
+	assert.Equal(t, Money(1999), in.Amount)
+}
+
+func TestMoneySplit(t *testing.T) {
+	shares := Money(1000).Split(3)
+	assert.Equal(t, []Money{334, 333, 333}, shares)
+	assert.Equal(t, "-0.05", Money(-5).String())
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -15,39 +16,216 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// OrderType is how an order is fulfilled
+type OrderType string
+
+const (
+	OrderTypeDineIn   OrderType = "dine_in"
+	OrderTypeTakeaway OrderType = "takeaway"
+	OrderTypeDelivery OrderType = "delivery"
+)
+
+func (t OrderType) IsValid() bool {
+	switch t {
+	case OrderTypeDineIn, OrderTypeTakeaway, OrderTypeDelivery:
+		return true
+	}
+	return false
+}
+
+// SplitType records how a derived order was produced from its parent
+type SplitType string
+
+const (
+	SplitByItem     SplitType = "items"
+	SplitByQuantity SplitType = "quantity"
+	SplitEvenly     SplitType = "even"
+)
+
+// IsValid reports whether s is one of the known order statuses
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusPreparing, OrderStatusReady, OrderStatusCompleted, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Order is a customer order. OrderNumber is the short ticket number staff call out; it restarts
+// at 1 every BusinessDate (the calendar day in the restaurant's time zone), so it is only unique
+// together with the restaurant and that date. MenuVersionID is the published menu version the
+// order was priced against.
 type Order struct {
-	ID          string      `json:"id" db:"id"`
-	CustomerID  string      `json:"customer_id" db:"customer_id"`
-	SessionID   string      `json:"session_id,omitempty" db:"session_id"`
-	Items       []OrderItem `json:"items" db:"items"`
-	TotalAmount float64     `json:"total_amount" db:"total_amount"`
-	Status      OrderStatus `json:"status" db:"status"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	ID                string       `json:"id" db:"id"`
+	OrderNumber       int          `json:"order_number,omitempty" db:"order_number"`
+	BusinessDate      string       `json:"business_date,omitempty" db:"business_date"`
+	CustomerID        string       `json:"customer_id" db:"customer_id"`
+	SessionID         string       `json:"session_id,omitempty" db:"session_id"`
+	Type              OrderType    `json:"type" db:"type"`
+	TableID           string       `json:"table_id,omitempty" db:"table_id"`
+	PickupAt          *time.Time   `json:"pickup_at,omitempty" db:"pickup_at"`
+	DeliveryAddress   string       `json:"delivery_address,omitempty" db:"delivery_address"`
+	RestaurantID      string       `json:"restaurant_id,omitempty" db:"restaurant_id"`
+	DiscountID        string       `json:"discount_id,omitempty" db:"discount_id"`
+	MenuVersionID     string       `json:"menu_version_id,omitempty" db:"menu_version_id"`
+	Items             []OrderItem  `json:"items" db:"items"`
+	TotalAmount       Money        `json:"total_amount" db:"total_amount"`
+	Currency          string       `json:"currency" db:"currency"`
+	Pricing           OrderPricing `json:"pricing"`
+	Status            OrderStatus  `json:"status" db:"status"`
+	ParentOrderID     string       `json:"parent_order_id,omitempty" db:"parent_order_id"`
+	SplitType         SplitType    `json:"split_type,omitempty" db:"split_type"`
+	MergedIntoOrderID string       `json:"merged_into_order_id,omitempty" db:"merged_into_order_id"`
+	ClientOrderID     string       `json:"client_order_id,omitempty" db:"client_order_id"`
+	ClientCreatedAt   *time.Time   `json:"client_created_at,omitempty" db:"client_created_at"`
+	EstimatedReadyAt  *time.Time   `json:"estimated_ready_at,omitempty" db:"estimated_ready_at"`
+	ETAManual         bool         `json:"eta_manual,omitempty" db:"eta_manual"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// OrderPricing is the stored breakdown of an order's total. Total always equals TotalAmount;
+// with TaxInclusive the tax is already contained in Subtotal and is shown for information only.
+type OrderPricing struct {
+	Subtotal      Money `json:"subtotal"`
+	Discount      Money `json:"discount"`
+	ServiceCharge Money `json:"service_charge"`
+	DeliveryFee   Money `json:"delivery_fee"`
+	Tax           Money `json:"tax"`
+	TaxInclusive  bool  `json:"tax_inclusive"`
+	Rounding      Money `json:"rounding"`
+	Total         Money `json:"total"`
 }
 
 type OrderItem struct {
-	ID                  string  `json:"id" db:"id"`
-	OrderID             string  `json:"order_id" db:"order_id"`
-	MenuItemID          string  `json:"menu_item_id" db:"menu_item_id"`
-	Name                string  `json:"name" db:"name"`
-	Price               float64 `json:"price" db:"price"`
-	Quantity            int     `json:"quantity" db:"quantity"`
-	TotalPrice          float64 `json:"total_price" db:"total_price"`
-	SpecialInstructions string  `json:"special_instructions,omitempty" db:"special_instructions"`
+	ID                  string              `json:"id" db:"id"`
+	OrderID             string              `json:"order_id" db:"order_id"`
+	MenuItemID          string              `json:"menu_item_id" db:"menu_item_id"`
+	Name                string              `json:"name" db:"name"`
+	Price               Money               `json:"price" db:"price"`
+	Quantity            int                 `json:"quantity" db:"quantity"`
+	TotalPrice          Money               `json:"total_price" db:"total_price"`
+	SpecialInstructions string              `json:"special_instructions,omitempty" db:"special_instructions"`
+	VariantID           string              `json:"variant_id,omitempty" db:"variant_id"`
+	VariantName         string              `json:"variant_name,omitempty" db:"variant_name"`
+	VariantPriceDelta   Money               `json:"variant_price_delta,omitempty" db:"variant_price_delta"`
+	Addons              []OrderItemAddon    `json:"addons,omitempty" db:"addons"`
+	Modifiers           []OrderItemModifier `json:"modifiers,omitempty" db:"modifiers"`
+	VoidedAt            *time.Time          `json:"voided_at,omitempty" db:"voided_at"`
+	VoidReason          VoidReason          `json:"void_reason,omitempty" db:"void_reason"`
+	TicketID            string              `json:"ticket_id,omitempty" db:"ticket_id"`
+	Course              Course              `json:"course,omitempty" db:"course"`
+	Allergens           Allergens           `json:"allergens,omitempty" db:"allergens"`
+	AllergenAlerts      Allergens           `json:"allergen_alerts,omitempty" db:"allergen_alerts"`
+	// A combo line carries ComboID and the price; its components point back with ParentItemID,
+	// go to the kitchen in its place, and hold their share of its total in AllocatedTotal.
+	ComboID        string `json:"combo_id,omitempty" db:"combo_id"`
+	ParentItemID   string `json:"parent_item_id,omitempty" db:"parent_item_id"`
+	ComboSlot      string `json:"combo_slot,omitempty" db:"combo_slot"`
+	AllocatedTotal Money  `json:"allocated_total,omitempty" db:"allocated_total"`
+}
+
+// Course groups dine-in lines that are served together. Lines without a course go to the
+// kitchen straight away.
+type Course string
+
+const (
+	CourseStarter Course = "starter"
+	CourseMain    Course = "main"
+	CourseDessert Course = "dessert"
+)
+
+// courseRank orders courses as they are served; 0 means no course
+var courseRank = map[Course]int{CourseStarter: 1, CourseMain: 2, CourseDessert: 3}
+
+func (c Course) IsValid() bool {
+	_, ok := courseRank[c]
+	return ok || c == ""
 }
 
+// Rank is the course's position in the meal, 0 for lines without a course
+func (c Course) Rank() int {
+	return courseRank[c]
+}
+
+// VoidReason is the reason code recorded when a line is removed or voided
+type VoidReason string
+
+const (
+	VoidCustomerRequest VoidReason = "customer_request"
+	VoidWrongItem       VoidReason = "wrong_item"
+	VoidOutOfStock      VoidReason = "out_of_stock"
+	VoidQualityIssue    VoidReason = "quality_issue"
+	VoidDuplicate       VoidReason = "duplicate"
+	VoidOther           VoidReason = "other"
+)
+
+func (r VoidReason) IsValid() bool {
+	switch r {
+	case VoidCustomerRequest, VoidWrongItem, VoidOutOfStock, VoidQualityIssue, VoidDuplicate, VoidOther:
+		return true
+	}
+	return false
+}
+
+// OrderItemAddon is a snapshot of an add-on as it was priced when the line was ordered
+type OrderItemAddon struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	PriceDelta Money  `json:"price_delta"`
+}
+
+// Label renders the line as printed on tickets and receipts, e.g. "Burger (Large) + Cheese, Bacon".
+// Modifiers follow the add-ons.
+func (it OrderItem) Label() string {
+	label := it.Name
+	if it.VariantName != "" {
+		label += " (" + it.VariantName + ")"
+	}
+	var extras []string
+	for _, a := range it.Addons {
+		extras = append(extras, a.Name)
+	}
+	for _, m := range it.Modifiers {
+		extras = append(extras, m.Name)
+	}
+	if len(extras) > 0 {
+		label += " + " + strings.Join(extras, ", ")
+	}
+	return label
+}
+
+// MenuItem is an orderable dish. Items without a RestaurantID predate restaurant-scoped menus
+// and are offered by every restaurant. Category is the name of CategoryID's category, kept in
+// step with it so the QR menu and station routes can group by name. An item with a ScheduleID,
+// or in a category with one, is only served while that schedule is open. NameAm and
+// DescriptionAm are for older clients: writing them sets the item's "am" translations.
 type MenuItem struct {
-	ID            string  `json:"id" db:"id"`
-	Name          string  `json:"name" db:"name"`
-	Description   string  `json:"description" db:"description"`
-	Price         float64 `json:"price" db:"price"`
-	Category      string  `json:"category" db:"category"`
-	Available     bool    `json:"available" db:"available"`
-	ImageURL      string  `json:"image_url,omitempty" db:"image_url"`
-	SpecialNotes  string  `json:"special_notes,omitempty" db:"special_notes"`
-	NameAm        string  `json:"name_am,omitempty" db:"name_am"`
-	DescriptionAm string  `json:"description_am,omitempty" db:"description_am"`
+	ID            string      `json:"id" db:"id"`
+	RestaurantID  string      `json:"restaurant_id,omitempty" db:"restaurant_id"`
+	CategoryID    string      `json:"category_id,omitempty" db:"category_id"`
+	ScheduleID    string      `json:"schedule_id,omitempty" db:"schedule_id"`
+	Name          string      `json:"name" db:"name"`
+	Description   string      `json:"description" db:"description"`
+	Price         float64     `json:"price" db:"price"`
+	Category      string      `json:"category" db:"category"`
+	Available     bool        `json:"available" db:"available"`
+	ImageURL      string      `json:"image_url,omitempty" db:"image_url"`
+	SpecialNotes  string      `json:"special_notes,omitempty" db:"special_notes"`
+	NameAm        string      `json:"name_am,omitempty" db:"name_am"`
+	DescriptionAm string      `json:"description_am,omitempty" db:"description_am"`
+	Allergens     Allergens   `json:"allergens,omitempty" db:"allergens"`
+	Dietary       DietaryTags `json:"dietary,omitempty" db:"dietary"`
+	// ImageVariants are the sizes of an uploaded image; they are dropped when ImageURL is
+	// changed by hand
+	ImageVariants ImageVariants `json:"image_variants,omitempty" db:"image_variants"`
+	// AllergenWarnings lists the allergens a menu reader asked to be flagged
+	AllergenWarnings Allergens `json:"allergen_warnings,omitempty" db:"-"`
+	// ModifierGroups are the choices offered on the item, filled in for menu readers
+	ModifierGroups []ModifierGroup `json:"modifier_groups,omitempty" db:"-"`
+	// Variants and Addons are the item's options, filled in for menu readers
+	Variants []MenuVariant `json:"variants,omitempty" db:"-"`
+	Addons   []MenuAddon   `json:"addons,omitempty" db:"-"`
 }
 
 type Favorite struct {
@@ -64,18 +242,153 @@ type Review struct {
 	Comment    string `json:"comment,omitempty" db:"comment"`
 }
 
+// CreateOrderRequest places an order. Type defaults to dine_in when a session or table
+// is given and takeaway otherwise; a takeaway with pickup_at is a scheduled pickup.
 type CreateOrderRequest struct {
-	CustomerID string            `json:"customer_id" binding:"required"`
-	SessionID  string            `json:"session_id,omitempty"`
-	Items      []CreateOrderItem `json:"items" binding:"required"`
+	CustomerID      string            `json:"customer_id" binding:"required"`
+	SessionID       string            `json:"session_id,omitempty"`
+	RestaurantID    string            `json:"restaurant_id,omitempty"`
+	DiscountCode    string            `json:"discount_code,omitempty"`
+	Type            OrderType         `json:"type,omitempty"`
+	TableID         string            `json:"table_id,omitempty"`
+	PickupAt        *time.Time        `json:"pickup_at,omitempty"`
+	DeliveryAddress string            `json:"delivery_address,omitempty"`
+	Items           []CreateOrderItem `json:"items" binding:"required"`
+	// AcknowledgeAllergens confirms the customer accepted the allergen warnings of a previous attempt
+	AcknowledgeAllergens bool `json:"acknowledge_allergens,omitempty"`
 }
 
+// CreateOrderItem orders a menu item, or a combo when ComboID is set
 type CreateOrderItem struct {
-	MenuItemID          string `json:"menu_item_id" binding:"required"`
-	Quantity            int    `json:"quantity" binding:"required,min=1"`
-	SpecialInstructions string `json:"special_instructions,omitempty"`
+	MenuItemID          string              `json:"menu_item_id,omitempty"`
+	Quantity            int                 `json:"quantity" binding:"required,min=1"`
+	SpecialInstructions string              `json:"special_instructions,omitempty"`
+	VariantID           string              `json:"variant_id,omitempty"`
+	AddonIDs            []string            `json:"addon_ids,omitempty"`
+	Modifiers           []ModifierSelection `json:"modifiers,omitempty"`
+	ComboID             string              `json:"combo_id,omitempty"`
+	ComboChoices        []ComboSelection    `json:"combo_choices,omitempty"`
+	Course              Course              `json:"course,omitempty"`
+}
+
+type AddOrderItemsRequest struct {
+	Items                []CreateOrderItem `json:"items" binding:"required,min=1,dive"`
+	AcknowledgeAllergens bool              `json:"acknowledge_allergens,omitempty"`
+}
+
+// OrderLineChangeRequest is the body for removing or voiding a line
+type OrderLineChangeRequest struct {
+	Reason VoidReason `json:"reason" binding:"required"`
+	Note   string     `json:"note,omitempty"`
+}
+
+// OrderChange is the delta pushed to the kitchen when lines are added, removed or voided
+type OrderChange struct {
+	Action string      `json:"action"`
+	Items  []OrderItem `json:"items"`
+	Reason VoidReason  `json:"reason,omitempty"`
+	Note   string      `json:"note,omitempty"`
+}
+
+// SyncOrdersRequest is a batch of orders queued on an offline device
+type SyncOrdersRequest struct {
+	CustomerID   string      `json:"customer_id" binding:"required"`
+	SessionID    string      `json:"session_id,omitempty"`
+	RestaurantID string      `json:"restaurant_id,omitempty"`
+	Orders       []SyncOrder `json:"orders" binding:"required,dive"`
+}
+
+type SyncOrder struct {
+	ClientOrderID string            `json:"client_order_id" binding:"required"`
+	CreatedAt     *time.Time        `json:"created_at,omitempty"`
+	DiscountCode  string            `json:"discount_code,omitempty"`
+	Type          OrderType         `json:"type,omitempty"`
+	TableID       string            `json:"table_id,omitempty"`
+	PickupAt      *time.Time        `json:"pickup_at,omitempty"`
+	Items         []CreateOrderItem `json:"items" binding:"required,dive"`
+	// AcknowledgeAllergens confirms the customer accepted the allergen warnings of a previous attempt
+	AcknowledgeAllergens bool `json:"acknowledge_allergens,omitempty"`
+}
+
+// ReorderRequest is the optional body for repeating an earlier order. The earlier order's table
+// session has closed, so a repeat at a table names the guest's current session or table.
+type ReorderRequest struct {
+	SessionID string `json:"session_id,omitempty"`
+	TableID   string `json:"table_id,omitempty"`
+	// AcknowledgeAllergens confirms the customer accepted the allergen warnings of a previous attempt
+	AcknowledgeAllergens bool `json:"acknowledge_allergens,omitempty"`
+}
+
+type SyncStatus string
+
+const (
+	SyncCreated   SyncStatus = "created"
+	SyncDuplicate SyncStatus = "duplicate"
+	SyncRejected  SyncStatus = "rejected"
+)
+
+// SyncResult reports what happened to one offline order; Order is set unless it was rejected
+type SyncResult struct {
+	ClientOrderID string     `json:"client_order_id"`
+	Status        SyncStatus `json:"status"`
+	Order         *Order     `json:"order,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+}
+
+// OrderPage is one page of a filtered order listing. Total counts every match, not just this
+// page; NextCursor is empty on the last page.
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	Total      int      `json:"total"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// SalesTotal sums the orders in one currency
+type SalesTotal struct {
+	Currency string `json:"currency"`
+	Orders   int    `json:"orders"`
+	Total    Money  `json:"total"`
 }
 
 type UpdateOrderStatusRequest struct {
 	Status OrderStatus `json:"status" binding:"required"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+type SplitOrderRequest struct {
+	Mode SplitType `json:"mode" binding:"required,oneof=items quantity even"`
+	// Groups lists order item IDs per new order (mode=items)
+	Groups [][]string `json:"groups,omitempty"`
+	// Lines moves part of a line's quantity into one new order (mode=quantity)
+	Lines []SplitLine `json:"lines,omitempty"`
+	// Guests is the number of equal shares (mode=even)
+	Guests int `json:"guests,omitempty"`
+}
+
+type SplitLine struct {
+	OrderItemID string `json:"order_item_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,min=1"`
+}
+
+type MergeOrdersRequest struct {
+	TargetOrderID string   `json:"target_order_id,omitempty"`
+	OrderIDs      []string `json:"order_ids" binding:"required,min=1"`
+}
+
+type Receipt struct {
+	OrderID     string        `json:"order_id"`
+	OrderNumber int           `json:"order_number,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Lines       []ReceiptLine `json:"lines"`
+	Pricing     OrderPricing  `json:"pricing"`
+	TotalAmount Money         `json:"total_amount"`
+	Currency    string        `json:"currency"`
+}
+
+type ReceiptLine struct {
+	Label               string `json:"label"`
+	Quantity            int    `json:"quantity"`
+	UnitPrice           Money  `json:"unit_price"`
+	TotalPrice          Money  `json:"total_price"`
+	SpecialInstructions string `json:"special_instructions,omitempty"`
 }
@@ -25,7 +25,8 @@ const (
 type Payment struct {
 	ID            string        `json:"id" db:"id"`
 	OrderID       string        `json:"order_id" db:"order_id"`
-	Amount        float64       `json:"amount" db:"amount"`
+	Amount        Money         `json:"amount" db:"amount"`
+	Currency      string        `json:"currency" db:"currency"`
 	Method        PaymentMethod `json:"method" db:"method"`
 	Status        PaymentStatus `json:"status" db:"status"`
 	TransactionID string        `json:"transaction_id,omitempty" db:"transaction_id"`
@@ -43,7 +44,7 @@ type ProcessPaymentRequest struct {
 type PaymentResponse struct {
 	ID            string        `json:"id"`
 	OrderID       string        `json:"order_id"`
-	Amount        float64       `json:"amount"`
+	Amount        Money         `json:"amount"`
 	Method        PaymentMethod `json:"method"`
 	Status        PaymentStatus `json:"status"`
 	TransactionID string        `json:"transaction_id,omitempty"`
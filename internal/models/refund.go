@@ -13,7 +13,8 @@ const (
 type Refund struct {
 	ID        string       `json:"id" db:"id"`
 	PaymentID string       `json:"payment_id" db:"payment_id"`
-	Amount    float64      `json:"amount" db:"amount"`
+	Amount    Money        `json:"amount" db:"amount"`
+	Currency  string       `json:"currency" db:"currency"`
 	Reason    string       `json:"reason,omitempty" db:"reason"`
 	Status    RefundStatus `json:"status" db:"status"`
 	CreatedAt time.Time    `json:"created_at" db:"created_at"`
@@ -20,7 +20,7 @@ type TelebirrOrder struct {
 	OrderID        string         `json:"order_id" gorm:"index;type:text;not null"`
 	PrepayID       string         `json:"prepay_id" gorm:"uniqueIndex;type:text;not null"`
 	MerchOrderID   string         `json:"merch_order_id" gorm:"type:text;not null"`
-	Amount         float64        `json:"amount" gorm:"not null"`
+	Amount         Money          `json:"amount" gorm:"not null"`
 	Currency       string         `json:"currency" gorm:"type:text;default:'ETB'"`
 	Subject        string         `json:"subject" gorm:"type:text"`
 	Body           string         `json:"body" gorm:"type:text"`
@@ -40,7 +40,7 @@ type TelebirrNotification struct {
 	MerchOrderID string    `json:"merch_order_id" gorm:"type:text;not null"`
 	TradeNo      string    `json:"trade_no" gorm:"type:text"`
 	TradeStatus  string    `json:"trade_status" gorm:"type:text;not null"`
-	TotalAmount  float64   `json:"total_amount" gorm:"not null"`
+	TotalAmount  Money     `json:"total_amount" gorm:"not null"`
 	Currency     string    `json:"currency" gorm:"type:text;default:'ETB'"`
 	GmtPayment   time.Time `json:"gmt_payment"`
 	Sign         string    `json:"sign" gorm:"type:text"`
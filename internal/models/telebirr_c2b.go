@@ -13,7 +13,7 @@ type TelebirrC2BOrder struct {
 	OutTradeNo     string         `json:"out_trade_no" gorm:"uniqueIndex;type:text;not null"` // Merchant order ID
 	Subject        string         `json:"subject" gorm:"type:text;not null"`
 	Body           string         `json:"body" gorm:"type:text"`
-	TotalAmount    float64        `json:"total_amount" gorm:"not null"`
+	TotalAmount    Money          `json:"total_amount" gorm:"not null"`
 	Currency       string         `json:"currency" gorm:"type:text;default:'ETB'"`
 	NotifyURL      string         `json:"notify_url" gorm:"type:text"`
 	ReturnURL      string         `json:"return_url" gorm:"type:text"`
@@ -32,7 +32,7 @@ type TelebirrC2BNotification struct {
 	OutTradeNo     string    `json:"out_trade_no" gorm:"index;type:text;not null"`
 	TradeNo        string    `json:"trade_no" gorm:"type:text;not null"`
 	TradeStatus    string    `json:"trade_status" gorm:"type:text;not null"`
-	TotalAmount    float64   `json:"total_amount" gorm:"not null"`
+	TotalAmount    Money     `json:"total_amount" gorm:"not null"`
 	Currency       string    `json:"currency" gorm:"type:text;default:'ETB'"`
 	GmtPayment     time.Time `json:"gmt_payment"`
 	PassbackParams string    `json:"passback_params" gorm:"type:text"`
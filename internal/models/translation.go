@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TranslatableEntity is a kind of menu entry whose text can be translated
+type TranslatableEntity string
+
+const (
+	TranslateCategory       TranslatableEntity = "category"
+	TranslateItem           TranslatableEntity = "item"
+	TranslateVariant        TranslatableEntity = "variant"
+	TranslateAddon          TranslatableEntity = "addon"
+	TranslateModifierGroup  TranslatableEntity = "modifier_group"
+	TranslateModifierOption TranslatableEntity = "modifier_option"
+)
+
+// translatableFields are the fields each entity has translations for
+var translatableFields = map[TranslatableEntity][]string{
+	TranslateCategory:       {"name"},
+	TranslateItem:           {"name", "description"},
+	TranslateVariant:        {"name"},
+	TranslateAddon:          {"name"},
+	TranslateModifierGroup:  {"name"},
+	TranslateModifierOption: {"name"},
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// NormalizeLocale lowercases a language tag and uses hyphens, so "am_ET" and "am-et" match
+func NormalizeLocale(l string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(l), "_", "-"))
+}
+
+// MenuTranslation is one field of a menu entry in one locale
+type MenuTranslation struct {
+	EntityType TranslatableEntity `json:"entity_type"`
+	EntityID   string             `json:"entity_id"`
+	Field      string             `json:"field"`
+	Locale     string             `json:"locale"`
+	Value      string             `json:"value"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// Validate checks the entry and normalizes its locale
+func (t *MenuTranslation) Validate() error {
+	fields, ok := translatableFields[t.EntityType]
+	if !ok {
+		return errors.New("unknown entity_type " + string(t.EntityType))
+	}
+	if t.EntityID == "" {
+		return errors.New("entity_id required")
+	}
+	known := false
+	for _, f := range fields {
+		known = known || f == t.Field
+	}
+	if !known {
+		return errors.New(string(t.EntityType) + " has no translatable field " + t.Field)
+	}
+	t.Locale = NormalizeLocale(t.Locale)
+	if !localePattern.MatchString(t.Locale) {
+		return errors.New("invalid locale " + t.Locale)
+	}
+	if strings.TrimSpace(t.Value) == "" {
+		return errors.New("value required")
+	}
+	return nil
+}
+
+// MissingTranslation is a field with text in the menu's own language but none in a locale
+type MissingTranslation struct {
+	EntityType TranslatableEntity `json:"entity_type"`
+	EntityID   string             `json:"entity_id"`
+	Field      string             `json:"field"`
+	Source     string             `json:"source"`
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header, most preferred
+// first. Wildcards and languages with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = NormalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > 0 {
+			prefs = append(prefs, pref{tag, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	out := make([]string, len(prefs))
+	for i, p := range prefs {
+		out[i] = p.tag
+	}
+	return out
+}
+
+// LocaleChain expands preferred languages into the locales to try in order: each language is
+// followed by its shorter forms before the next one, so "am-et, en" gives am-et, am, en.
+func LocaleChain(prefs ...string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	for _, p := range prefs {
+		for l := NormalizeLocale(p); l != ""; {
+			if !seen[l] && localePattern.MatchString(l) {
+				seen[l] = true
+				chain = append(chain, l)
+			}
+			i := strings.LastIndex(l, "-")
+			if i < 0 {
+				break
+			}
+			l = l[:i]
+		}
+	}
+	return chain
+}
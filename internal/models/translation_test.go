@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"am-et", "am", "en"}, ParseAcceptLanguage("en;q=0.5, am-ET, am;q=0.8, *;q=0.1"))
+	assert.Equal(t, []string{"fr"}, ParseAcceptLanguage("de;q=0, fr"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestLocaleChain(t *testing.T) {
+	assert.Equal(t, []string{"am-et", "am", "en-gb", "en"}, LocaleChain("am_ET", "en-GB", "am"))
+	assert.Equal(t, []string{"om"}, LocaleChain("", "om", "not a locale"))
+}
+
+func TestMenuTranslationValidate(t *testing.T) {
+	tr := MenuTranslation{EntityType: TranslateItem, EntityID: "i1", Field: "description", Locale: "AM", Value: "ጣፋጭ"}
+	assert.NoError(t, tr.Validate())
+	assert.Equal(t, "am", tr.Locale)
+
+	tr.EntityType = TranslateVariant
+	assert.Error(t, tr.Validate())
+	tr.EntityType, tr.Field, tr.Value = TranslateCategory, "name", " "
+	assert.Error(t, tr.Validate())
+}
@@ -15,15 +15,16 @@ import (
 	"time"
 
 	"restaurant-system/internal/config"
+	"restaurant-system/internal/models"
 )
 
 type InitiateRequest struct {
-	OutTradeNo  string  `json:"outTradeNo"`
-	Subject     string  `json:"subject"`
-	TotalAmount float64 `json:"totalAmount"`
-	ReturnUrl   string  `json:"returnUrl"`
-	NotifyUrl   string  `json:"notifyUrl"`
-	PhoneNumber string  `json:"msisdn,omitempty"`
+	OutTradeNo  string       `json:"outTradeNo"`
+	Subject     string       `json:"subject"`
+	TotalAmount models.Money `json:"totalAmount"`
+	ReturnUrl   string       `json:"returnUrl"`
+	NotifyUrl   string       `json:"notifyUrl"`
+	PhoneNumber string       `json:"msisdn,omitempty"`
 }
 
 type InitiateResponse struct {
@@ -46,7 +47,7 @@ func InitiatePayment(req *InitiateRequest) (*InitiateResponse, error) {
 		"appId":       cfg.MerchantAppID,
 		"outTradeNo":  req.OutTradeNo,
 		"subject":     req.Subject,
-		"totalAmount": strconv.FormatFloat(req.TotalAmount, 'f', 2, 64),
+		"totalAmount": req.TotalAmount.String(),
 		"shortCode":   cfg.ShortCode,
 		"nonceStr":    strconv.FormatInt(time.Now().UnixNano(), 10),
 		"timestamp":   strconv.FormatInt(time.Now().Unix(), 10),
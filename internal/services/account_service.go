@@ -122,3 +122,37 @@ func (s *AccountService) AddToAccountBalance(accountID string, amount float64) e
 	newBalance := currentBalance + amount
 	return s.UpdateAccountBalance(accountID, newBalance)
 }
+
+// GetPreferences returns the allergies and dietary needs saved on an account
+func (s *AccountService) GetPreferences(accountID string) (*models.AccountPreferences, error) {
+	prefs := &models.AccountPreferences{Allergens: models.Allergens{}, Dietary: models.DietaryTags{}}
+	err := s.db.Conn().QueryRow(
+		"SELECT COALESCE(allergens, '[]'), COALESCE(dietary, '[]') FROM accounts WHERE id = $1",
+		accountID,
+	).Scan(&prefs.Allergens, &prefs.Dietary)
+
+	if err != nil {
+		return nil, fmt.Errorf("account not found")
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreferences replaces the allergies and dietary needs saved on an account
+func (s *AccountService) UpdatePreferences(accountID string, prefs models.AccountPreferences) (*models.AccountPreferences, error) {
+	if err := models.ValidateTags(prefs.Allergens, prefs.Dietary); err != nil {
+		return nil, err
+	}
+	res, err := s.db.Conn().Exec(
+		"UPDATE accounts SET allergens = $1, dietary = $2, updated_at = $3 WHERE id = $4",
+		prefs.Allergens.Union(nil), prefs.Dietary, time.Now(), accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("account not found")
+	}
+
+	return s.GetPreferences(accountID)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// maxMetricsRange bounds the period a kitchen metrics report may cover
+const maxMetricsRange = 93 * 24 * time.Hour
+
+// ErrMetricsRange is returned for a metrics period that is empty, reversed or too long
+var ErrMetricsRange = errors.New("invalid date range")
+
+// ticketHour aggregates the tickets one station finished in one hour
+type ticketHour struct {
+	StationID   string
+	StationName string
+	Hour        time.Time
+	Tickets     int
+	TicketSecs  float64
+	PrepSecs    float64
+	Started     int
+	Late        int
+}
+
+// summarizeKitchen folds hourly station rows into per-station and overall averages. recalls
+// maps station IDs to their recall counts.
+func summarizeKitchen(rows []ticketHour, recalls map[string]int) (models.StationMetrics, []models.StationMetrics, []models.HourlyThroughput) {
+	type sums struct {
+		m       models.StationMetrics
+		tickets float64
+		prep    float64
+		started int
+	}
+	var total sums
+	byStation := map[string]*sums{}
+	var order []string
+	throughput := make([]models.HourlyThroughput, 0, len(rows))
+	for _, r := range rows {
+		st := byStation[r.StationID]
+		if st == nil {
+			st = &sums{m: models.StationMetrics{StationID: r.StationID, StationName: r.StationName}}
+			byStation[r.StationID] = st
+			order = append(order, r.StationID)
+		}
+		for _, s := range []*sums{st, &total} {
+			s.m.Tickets += r.Tickets
+			s.m.LateTickets += r.Late
+			s.tickets += r.TicketSecs
+			s.prep += r.PrepSecs
+			s.started += r.Started
+		}
+		throughput = append(throughput, models.HourlyThroughput{Hour: r.Hour, StationID: r.StationID, Tickets: r.Tickets})
+	}
+	// stations may have recalls without any bump in the period
+	var idle []string
+	for id := range recalls {
+		if byStation[id] == nil {
+			byStation[id] = &sums{m: models.StationMetrics{StationID: id}}
+			idle = append(idle, id)
+		}
+	}
+	sort.Strings(idle)
+	order = append(order, idle...)
+	finish := func(s *sums) models.StationMetrics {
+		if s.m.Tickets > 0 {
+			s.m.AvgTicketSeconds = s.tickets / float64(s.m.Tickets)
+		}
+		if s.started > 0 {
+			s.m.AvgPrepSeconds = s.prep / float64(s.started)
+		}
+		return s.m
+	}
+	stations := make([]models.StationMetrics, 0, len(order))
+	for _, id := range order {
+		st := byStation[id]
+		st.m.Recalls = recalls[id]
+		total.m.Recalls += recalls[id]
+		stations = append(stations, finish(st))
+	}
+	return finish(&total), stations, throughput
+}
+
+// KitchenMetrics reports ticket times, late tickets and hourly throughput per station for
+// tickets bumped in [from, to). It reads the ticket entries of the order audit log, counting
+// each ticket once at its last bump so recalled tickets are timed to when they finally left.
+func (s *OrderSQLService) KitchenMetrics(ctx context.Context, restaurantID string, from, to time.Time) (*models.KitchenMetrics, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrMetricsRange)
+	}
+	if to.Sub(from) > maxMetricsRange {
+		return nil, fmt.Errorf("%w: longer than %d days", ErrMetricsRange, int(maxMetricsRange.Hours()/24))
+	}
+	rows, err := s.db.QueryContext(ctx, "WITH bumps AS ("+
+		"SELECT DISTINCT ON (a.details::jsonb->>'ticket_id') a.details::jsonb AS d, a.created_at AS bumped_at "+
+		"FROM order_audits a JOIN orders o ON o.id=a.order_id "+
+		"WHERE a.action='ticket_bumped' AND a.created_at >= $1 AND a.created_at < $2 AND ($3='' OR o.restaurant_id=$3) "+
+		"ORDER BY a.details::jsonb->>'ticket_id', a.created_at DESC) "+
+		"SELECT COALESCE(b.d->>'station_id', ''), COALESCE(MAX(ks.name), ''), date_trunc('hour', b.bumped_at), COUNT(*), "+
+		"COALESCE(SUM(EXTRACT(EPOCH FROM b.bumped_at - (b.d->>'received_at')::timestamptz)), 0), "+
+		"COALESCE(SUM(EXTRACT(EPOCH FROM b.bumped_at - (b.d->>'started_at')::timestamptz)), 0), COUNT(b.d->>'started_at'), "+
+		"COUNT(*) FILTER (WHERE b.bumped_at > (b.d->>'due_at')::timestamptz) "+
+		"FROM bumps b LEFT JOIN kitchen_stations ks ON ks.id=b.d->>'station_id' "+
+		"GROUP BY 1, 3 ORDER BY 3, 1", from, to, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hours []ticketHour
+	for rows.Next() {
+		var h ticketHour
+		if err := rows.Scan(&h.StationID, &h.StationName, &h.Hour, &h.Tickets, &h.TicketSecs, &h.PrepSecs, &h.Started, &h.Late); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	recalls, err := countRecalls(ctx, s.db, restaurantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	m := &models.KitchenMetrics{From: from, To: to}
+	m.Total, m.Stations, m.Throughput = summarizeKitchen(hours, recalls)
+	return m, nil
+}
+
+// countRecalls counts recalled tickets per station in [from, to)
+func countRecalls(ctx context.Context, q sqlQueryer, restaurantID string, from, to time.Time) (map[string]int, error) {
+	rows, err := q.QueryContext(ctx, "SELECT COALESCE(a.details::jsonb->>'station_id', ''), COUNT(*) FROM order_audits a JOIN orders o ON o.id=a.order_id "+
+		"WHERE a.action='ticket_recalled' AND a.created_at >= $1 AND a.created_at < $2 AND ($3='' OR o.restaurant_id=$3) GROUP BY 1", from, to, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
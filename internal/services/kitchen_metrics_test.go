@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeKitchen(t *testing.T) {
+	h := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	total, stations, throughput := summarizeKitchen([]ticketHour{
+		{StationID: "grill", StationName: "Grill", Hour: h, Tickets: 2, TicketSecs: 1200, PrepSecs: 600, Started: 2, Late: 1},
+		{StationID: "cold", StationName: "Cold", Hour: h, Tickets: 1, TicketSecs: 300, Started: 0},
+		{StationID: "grill", StationName: "Grill", Hour: h.Add(time.Hour), Tickets: 2, TicketSecs: 1600, PrepSecs: 1000, Started: 2},
+	}, map[string]int{"grill": 1, "bar": 2})
+
+	assert.Equal(t, 5, total.Tickets)
+	assert.Equal(t, 1, total.LateTickets)
+	assert.Equal(t, 3, total.Recalls)
+	assert.InDelta(t, 620.0, total.AvgTicketSeconds, 0.001)
+	assert.InDelta(t, 400.0, total.AvgPrepSeconds, 0.001)
+
+	assert.Len(t, stations, 3)
+	assert.Equal(t, "grill", stations[0].StationID)
+	assert.Equal(t, 4, stations[0].Tickets)
+	assert.InDelta(t, 700.0, stations[0].AvgTicketSeconds, 0.001)
+	assert.Equal(t, 1, stations[0].Recalls)
+	// cold never started a ticket before bumping it
+	assert.Equal(t, 0.0, stations[1].AvgPrepSeconds)
+	// bar only recalled tickets in the period
+	assert.Equal(t, "bar", stations[2].StationID)
+	assert.Equal(t, 2, stations[2].Recalls)
+
+	assert.Len(t, throughput, 3)
+	assert.Equal(t, 2, throughput[2].Tickets)
+}
+
+func TestTicketAction(t *testing.T) {
+	assert.Equal(t, "ticket_started", ticketAction("queued", "in_progress"))
+	assert.Equal(t, "ticket_bumped", ticketAction("in_progress", "bumped"))
+	assert.Equal(t, "ticket_recalled", ticketAction("bumped", "in_progress"))
+}
@@ -1,10 +1,9 @@
 package services
 
 import (
-	"fmt"
+	"context"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/models"
-	"time"
 )
 
 type KitchenService struct {
@@ -45,58 +44,10 @@ func (s *KitchenService) GetPendingOrders() ([]*models.Order, error) {
 	return orders, nil
 }
 
-func (s *KitchenService) UpdateOrderStatus(orderID string, status models.OrderStatus) error {
-	// Validate status transition
-	currentStatus, err := s.getOrderStatus(orderID)
-	if err != nil {
-		return err
-	}
-
-	if !s.isValidStatusTransition(currentStatus, status) {
-		return fmt.Errorf("invalid status transition from %s to %s", currentStatus, status)
-	}
-
-	_, err = s.db.Conn().Exec(
-		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3",
-		status, time.Now(), orderID,
-	)
-
-	return err
-}
-
-func (s *KitchenService) getOrderStatus(orderID string) (models.OrderStatus, error) {
-	var status models.OrderStatus
-	err := s.db.Conn().QueryRow(
-		"SELECT status FROM orders WHERE id = $1",
-		orderID,
-	).Scan(&status)
-
-	return status, err
-}
-
-func (s *KitchenService) isValidStatusTransition(current, new models.OrderStatus) bool {
-	// Define valid status transitions
-	validTransitions := map[models.OrderStatus][]models.OrderStatus{
-		models.OrderStatusPending:   {models.OrderStatusConfirmed, models.OrderStatusCancelled},
-		models.OrderStatusConfirmed: {models.OrderStatusPreparing, models.OrderStatusCancelled},
-		models.OrderStatusPreparing: {models.OrderStatusReady, models.OrderStatusCancelled},
-		models.OrderStatusReady:     {models.OrderStatusCompleted},
-		models.OrderStatusCompleted: {},
-		models.OrderStatusCancelled: {},
-	}
-
-	allowedStatuses, exists := validTransitions[current]
-	if !exists {
-		return false
-	}
-
-	for _, allowed := range allowedStatuses {
-		if allowed == new {
-			return true
-		}
-	}
-
-	return false
+// UpdateOrderStatus moves an order through the shared state machine; t.UserID is the account
+// recorded in order_audits as making the change
+func (s *KitchenService) UpdateOrderStatus(orderID string, t OrderTransition) error {
+	return transitionOrder(context.Background(), s.db.Conn(), orderID, t)
 }
 
 func (s *KitchenService) GetOrderDetails(orderID string) (*models.Order, error) {
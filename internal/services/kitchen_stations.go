@@ -0,0 +1,522 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrTicketNotFound is returned when a ticket ID does not exist.
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// ticketTransitions lists the states a ticket may move to from each state
+var ticketTransitions = map[models.TicketStatus][]models.TicketStatus{
+	models.TicketQueued:     {models.TicketInProgress, models.TicketBumped},
+	models.TicketInProgress: {models.TicketBumped},
+	// a recalled ticket goes back to the station as in progress
+	models.TicketBumped: {models.TicketInProgress},
+}
+
+// kitchenFlow is the order status path that ticket progress pushes an order along
+var kitchenFlow = []models.OrderStatus{models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusPreparing, models.OrderStatusReady}
+
+// CreateStation adds a station; a new default station replaces the restaurant's previous default.
+func (s *OrderSQLService) CreateStation(ctx context.Context, req models.CreateStationRequest) (*models.KitchenStation, error) {
+	st := &models.KitchenStation{ID: uuid.New().String(), RestaurantID: req.RestaurantID, Name: req.Name, IsDefault: req.IsDefault, CreatedAt: time.Now()}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if st.IsDefault {
+		if _, err = tx.ExecContext(ctx, "UPDATE kitchen_stations SET is_default=FALSE WHERE COALESCE(restaurant_id, '')=$1", st.RestaurantID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO kitchen_stations (id, restaurant_id, name, is_default, created_at) VALUES ($1,$2,$3,$4,$5)",
+		st.ID, nullIfEmpty(st.RestaurantID), st.Name, st.IsDefault, st.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *OrderSQLService) ListStations(ctx context.Context, restaurantID string) ([]models.KitchenStation, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, COALESCE(restaurant_id, ''), name, is_default, created_at FROM kitchen_stations WHERE COALESCE(restaurant_id, '')=$1 ORDER BY name", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stations := []models.KitchenStation{}
+	for rows.Next() {
+		var st models.KitchenStation
+		if err := rows.Scan(&st.ID, &st.RestaurantID, &st.Name, &st.IsDefault, &st.CreatedAt); err != nil {
+			return nil, err
+		}
+		stations = append(stations, st)
+	}
+	return stations, rows.Err()
+}
+
+// SetStationRoute sends a menu category or item to a station, replacing any route the same
+// category or item had to another station of the restaurant.
+func (s *OrderSQLService) SetStationRoute(ctx context.Context, stationID string, req models.StationRouteRequest) (*models.StationRoute, error) {
+	if (req.Category == "") == (req.MenuItemID == "") {
+		return nil, errors.New("set exactly one of category and menu_item_id")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var restaurantID string
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(restaurant_id, '') FROM kitchen_stations WHERE id=$1", stationID).Scan(&restaurantID)
+	if err == sql.ErrNoRows {
+		err = errors.New("station " + stationID + " not found")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM kitchen_station_routes r USING kitchen_stations s WHERE s.id=r.station_id AND COALESCE(s.restaurant_id, '')=$1 AND "+
+		"COALESCE(r.category, '')=$2 AND COALESCE(r.menu_item_id, '')=$3", restaurantID, req.Category, req.MenuItemID)
+	if err != nil {
+		return nil, err
+	}
+	rt := &models.StationRoute{ID: uuid.New().String(), StationID: stationID, Category: req.Category, MenuItemID: req.MenuItemID}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO kitchen_station_routes (id, station_id, category, menu_item_id) VALUES ($1,$2,$3,$4)",
+		rt.ID, stationID, nullIfEmpty(rt.Category), nullIfEmpty(rt.MenuItemID)); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (s *OrderSQLService) ListStationRoutes(ctx context.Context, stationID string) ([]models.StationRoute, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, station_id, COALESCE(category, ''), COALESCE(menu_item_id, '') FROM kitchen_station_routes WHERE station_id=$1 ORDER BY category, menu_item_id", stationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	routes := []models.StationRoute{}
+	for rows.Next() {
+		var rt models.StationRoute
+		if err := rows.Scan(&rt.ID, &rt.StationID, &rt.Category, &rt.MenuItemID); err != nil {
+			return nil, err
+		}
+		routes = append(routes, rt)
+	}
+	return routes, rows.Err()
+}
+
+func (s *OrderSQLService) DeleteStationRoute(ctx context.Context, stationID, routeID string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM kitchen_station_routes WHERE id=$1 AND station_id=$2", routeID, stationID)
+	return err
+}
+
+// stationFor picks the station for a menu item: an item route wins over a category route,
+// and the restaurant's default station takes anything unrouted. "" means no station matched.
+func stationFor(ctx context.Context, q sqlQueryer, restaurantID, menuItemID string) (string, error) {
+	var id string
+	err := q.QueryRowContext(ctx, "SELECT s.id FROM kitchen_station_routes r JOIN kitchen_stations s ON s.id=r.station_id "+
+		"WHERE COALESCE(s.restaurant_id, '')=$1 AND (r.menu_item_id=$2 OR r.category=(SELECT category FROM menu_items WHERE id=$2)) "+
+		"ORDER BY r.menu_item_id IS NULL LIMIT 1", restaurantID, menuItemID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+	err = q.QueryRowContext(ctx, "SELECT id FROM kitchen_stations WHERE COALESCE(restaurant_id, '')=$1 AND is_default LIMIT 1", restaurantID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// assignTickets routes new lines to stations and opens one ticket per station and course,
+// setting TicketID on each line before it is inserted. Courses for which fire is true go to the kitchen
+// now; the others are held until fired.
+func assignTickets(ctx context.Context, q sqlQueryer, orderID, restaurantID string, items []models.OrderItem, fire func(models.Course) bool) error {
+	type ticketKey struct {
+		station string
+		course  models.Course
+	}
+	tickets := map[ticketKey]string{}
+	now := time.Now()
+	for i := range items {
+		// a combo line is cooked as its components
+		if items[i].ComboID != "" {
+			continue
+		}
+		stationID, err := stationFor(ctx, q, restaurantID, items[i].MenuItemID)
+		if err != nil {
+			return err
+		}
+		key := ticketKey{stationID, items[i].Course}
+		ticketID, ok := tickets[key]
+		if !ok {
+			ticketID = uuid.New().String()
+			var firedAt *time.Time
+			if fire(key.course) {
+				firedAt = &now
+			}
+			if _, err := q.ExecContext(ctx, "INSERT INTO kitchen_tickets (id, order_id, station_id, course, status, fired_at, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)",
+				ticketID, orderID, nullIfEmpty(stationID), nullIfEmpty(string(key.course)), string(models.TicketQueued), firedAt, now, now); err != nil {
+				return err
+			}
+			tickets[key] = ticketID
+		}
+		items[i].TicketID = ticketID
+	}
+	return nil
+}
+
+// dropEmptyTicket deletes a ticket whose last line was removed
+func dropEmptyTicket(ctx context.Context, q sqlQueryer, ticketID string) error {
+	if ticketID == "" {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, "DELETE FROM kitchen_tickets WHERE id=$1 AND NOT EXISTS (SELECT 1 FROM order_items WHERE ticket_id=$1)", ticketID)
+	return err
+}
+
+const ticketColumns = "t.id, t.order_id, COALESCE(o.order_number, 0), COALESCE(t.station_id, ''), COALESCE(t.course, ''), t.status, t.fired_at, t.created_at, t.started_at, t.bumped_at, t.recalled_at, COALESCE(t.recall_count, 0)"
+
+func scanTicket(row rowScanner) (*models.KitchenTicket, error) {
+	var t models.KitchenTicket
+	var fired, started, bumped, recalled sql.NullTime
+	if err := row.Scan(&t.ID, &t.OrderID, &t.OrderNumber, &t.StationID, &t.Course, &t.Status, &fired, &t.CreatedAt, &started, &bumped, &recalled, &t.RecallCount); err != nil {
+		return nil, err
+	}
+	if fired.Valid {
+		t.FiredAt = &fired.Time
+	}
+	if started.Valid {
+		t.StartedAt = &started.Time
+	}
+	if bumped.Valid {
+		t.BumpedAt = &bumped.Time
+	}
+	if recalled.Valid {
+		t.RecalledAt = &recalled.Time
+	}
+	return &t, nil
+}
+
+// queryTickets runs a ticket query and loads each ticket's lines, voided ones included so the
+// station can see what to stop making.
+func queryTickets(ctx context.Context, q sqlQueryer, query string, args ...interface{}) ([]models.KitchenTicket, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var tickets []models.KitchenTicket
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tickets = append(tickets, *t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range tickets {
+		t := &tickets[i]
+		if t.Items, err = loadTicketItems(ctx, q, t.ID); err != nil {
+			return nil, err
+		}
+		// the station sees every allergen on the ticket, with the customer's own called out
+		for _, it := range t.Items {
+			t.Allergens = t.Allergens.Union(it.Allergens)
+			t.AllergenAlerts = t.AllergenAlerts.Union(it.AllergenAlerts)
+		}
+	}
+	return tickets, nil
+}
+
+func loadTicketItems(ctx context.Context, q sqlQueryer, ticketID string) ([]models.OrderItem, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+orderItemColumns+" FROM order_items WHERE ticket_id=$1", ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.OrderItem{}
+	for rows.Next() {
+		it, err := scanOrderItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *it)
+	}
+	return items, rows.Err()
+}
+
+// ListStationTickets returns the open, fired tickets for a station in firing order. Held courses
+// and scheduled pickups (until KitchenFireLead before pickup) stay hidden. An empty stationID
+// lists unrouted tickets.
+func (s *OrderSQLService) ListStationTickets(ctx context.Context, stationID string) ([]models.KitchenTicket, error) {
+	return queryTickets(ctx, s.db, "SELECT "+ticketColumns+" FROM kitchen_tickets t JOIN orders o ON o.id=t.order_id "+
+		"WHERE COALESCE(t.station_id, '')=$1 AND t.fired_at IS NOT NULL AND t.status IN ($2,$3) AND o.status IN ($4,$5,$6) AND (o.pickup_at IS NULL OR o.pickup_at <= $7) ORDER BY t.fired_at, t.created_at",
+		stationID, string(models.TicketQueued), string(models.TicketInProgress),
+		string(models.OrderStatusPending), string(models.OrderStatusConfirmed), string(models.OrderStatusPreparing), time.Now().Add(KitchenFireLead))
+}
+
+// DueOrderTickets returns the tickets of an order that a station shows: fired ones, and for a
+// scheduled pickup only from KitchenFireLead before it.
+func (s *OrderSQLService) DueOrderTickets(ctx context.Context, orderID string) ([]models.KitchenTicket, error) {
+	return queryTickets(ctx, s.db, "SELECT "+ticketColumns+" FROM kitchen_tickets t JOIN orders o ON o.id=t.order_id "+
+		"WHERE t.order_id=$1 AND t.fired_at IS NOT NULL AND (o.pickup_at IS NULL OR o.pickup_at <= $2) ORDER BY t.created_at",
+		orderID, time.Now().Add(KitchenFireLead))
+}
+
+// OrderTickets returns every ticket of an order
+func (s *OrderSQLService) OrderTickets(ctx context.Context, orderID string) ([]models.KitchenTicket, error) {
+	return queryTickets(ctx, s.db, "SELECT "+ticketColumns+" FROM kitchen_tickets t JOIN orders o ON o.id=t.order_id WHERE t.order_id=$1 ORDER BY t.created_at", orderID)
+}
+
+func (s *OrderSQLService) GetTicket(ctx context.Context, id string) (*models.KitchenTicket, error) {
+	tickets, err := queryTickets(ctx, s.db, "SELECT "+ticketColumns+" FROM kitchen_tickets t JOIN orders o ON o.id=t.order_id WHERE t.id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, ErrTicketNotFound
+	}
+	return &tickets[0], nil
+}
+
+// UpdateTicketStatus moves a ticket and carries the order along: starting any ticket puts the
+// order into preparing, and bumping the last one marks it ready. Moving a bumped ticket back to
+// in progress recalls it. It reports whether the order became ready.
+func (s *OrderSQLService) UpdateTicketStatus(ctx context.Context, ticketID string, to models.TicketStatus, userID string) (*models.KitchenTicket, bool, error) {
+	return s.moveTicket(ctx, ticketID, "", to, userID)
+}
+
+// BumpTicket marks a ticket done at its station; see UpdateTicketStatus.
+func (s *OrderSQLService) BumpTicket(ctx context.Context, ticketID, userID string) (*models.KitchenTicket, bool, error) {
+	return s.moveTicket(ctx, ticketID, "", models.TicketBumped, userID)
+}
+
+// RecallTicket brings a bumped ticket back to its station, taking a ready order back into
+// preparation.
+func (s *OrderSQLService) RecallTicket(ctx context.Context, ticketID, userID string) (*models.KitchenTicket, error) {
+	t, _, err := s.moveTicket(ctx, ticketID, models.TicketBumped, models.TicketInProgress, userID)
+	return t, err
+}
+
+// ticketAudit is the order_audits detail of a ticket change; the kitchen metrics are read back
+// from it, so it carries the times each ticket was received, started and due.
+type ticketAudit struct {
+	TicketID   string        `json:"ticket_id"`
+	StationID  string        `json:"station_id,omitempty"`
+	Course     models.Course `json:"course,omitempty"`
+	From       string        `json:"from"`
+	ReceivedAt time.Time     `json:"received_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	DueAt      *time.Time    `json:"due_at,omitempty"`
+}
+
+// ticketAction names the audit entry of a ticket move
+func ticketAction(from, to models.TicketStatus) string {
+	switch {
+	case from == models.TicketBumped:
+		return "ticket_recalled"
+	case to == models.TicketBumped:
+		return "ticket_bumped"
+	}
+	return "ticket_started"
+}
+
+// moveTicket runs a ticket transition; a non-empty from requires the ticket to be in that state.
+func (s *OrderSQLService) moveTicket(ctx context.Context, ticketID string, from, to models.TicketStatus, userID string) (*models.KitchenTicket, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var orderID string
+	var current models.TicketStatus
+	var firedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT order_id, status, fired_at FROM kitchen_tickets WHERE id=$1 FOR UPDATE", ticketID).Scan(&orderID, &current, &firedAt)
+	if err == sql.ErrNoRows {
+		err = ErrTicketNotFound
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !firedAt.Valid {
+		err = ErrCourseHeld
+		return nil, false, err
+	}
+	if (from != "" && current != from) || !ticketAllowed(current, to) {
+		err = &TicketTransitionError{From: current, To: to}
+		return nil, false, err
+	}
+
+	now := time.Now()
+	switch {
+	case current == models.TicketBumped:
+		_, err = tx.ExecContext(ctx, "UPDATE kitchen_tickets SET status=$1, bumped_at=NULL, recalled_at=$2, recall_count=COALESCE(recall_count, 0)+1, updated_at=$2 WHERE id=$3", string(to), now, ticketID)
+	case to == models.TicketInProgress:
+		_, err = tx.ExecContext(ctx, "UPDATE kitchen_tickets SET status=$1, started_at=$2, updated_at=$2 WHERE id=$3", string(to), now, ticketID)
+	case to == models.TicketBumped:
+		_, err = tx.ExecContext(ctx, "UPDATE kitchen_tickets SET status=$1, started_at=COALESCE(started_at, $2), bumped_at=$2, updated_at=$2 WHERE id=$3", string(to), now, ticketID)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	a := ticketAudit{TicketID: ticketID, From: string(current)}
+	var started, due sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(t.station_id, ''), COALESCE(t.course, ''), COALESCE(t.fired_at, t.created_at), t.started_at, o.estimated_ready_at "+
+		"FROM kitchen_tickets t JOIN orders o ON o.id=t.order_id WHERE t.id=$1", ticketID).Scan(&a.StationID, &a.Course, &a.ReceivedAt, &started, &due)
+	if err != nil {
+		return nil, false, err
+	}
+	if started.Valid {
+		a.StartedAt = &started.Time
+	}
+	if due.Valid {
+		a.DueAt = &due.Time
+	}
+	if err = writeOrderAudit(ctx, tx, orderID, ticketAction(current, to), userID, a); err != nil {
+		return nil, false, err
+	}
+
+	ready := false
+	if current == models.TicketBumped {
+		if err = recallOrder(ctx, tx, orderID, userID); err != nil {
+			return nil, false, err
+		}
+	}
+	if err = advanceOrder(ctx, tx, orderID, models.OrderStatusPreparing, userID); err != nil {
+		return nil, false, err
+	}
+	if to == models.TicketBumped {
+		var open int
+		if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM kitchen_tickets WHERE order_id=$1 AND status<>$2", orderID, string(models.TicketBumped)).Scan(&open); err != nil {
+			return nil, false, err
+		}
+		if open == 0 {
+			if err = advanceOrder(ctx, tx, orderID, models.OrderStatusReady, userID); err != nil {
+				return nil, false, err
+			}
+			ready = true
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	t, err := s.GetTicket(ctx, ticketID)
+	return t, ready, err
+}
+
+// TicketTransitionError is returned when a ticket cannot move to the requested state.
+type TicketTransitionError struct {
+	From models.TicketStatus
+	To   models.TicketStatus
+}
+
+func (e *TicketTransitionError) Error() string {
+	return "invalid ticket transition from " + string(e.From) + " to " + string(e.To)
+}
+
+func ticketAllowed(from, to models.TicketStatus) bool {
+	for _, allowed := range ticketTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// advanceOrder steps an order along kitchenFlow up to status to, through the state machine so
+// each step is validated and audited. Orders already there, or off the flow, are left alone.
+func advanceOrder(ctx context.Context, tx *sql.Tx, orderID string, to models.OrderStatus, userID string) error {
+	var current models.OrderStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM orders WHERE id=$1", orderID).Scan(&current); err != nil {
+		return err
+	}
+	from, target := -1, -1
+	for i, st := range kitchenFlow {
+		if st == current {
+			from = i
+		}
+		if st == to {
+			target = i
+		}
+	}
+	if from < 0 || from >= target {
+		return nil
+	}
+	for _, st := range kitchenFlow[from+1 : target+1] {
+		if _, err := orderStates.Apply(ctx, tx, orderID, OrderTransition{To: st, UserID: userID, Reason: "kitchen ticket"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recallOrder takes a ready order back into preparation when one of its tickets is recalled
+func recallOrder(ctx context.Context, tx *sql.Tx, orderID, userID string) error {
+	var current models.OrderStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM orders WHERE id=$1", orderID).Scan(&current); err != nil {
+		return err
+	}
+	if current != models.OrderStatusReady {
+		return nil
+	}
+	_, err := orderStates.Apply(ctx, tx, orderID, OrderTransition{To: models.OrderStatusPreparing, UserID: userID, Reason: "kitchen ticket recalled", Recall: true})
+	return err
+}
+
+// ExpoOrders pairs each order on the page with its tickets so the pass can see which stations
+// are still working.
+func (s *OrderSQLService) ExpoOrders(ctx context.Context, f OrderFilter, page PageRequest) ([]models.ExpoOrder, string, error) {
+	res, err := s.ListOrders(ctx, f, page)
+	if err != nil {
+		return nil, "", err
+	}
+	out := make([]models.ExpoOrder, 0, len(res.Orders))
+	for _, o := range res.Orders {
+		tickets, err := s.OrderTickets(ctx, o.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		eo := models.ExpoOrder{Order: o, Tickets: tickets, Ready: len(tickets) > 0}
+		for _, t := range tickets {
+			if t.Status != models.TicketBumped {
+				eo.Ready = false
+			}
+		}
+		out = append(out, eo)
+	}
+	return out, res.NextCursor, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// ErrTableNotInRestaurant is returned when a QR code's table belongs to another restaurant.
+var ErrTableNotInRestaurant = errors.New("table does not belong to this restaurant")
+
+// branchMenuJoin joins a branch's overrides onto menu_items m as o; $1 is the branch
+const branchMenuJoin = "LEFT JOIN menu_item_overrides o ON o.menu_item_id = m.id AND o.restaurant_id = $1"
+
+// QRMenu is a branch's menu as one of its tables sees it, priced in the branch's currency. At
+// is the local time the menu's schedules were evaluated at. Combos only offer the choices on
+// the menu at that time. VersionID is the published menu version, if the branch has one.
+type QRMenu struct {
+	RestaurantID string            `json:"restaurant_id"`
+	TableID      string            `json:"table_id,omitempty"`
+	VersionID    string            `json:"version_id,omitempty"`
+	Currency     string            `json:"currency"`
+	At           time.Time         `json:"at"`
+	Categories   []MenuCategoryDTO `json:"categories"`
+	Combos       []models.Combo    `json:"combos"`
+}
+
+// restaurantCurrency returns the currency a restaurant's menu is priced in
+func (s *MenuSQLService) restaurantCurrency(ctx context.Context, restaurantID string) (string, error) {
+	var currency string
+	err := s.conn().QueryRowContext(ctx, "SELECT COALESCE(NULLIF(currency, ''), $2) FROM restaurants WHERE id=$1 AND deleted_at IS NULL", restaurantID, models.DefaultCurrency).
+		Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", ErrMenuNotFound
+	}
+	return currency, err
+}
+
+// checkTable checks that a QR code's table belongs to the restaurant
+func (s *MenuSQLService) checkTable(ctx context.Context, restaurantID, tableID string) error {
+	var owner string
+	err := s.conn().QueryRowContext(ctx, "SELECT COALESCE(restaurant_id, '') FROM tables WHERE id=$1", tableID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != restaurantID) {
+		return ErrTableNotInRestaurant
+	}
+	return err
+}
+
+// SetItemOverride sets a branch's price and availability for an item the branch offers
+func (s *MenuSQLService) SetItemOverride(ctx context.Context, o *models.MenuItemOverride) error {
+	if o.Price != nil && *o.Price <= 0 {
+		return errors.New("price must be positive")
+	}
+	var owner sql.NullString
+	err := s.conn().QueryRowContext(ctx, "SELECT restaurant_id FROM menu_items WHERE id=$1 AND deleted_at IS NULL", o.MenuItemID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return ErrMenuNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if owner.Valid && owner.String != o.RestaurantID {
+		return errors.New("menu item belongs to another restaurant")
+	}
+	o.UpdatedAt = time.Now()
+	_, err = s.conn().ExecContext(ctx, "INSERT INTO menu_item_overrides (restaurant_id, menu_item_id, price, available, updated_at) VALUES ($1,$2,$3,$4,$5) "+
+		"ON CONFLICT (restaurant_id, menu_item_id) DO UPDATE SET price=EXCLUDED.price, available=EXCLUDED.available, updated_at=EXCLUDED.updated_at",
+		o.RestaurantID, o.MenuItemID, o.Price, o.Available, o.UpdatedAt)
+	return err
+}
+
+// SetBranchAvailability sets a branch's availability for a live item right away, keeping the
+// branch's price. Like an item's own availability it is not held back for a draft; an item
+// only a draft has takes the draft's availability when it is published.
+func (s *MenuSQLService) SetBranchAvailability(ctx context.Context, restaurantID, itemID string, available *bool) error {
+	var live bool
+	err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM menu_items WHERE id=$1 AND deleted_at IS NULL)", itemID).Scan(&live)
+	if err != nil || !live {
+		return err
+	}
+	_, err = s.conn().ExecContext(ctx, "INSERT INTO menu_item_overrides (restaurant_id, menu_item_id, available, updated_at) VALUES ($1,$2,$3,NOW()) "+
+		"ON CONFLICT (restaurant_id, menu_item_id) DO UPDATE SET available=EXCLUDED.available, updated_at=EXCLUDED.updated_at",
+		restaurantID, itemID, available)
+	return err
+}
+
+// DeleteItemOverride returns a branch to the item's own price and availability
+func (s *MenuSQLService) DeleteItemOverride(ctx context.Context, restaurantID, itemID string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM menu_item_overrides WHERE restaurant_id=$1 AND menu_item_id=$2", restaurantID, itemID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+func (s *MenuSQLService) ListItemOverrides(ctx context.Context, restaurantID string) ([]models.MenuItemOverride, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT restaurant_id, menu_item_id, price, available, updated_at FROM menu_item_overrides WHERE restaurant_id=$1 ORDER BY menu_item_id", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.MenuItemOverride{}
+	for rows.Next() {
+		var o models.MenuItemOverride
+		var price sql.NullFloat64
+		var available sql.NullBool
+		if err := rows.Scan(&o.RestaurantID, &o.MenuItemID, &price, &available, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if price.Valid {
+			o.Price = &price.Float64
+		}
+		if available.Valid {
+			o.Available = &available.Bool
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
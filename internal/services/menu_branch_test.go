@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// qrPrices maps the names on a QR menu to their prices
+func qrPrices(menu *QRMenu) map[string]float64 {
+	prices := map[string]float64{}
+	for _, cat := range menu.Categories {
+		for _, it := range cat.Items {
+			prices[it.Name] = it.Price
+		}
+	}
+	return prices
+}
+
+func TestGetQRMenuBranch(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	_, err := db.ExecContext(ctx, "UPDATE restaurants SET currency=CASE id WHEN 'r1' THEN 'KES' ELSE '' END")
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO tables (id, restaurant_id) VALUES ('t1', 'r1'), ('t2', 'r2')")
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO menu_items (id, name, price, category, available) VALUES ('s1', 'Buna', 60, 'Drinks', TRUE), ('s2', 'Shai', 30, 'Drinks', FALSE)")
+	assert.NoError(t, err)
+
+	price, on, off := 75.0, true, false
+	assert.NoError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "s1", Price: &price}))
+	assert.NoError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "s2", Available: &on}))
+	assert.NoError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r2", MenuItemID: "s1", Available: &off}))
+
+	menu, err := s.GetQRMenu(ctx, "r1", "t1", nil, MenuTagFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "KES", menu.Currency)
+	assert.Equal(t, "t1", menu.TableID)
+	assert.Equal(t, map[string]float64{"Tibs": 250, "Buna": 75, "Shai": 30}, qrPrices(menu))
+
+	// r2 has no currency or items of its own, does not offer Buna and keeps Shai off
+	menu, err = s.GetQRMenu(ctx, "r2", "t2", nil, MenuTagFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultCurrency, menu.Currency)
+	assert.Empty(t, menu.Categories)
+
+	// dropping the override returns r1 to the item's own price
+	assert.NoError(t, s.DeleteItemOverride(ctx, "r1", "s1"))
+	menu, err = s.GetQRMenu(ctx, "r1", "t1", nil, MenuTagFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 60.0, qrPrices(menu)["Buna"])
+	assert.ErrorIs(t, s.DeleteItemOverride(ctx, "r1", "s1"), ErrMenuNotFound)
+
+	_, err = s.GetQRMenu(ctx, "missing", "t1", nil, MenuTagFilter{})
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+}
+
+func TestCheckTable(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	_, err := db.ExecContext(ctx, "INSERT INTO tables (id, restaurant_id) VALUES ('t1', 'r1'), ('t2', 'r2'), ('t3', NULL)")
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.checkTable(ctx, "r1", "t1"))
+	assert.ErrorIs(t, s.checkTable(ctx, "r1", "t2"), ErrTableNotInRestaurant)
+	assert.ErrorIs(t, s.checkTable(ctx, "r1", "t3"), ErrTableNotInRestaurant)
+	assert.ErrorIs(t, s.checkTable(ctx, "r1", "missing"), ErrTableNotInRestaurant)
+	_, err = s.GetQRMenu(ctx, "r1", "t2", nil, MenuTagFilter{})
+	assert.ErrorIs(t, err, ErrTableNotInRestaurant)
+}
+
+func TestSetItemOverride(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+
+	price := 280.0
+	// a branch may only override its own items and shared ones
+	assert.EqualError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r2", MenuItemID: "i1", Price: &price}),
+		"menu item belongs to another restaurant")
+	assert.ErrorIs(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "missing", Price: &price}), ErrMenuNotFound)
+	zero := 0.0
+	assert.EqualError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "i1", Price: &zero}), "price must be positive")
+
+	// setting again replaces the branch's override
+	assert.NoError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "i1", Price: &price}))
+	off := false
+	assert.NoError(t, s.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "i1", Available: &off}))
+	overrides, err := s.ListItemOverrides(ctx, "r1")
+	assert.NoError(t, err)
+	if assert.Len(t, overrides, 1) {
+		assert.Nil(t, overrides[0].Price)
+		assert.Equal(t, &off, overrides[0].Available)
+	}
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// ErrMenuNotFound is returned when a menu category, item, variant or add-on does not exist.
+var ErrMenuNotFound = errors.New("not found")
+
+// menuItemColumns is the select list understood by scanMenuItem
+const menuItemColumns = "id, COALESCE(restaurant_id, ''), COALESCE(category_id, ''), COALESCE(schedule_id, ''), name, COALESCE(description, ''), price, COALESCE(category, ''), COALESCE(available, FALSE), " +
+	"COALESCE(image_url, ''), image_variants, COALESCE(special_notes, ''), COALESCE(name_am, ''), COALESCE(description_am, ''), COALESCE(allergens, '[]'), COALESCE(dietary, '[]')"
+
+func scanMenuItem(row rowScanner) (*models.MenuItem, error) {
+	var it models.MenuItem
+	if err := row.Scan(&it.ID, &it.RestaurantID, &it.CategoryID, &it.ScheduleID, &it.Name, &it.Description, &it.Price, &it.Category, &it.Available,
+		&it.ImageURL, &it.ImageVariants, &it.SpecialNotes, &it.NameAm, &it.DescriptionAm, &it.Allergens, &it.Dietary); err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// menuCategoryColumns is the select list understood by scanMenuCategory
+const menuCategoryColumns = "id, restaurant_id, name, COALESCE(schedule_id, ''), COALESCE(image_url, ''), image_variants, created_at, updated_at"
+
+func scanMenuCategory(row rowScanner) (*models.MenuCategory, error) {
+	var cat models.MenuCategory
+	if err := row.Scan(&cat.ID, &cat.RestaurantID, &cat.Name, &cat.ScheduleID, &cat.ImageURL, &cat.ImageVariants, &cat.CreatedAt, &cat.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+func (s *MenuSQLService) CreateMenuCategory(ctx context.Context, cat *models.MenuCategory) error {
+	if cat.RestaurantID == "" || cat.Name == "" {
+		return errors.New("restaurant_id and name required")
+	}
+	if err := checkSchedule(ctx, s.conn(), cat.ScheduleID, cat.RestaurantID); err != nil {
+		return err
+	}
+	now := time.Now()
+	cat.CreatedAt, cat.UpdatedAt = now, now
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO menu_categories (id, restaurant_id, name, schedule_id, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$5)",
+		cat.ID, cat.RestaurantID, cat.Name, nullIfEmpty(cat.ScheduleID), now)
+	return err
+}
+
+func (s *MenuSQLService) GetMenuCategory(ctx context.Context, id string) (*models.MenuCategory, error) {
+	cat, err := scanMenuCategory(s.conn().QueryRowContext(ctx, "SELECT "+menuCategoryColumns+" FROM menu_categories WHERE id=$1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	return cat, err
+}
+
+// ListMenuCategories lists a restaurant's categories by name; an empty restaurantID lists all.
+func (s *MenuSQLService) ListMenuCategories(ctx context.Context, restaurantID string) ([]models.MenuCategory, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+menuCategoryColumns+" FROM menu_categories WHERE deleted_at IS NULL AND ($1='' OR restaurant_id=$1) ORDER BY name ASC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cats := []models.MenuCategory{}
+	for rows.Next() {
+		cat, err := scanMenuCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		cats = append(cats, *cat)
+	}
+	return cats, rows.Err()
+}
+
+// UpdateMenuCategory renames a category, along with the category name its items and station
+// routes are grouped by, and sets its schedule.
+func (s *MenuSQLService) UpdateMenuCategory(ctx context.Context, id, name, scheduleID string) (*models.MenuCategory, error) {
+	if name == "" {
+		return nil, errors.New("name required")
+	}
+	var cat *models.MenuCategory
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		cat, err = scanMenuCategory(tx.QueryRowContext(ctx, "SELECT "+menuCategoryColumns+" FROM menu_categories WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id))
+		if err == sql.ErrNoRows {
+			return ErrMenuNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkSchedule(ctx, tx, scheduleID, cat.RestaurantID); err != nil {
+			return err
+		}
+		old := cat.Name
+		cat.Name, cat.ScheduleID, cat.UpdatedAt = name, scheduleID, time.Now()
+		if _, err := tx.ExecContext(ctx, "UPDATE menu_categories SET name=$1, schedule_id=$2, updated_at=$3 WHERE id=$4", name, nullIfEmpty(scheduleID), cat.UpdatedAt, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE menu_items SET category=$1 WHERE category_id=$2", name, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE kitchen_station_routes SET category=$1 WHERE category=$2 AND station_id IN (SELECT id FROM kitchen_stations WHERE restaurant_id=$3)",
+			name, old, cat.RestaurantID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+// DeleteMenuCategory removes an empty category; items must be moved or deleted first.
+func (s *MenuSQLService) DeleteMenuCategory(ctx context.Context, id string) error {
+	var items int
+	if err := s.conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM menu_items WHERE category_id=$1 AND deleted_at IS NULL", id).Scan(&items); err != nil {
+		return err
+	}
+	if items > 0 {
+		return errors.New("category still has items")
+	}
+	res, err := s.conn().ExecContext(ctx, "UPDATE menu_categories SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// resolveCategory checks an item's fields and, when CategoryID is set, that the category
+// belongs to the item's restaurant, copying its name onto the item. The item's schedule must
+// be one of its restaurant's.
+func resolveCategory(ctx context.Context, q sqlQueryer, it *models.MenuItem) error {
+	if it.Name == "" || it.Price <= 0 || (it.Category == "" && it.CategoryID == "") {
+		return errors.New("invalid item")
+	}
+	if err := models.ValidateTags(it.Allergens, it.Dietary); err != nil {
+		return err
+	}
+	if it.CategoryID == "" {
+		return checkSchedule(ctx, q, it.ScheduleID, it.RestaurantID)
+	}
+	var restaurantID string
+	err := q.QueryRowContext(ctx, "SELECT restaurant_id, name FROM menu_categories WHERE id=$1 AND deleted_at IS NULL", it.CategoryID).Scan(&restaurantID, &it.Category)
+	if err == sql.ErrNoRows {
+		return errors.New("category " + it.CategoryID + " not found")
+	}
+	if err != nil {
+		return err
+	}
+	if it.RestaurantID == "" {
+		it.RestaurantID = restaurantID
+	}
+	if it.RestaurantID != restaurantID {
+		return errors.New("category belongs to another restaurant")
+	}
+	return checkSchedule(ctx, q, it.ScheduleID, it.RestaurantID)
+}
+
+func (s *MenuSQLService) GetItem(ctx context.Context, id string) (*models.MenuItem, error) {
+	it, err := scanMenuItem(s.conn().QueryRowContext(ctx, "SELECT "+menuItemColumns+" FROM menu_items WHERE id=$1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	return it, err
+}
+
+// ListItems lists menu items by name, optionally for one restaurant or category. A restaurant's
+// list includes the shared items that have no restaurant.
+func (s *MenuSQLService) ListItems(ctx context.Context, restaurantID, categoryID string) ([]models.MenuItem, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+menuItemColumns+" FROM menu_items WHERE deleted_at IS NULL "+
+		"AND ($1='' OR restaurant_id=$1 OR restaurant_id IS NULL) AND ($2='' OR category_id=$2) ORDER BY name ASC", restaurantID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.MenuItem{}
+	for rows.Next() {
+		it, err := scanMenuItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *it)
+	}
+	return items, rows.Err()
+}
+
+func (s *MenuSQLService) SetItemAvailability(ctx context.Context, id string, available bool) error {
+	res, err := s.conn().ExecContext(ctx, "UPDATE menu_items SET available=$1, updated_at=NOW() WHERE id=$2 AND deleted_at IS NULL", available, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// menuOptionTable is a table of per-item options: menu_variants or menu_addons
+type menuOptionTable string
+
+const (
+	variantTable menuOptionTable = "menu_variants"
+	addonTable   menuOptionTable = "menu_addons"
+)
+
+func (s *MenuSQLService) createOption(ctx context.Context, table menuOptionTable, id, itemID, name string, delta float64, a models.Allergens, d models.DietaryTags) error {
+	if name == "" {
+		return errors.New("name required")
+	}
+	if err := models.ValidateTags(a, d); err != nil {
+		return err
+	}
+	if _, err := s.GetItem(ctx, itemID); err != nil {
+		return err
+	}
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO "+string(table)+" (id, item_id, name, price_delta, allergens, dietary) VALUES ($1,$2,$3,$4,$5,$6)",
+		id, itemID, name, delta, a, d)
+	return err
+}
+
+func (s *MenuSQLService) updateOption(ctx context.Context, table menuOptionTable, id, name string, delta float64, a models.Allergens, d models.DietaryTags) (string, error) {
+	if name == "" {
+		return "", errors.New("name required")
+	}
+	if err := models.ValidateTags(a, d); err != nil {
+		return "", err
+	}
+	var itemID string
+	err := s.conn().QueryRowContext(ctx, "UPDATE "+string(table)+" SET name=$1, price_delta=$2, allergens=$3, dietary=$4 WHERE id=$5 RETURNING item_id",
+		name, delta, a, d, id).Scan(&itemID)
+	if err == sql.ErrNoRows {
+		return "", ErrMenuNotFound
+	}
+	return itemID, err
+}
+
+func (s *MenuSQLService) deleteOption(ctx context.Context, table menuOptionTable, id string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM "+string(table)+" WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+func (s *MenuSQLService) CreateVariant(ctx context.Context, v *models.MenuVariant) error {
+	return s.createOption(ctx, variantTable, v.ID, v.ItemID, v.Name, v.PriceDelta, v.Allergens, v.Dietary)
+}
+
+func (s *MenuSQLService) UpdateVariant(ctx context.Context, v *models.MenuVariant) (err error) {
+	v.ItemID, err = s.updateOption(ctx, variantTable, v.ID, v.Name, v.PriceDelta, v.Allergens, v.Dietary)
+	return err
+}
+
+func (s *MenuSQLService) DeleteVariant(ctx context.Context, id string) error {
+	return s.deleteOption(ctx, variantTable, id)
+}
+
+func (s *MenuSQLService) CreateAddon(ctx context.Context, a *models.MenuAddon) error {
+	return s.createOption(ctx, addonTable, a.ID, a.ItemID, a.Name, a.PriceDelta, a.Allergens, a.Dietary)
+}
+
+func (s *MenuSQLService) UpdateAddon(ctx context.Context, a *models.MenuAddon) (err error) {
+	a.ItemID, err = s.updateOption(ctx, addonTable, a.ID, a.Name, a.PriceDelta, a.Allergens, a.Dietary)
+	return err
+}
+
+func (s *MenuSQLService) DeleteAddon(ctx context.Context, id string) error {
+	return s.deleteOption(ctx, addonTable, id)
+}
+
+// loadItemOptions returns the variants or add-ons of the items matching where, by item
+func loadItemOptions(ctx context.Context, q sqlQueryer, table menuOptionTable, where string, args ...interface{}) (map[string][]models.MenuVariant, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, item_id, name, price_delta, COALESCE(allergens, '[]'), COALESCE(dietary, '[]') FROM "+string(table)+
+		" WHERE item_id IN (SELECT id FROM menu_items WHERE "+where+") ORDER BY price_delta ASC, name ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]models.MenuVariant{}
+	for rows.Next() {
+		var v models.MenuVariant
+		if err := rows.Scan(&v.ID, &v.ItemID, &v.Name, &v.PriceDelta, &v.Allergens, &v.Dietary); err != nil {
+			return nil, err
+		}
+		out[v.ItemID] = append(out[v.ItemID], v)
+	}
+	return out, rows.Err()
+}
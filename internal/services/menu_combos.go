@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const comboColumns = "id, restaurant_id, name, COALESCE(description, ''), price, available, created_at, updated_at"
+
+func scanCombo(row rowScanner) (*models.Combo, error) {
+	var c models.Combo
+	if err := row.Scan(&c.ID, &c.RestaurantID, &c.Name, &c.Description, &c.Price, &c.Available, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *MenuSQLService) CreateCombo(ctx context.Context, c *models.Combo) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	c.CreatedAt, c.UpdatedAt = now, now
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO menu_combos (id, restaurant_id, name, description, price, available, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$7)",
+			c.ID, c.RestaurantID, c.Name, c.Description, c.Price, c.Available, now); err != nil {
+			return err
+		}
+		return writeComboSlots(ctx, tx, c)
+	})
+}
+
+func (s *MenuSQLService) GetCombo(ctx context.Context, id string) (*models.Combo, error) {
+	return getCombo(ctx, s.conn(), id)
+}
+
+// ListCombos returns a restaurant's combos, only those on sale when availableOnly is set
+func (s *MenuSQLService) ListCombos(ctx context.Context, restaurantID string, availableOnly bool) ([]models.Combo, error) {
+	return listCombos(ctx, s.conn(), restaurantID, availableOnly)
+}
+
+func listCombos(ctx context.Context, q sqlQueryer, restaurantID string, availableOnly bool) ([]models.Combo, error) {
+	query := "SELECT " + comboColumns + " FROM menu_combos WHERE restaurant_id=$1 AND deleted_at IS NULL"
+	if availableOnly {
+		query += " AND available"
+	}
+	rows, err := q.QueryContext(ctx, query+" ORDER BY name ASC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	combos := []models.Combo{}
+	for rows.Next() {
+		c, err := scanCombo(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		combos = append(combos, *c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slots, err := loadComboSlots(ctx, q, "combo_id IN (SELECT id FROM menu_combos WHERE restaurant_id = $1 AND deleted_at IS NULL)", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range combos {
+		combos[i].Slots = slots[combos[i].ID]
+	}
+	return combos, nil
+}
+
+// UpdateCombo replaces a combo's details and slots; it stays with its restaurant. Orders keep
+// the slot names and prices they were placed with.
+func (s *MenuSQLService) UpdateCombo(ctx context.Context, c *models.Combo) error {
+	cur, err := s.GetCombo(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	c.RestaurantID, c.CreatedAt, c.UpdatedAt = cur.RestaurantID, cur.CreatedAt, time.Now()
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE menu_combos SET name=$1, description=$2, price=$3, available=$4, updated_at=$5 WHERE id=$6",
+			c.Name, c.Description, c.Price, c.Available, c.UpdatedAt, c.ID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM combo_slots WHERE combo_id=$1", c.ID); err != nil {
+			return err
+		}
+		return writeComboSlots(ctx, tx, c)
+	})
+}
+
+func (s *MenuSQLService) DeleteCombo(ctx context.Context, id string) error {
+	res, err := s.conn().ExecContext(ctx, "UPDATE menu_combos SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// writeComboSlots inserts a combo's slots and choices; every choice must be an item the
+// combo's restaurant offers.
+func writeComboSlots(ctx context.Context, q sqlQueryer, c *models.Combo) error {
+	for si := range c.Slots {
+		slot := &c.Slots[si]
+		if slot.ID == "" {
+			slot.ID = uuid.New().String()
+		}
+		if _, err := q.ExecContext(ctx, "INSERT INTO combo_slots (id, combo_id, name, position) VALUES ($1,$2,$3,$4)", slot.ID, c.ID, slot.Name, slot.Position); err != nil {
+			return err
+		}
+		for ci := range slot.Choices {
+			ch := &slot.Choices[ci]
+			err := q.QueryRowContext(ctx, "SELECT name FROM menu_items WHERE id=$1 AND deleted_at IS NULL AND (restaurant_id IS NULL OR restaurant_id=$2)", ch.MenuItemID, c.RestaurantID).
+				Scan(&ch.Name)
+			if err == sql.ErrNoRows {
+				return errors.New("menu item " + ch.MenuItemID + " is not on this restaurant's menu")
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := q.ExecContext(ctx, "INSERT INTO combo_slot_items (slot_id, menu_item_id, upcharge, is_default, position) VALUES ($1,$2,$3,$4,$5)",
+				slot.ID, ch.MenuItemID, ch.Upcharge, ch.IsDefault, ci); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getCombo(ctx context.Context, q sqlQueryer, id string) (*models.Combo, error) {
+	c, err := scanCombo(q.QueryRowContext(ctx, "SELECT "+comboColumns+" FROM menu_combos WHERE id=$1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	slots, err := loadComboSlots(ctx, q, "combo_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	c.Slots = slots[id]
+	return c, nil
+}
+
+// loadComboSlots loads the slots matched by where (a condition on combo_slots) with their
+// choices, keyed by combo and in position order
+func loadComboSlots(ctx context.Context, q sqlQueryer, where string, args ...interface{}) (map[string][]models.ComboSlot, error) {
+	rows, err := q.QueryContext(ctx, "SELECT s.id, s.combo_id, s.name, s.position, i.menu_item_id, COALESCE(m.name, ''), i.upcharge, i.is_default FROM combo_slots s "+
+		"JOIN combo_slot_items i ON i.slot_id = s.id LEFT JOIN menu_items m ON m.id = i.menu_item_id "+
+		"WHERE s.id IN (SELECT id FROM combo_slots WHERE "+where+") ORDER BY s.combo_id, s.position ASC, s.name ASC, s.id, i.position ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]models.ComboSlot{}
+	for rows.Next() {
+		var slot models.ComboSlot
+		var comboID string
+		var ch models.ComboChoice
+		if err := rows.Scan(&slot.ID, &comboID, &slot.Name, &slot.Position, &ch.MenuItemID, &ch.Name, &ch.Upcharge, &ch.IsDefault); err != nil {
+			return nil, err
+		}
+		slots := out[comboID]
+		if n := len(slots); n == 0 || slots[n-1].ID != slot.ID {
+			slots = append(slots, slot)
+		}
+		slots[len(slots)-1].Choices = append(slots[len(slots)-1].Choices, ch)
+		out[comboID] = slots
+	}
+	return out, rows.Err()
+}
+
+// priceOrderLines builds the lines one requested item becomes: a single line for a menu item,
+// or a combo line followed by its components.
+func priceOrderLines(ctx context.Context, q sqlQueryer, orderID, restaurantID string, at time.Time, it CreateOrderItemReq) ([]models.OrderItem, error) {
+	if it.ComboID != "" {
+		return priceComboLines(ctx, q, orderID, restaurantID, at, it)
+	}
+	if it.MenuItemID == "" {
+		return nil, errors.New("menu_item_id or combo_id required")
+	}
+	oi, err := priceOrderLine(ctx, q, orderID, restaurantID, at, it)
+	if err != nil {
+		return nil, err
+	}
+	return []models.OrderItem{*oi}, nil
+}
+
+// priceComboLines prices a combo as one line at the combo price plus the chosen upcharges and
+// the components' modifier charges. Each slot becomes a component line the kitchen prepares,
+// checked and snapshotted like any item but carrying no price of its own; instead the combo
+// line's total is allocated across the components by their standalone prices plus upcharges.
+func priceComboLines(ctx context.Context, q sqlQueryer, orderID, restaurantID string, at time.Time, it CreateOrderItemReq) ([]models.OrderItem, error) {
+	if it.MenuItemID != "" || it.VariantID != "" || len(it.AddonIDs) > 0 || len(it.Modifiers) > 0 {
+		return nil, errors.New("a combo line takes combo_choices instead of an item, variant, add-ons or modifiers")
+	}
+	if it.Quantity < 1 {
+		return nil, errors.New("quantity must be at least 1")
+	}
+	combo, err := getCombo(ctx, q, it.ComboID)
+	if err == ErrMenuNotFound || (err == nil && (!combo.Available || (restaurantID != "" && combo.RestaurantID != restaurantID))) {
+		return nil, errors.New("combo " + it.ComboID + " is not available")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := map[string]models.ComboSelection{}
+	for _, sel := range it.ComboChoices {
+		if _, dup := chosen[sel.SlotID]; dup {
+			return nil, errors.New("combo slot " + sel.SlotID + " chosen twice")
+		}
+		chosen[sel.SlotID] = sel
+	}
+	line := models.OrderItem{
+		ID: uuid.New().String(), OrderID: orderID, ComboID: combo.ID, Name: combo.Name,
+		Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions, Course: it.Course, Addons: []models.OrderItemAddon{},
+	}
+	unit := models.MoneyFromFloat(combo.Price)
+	var components []models.OrderItem
+	var weights []models.Money
+	for _, slot := range combo.Slots {
+		sel, ok := chosen[slot.ID]
+		delete(chosen, slot.ID)
+		var choice *models.ComboChoice
+		for i := range slot.Choices {
+			ch := &slot.Choices[i]
+			if (ok && ch.MenuItemID == sel.MenuItemID) || (!ok && (ch.IsDefault || len(slot.Choices) == 1)) {
+				choice = ch
+				break
+			}
+		}
+		if choice == nil {
+			if ok {
+				return nil, errors.New("menu item " + sel.MenuItemID + " is not a choice for " + slot.Name)
+			}
+			return nil, errors.New("choose an item for " + slot.Name)
+		}
+		oi, err := priceOrderLine(ctx, q, orderID, restaurantID, at, CreateOrderItemReq{MenuItemID: choice.MenuItemID, Quantity: it.Quantity, Modifiers: sel.Modifiers, Course: it.Course})
+		if err != nil {
+			return nil, err
+		}
+		upcharge := models.MoneyFromFloat(choice.Upcharge)
+		for _, m := range oi.Modifiers {
+			unit += m.Charge()
+		}
+		unit += upcharge
+		weights = append(weights, oi.Price+upcharge)
+		oi.ParentItemID, oi.ComboSlot = line.ID, slot.Name
+		oi.Price, oi.TotalPrice = 0, 0
+		components = append(components, *oi)
+	}
+	for slotID := range chosen {
+		return nil, errors.New("combo slot " + slotID + " is not part of " + combo.Name)
+	}
+
+	line.Price = unit
+	line.TotalPrice = unit.Times(it.Quantity)
+	for i, share := range allocateRevenue(line.TotalPrice, weights) {
+		components[i].AllocatedTotal = share
+	}
+	return append([]models.OrderItem{line}, components...), nil
+}
+
+// allocateRevenue splits total in proportion to weights, handing the cents left over by
+// rounding to the largest remainders so the shares add back up to total exactly. Without any
+// weight the total is split evenly.
+func allocateRevenue(total models.Money, weights []models.Money) []models.Money {
+	var sum models.Money
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 {
+		return total.Split(len(weights))
+	}
+	out := make([]models.Money, len(weights))
+	rems := make([]models.Money, len(weights))
+	order := make([]int, len(weights))
+	given := models.Money(0)
+	for i, w := range weights {
+		out[i], rems[i] = total*w/sum, total*w%sum
+		given += out[i]
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rems[order[a]] > rems[order[b]] })
+	for k := 0; given < total; k++ {
+		out[order[k%len(order)]]++
+		given++
+	}
+	return out
+}
+
+// comboSelections rebuilds the choices behind a combo line's components, for reorders. Slots
+// are matched by the names the components were ordered under.
+func comboSelections(combo *models.Combo, components []models.OrderItem) []models.ComboSelection {
+	var out []models.ComboSelection
+	for _, c := range components {
+		for _, slot := range combo.Slots {
+			if slot.Name == c.ComboSlot {
+				out = append(out, models.ComboSelection{SlotID: slot.ID, MenuItemID: c.MenuItemID, Modifiers: modifierSelections(c.Modifiers)})
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateRevenue(t *testing.T) {
+	// a 10.00 combo of a 7.00 burger, 2.50 fries and a 2.50 drink
+	shares := allocateRevenue(1000, []models.Money{700, 250, 250})
+	assert.Equal(t, []models.Money{584, 208, 208}, shares)
+
+	// the cents lost to rounding go to the largest remainders
+	shares = allocateRevenue(100, []models.Money{1, 1, 1})
+	assert.Equal(t, []models.Money{34, 33, 33}, shares)
+
+	// free components split the price evenly
+	assert.Equal(t, []models.Money{501, 500}, allocateRevenue(1001, []models.Money{0, 0}))
+	assert.Equal(t, []models.Money{0, 250}, allocateRevenue(250, []models.Money{0, 400}))
+}
+
+func TestOfferedCombos(t *testing.T) {
+	combos := []models.Combo{
+		{ID: "meal", Slots: []models.ComboSlot{
+			{Name: "Main", Choices: []models.ComboChoice{{MenuItemID: "burger"}, {MenuItemID: "wrap"}}},
+			{Name: "Drink", Choices: []models.ComboChoice{{MenuItemID: "cola"}}},
+		}},
+		{ID: "breakfast", Slots: []models.ComboSlot{
+			{Name: "Main", Choices: []models.ComboChoice{{MenuItemID: "pancakes"}}},
+		}},
+	}
+	out := offeredCombos(combos, map[string]bool{"burger": true, "cola": true})
+	assert.Len(t, out, 1)
+	assert.Equal(t, []models.ComboChoice{{MenuItemID: "burger"}}, out[0].Slots[0].Choices)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// menuTestDriver runs the menu and order queries on sqlite: $n placeholders become ?n, casts
+// and row locks are dropped and NOW() and TO_CHAR() are provided
+type menuTestDriver struct{ sqlite3.SQLiteDriver }
+
+type menuTestConn struct{ driver.Conn }
+
+var (
+	pgPlaceholder = regexp.MustCompile(`\$(\d+)`)
+	pgCast        = regexp.MustCompile(`::[a-z]+`)
+)
+
+func (d *menuTestDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return menuTestConn{c}, nil
+}
+
+func (c menuTestConn) Prepare(query string) (driver.Stmt, error) {
+	query = pgPlaceholder.ReplaceAllString(query, "?$1")
+	query = pgCast.ReplaceAllString(strings.ReplaceAll(query, " FOR UPDATE", ""), "")
+	return c.Conn.Prepare(query)
+}
+
+func init() {
+	sql.Register("menu_test", &menuTestDriver{sqlite3.SQLiteDriver{
+		ConnectHook: func(c *sqlite3.SQLiteConn) error {
+			if err := c.RegisterFunc("now", func() string { return time.Now().UTC().Format("2006-01-02 15:04:05.999999999") }, false); err != nil {
+				return err
+			}
+			// dates are stored as YYYY-MM-DD text, the only format the queries ask for
+			return c.RegisterFunc("to_char", func(v interface{}, layout string) interface{} { return v }, true)
+		},
+	}})
+}
+
+const menuTestSchema = `
+CREATE TABLE restaurants (id TEXT PRIMARY KEY, name TEXT NOT NULL, timezone TEXT DEFAULT 'UTC', currency TEXT DEFAULT 'USD',
+	tax_rate NUMERIC, tax_inclusive BOOLEAN, service_charge_rate NUMERIC, delivery_fee INTEGER, rounding_increment INTEGER, rounding_mode TEXT,
+	created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP);
+CREATE TABLE tables (id TEXT PRIMARY KEY, restaurant_id TEXT);
+CREATE TABLE menu_categories (id TEXT PRIMARY KEY, restaurant_id TEXT NOT NULL, name TEXT NOT NULL, schedule_id TEXT, image_url TEXT,
+	image_variants TEXT, created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP);
+CREATE TABLE menu_items (id TEXT PRIMARY KEY, restaurant_id TEXT, category_id TEXT, schedule_id TEXT, name TEXT NOT NULL, description TEXT,
+	price NUMERIC NOT NULL, category TEXT NOT NULL, available BOOLEAN DEFAULT TRUE, image_url TEXT, image_variants TEXT, special_notes TEXT,
+	name_am TEXT, description_am TEXT, allergens TEXT, dietary TEXT, created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP);
+CREATE TABLE menu_variants (id TEXT PRIMARY KEY, item_id TEXT NOT NULL, name TEXT NOT NULL, price_delta NUMERIC DEFAULT 0, allergens TEXT, dietary TEXT);
+CREATE TABLE menu_addons (id TEXT PRIMARY KEY, item_id TEXT NOT NULL, name TEXT NOT NULL, price_delta NUMERIC DEFAULT 0, allergens TEXT, dietary TEXT);
+CREATE TABLE modifier_groups (id TEXT PRIMARY KEY, item_id TEXT NOT NULL, parent_option_id TEXT, name TEXT NOT NULL,
+	min_select INTEGER NOT NULL DEFAULT 0, max_select INTEGER NOT NULL DEFAULT 0, free_quantity INTEGER NOT NULL DEFAULT 0, position INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE modifier_options (id TEXT PRIMARY KEY, group_id TEXT NOT NULL, name TEXT NOT NULL, price_delta NUMERIC NOT NULL DEFAULT 0,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE, allergens TEXT, dietary TEXT, position INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE menu_combos (id TEXT PRIMARY KEY, restaurant_id TEXT NOT NULL, name TEXT NOT NULL, description TEXT, price NUMERIC NOT NULL,
+	available BOOLEAN NOT NULL DEFAULT TRUE, created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP);
+CREATE TABLE combo_slots (id TEXT PRIMARY KEY, combo_id TEXT NOT NULL, name TEXT NOT NULL, position INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE combo_slot_items (slot_id TEXT NOT NULL, menu_item_id TEXT NOT NULL, upcharge NUMERIC NOT NULL DEFAULT 0,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE, position INTEGER NOT NULL DEFAULT 0, PRIMARY KEY (slot_id, menu_item_id));
+CREATE TABLE menu_item_overrides (restaurant_id TEXT NOT NULL, menu_item_id TEXT NOT NULL, price NUMERIC, available BOOLEAN,
+	updated_at TIMESTAMP, PRIMARY KEY (restaurant_id, menu_item_id));
+CREATE TABLE menu_schedules (id TEXT PRIMARY KEY, restaurant_id TEXT NOT NULL, name TEXT NOT NULL, windows TEXT NOT NULL DEFAULT '[]',
+	start_date TEXT, end_date TEXT, holidays TEXT NOT NULL DEFAULT '', created_at TIMESTAMP, updated_at TIMESTAMP);
+CREATE TABLE restaurant_holidays (restaurant_id TEXT NOT NULL, date TEXT NOT NULL, name TEXT, PRIMARY KEY (restaurant_id, date));
+CREATE TABLE menu_translations (entity_type TEXT NOT NULL, entity_id TEXT NOT NULL, field TEXT NOT NULL, locale TEXT NOT NULL,
+	value TEXT NOT NULL, updated_at TIMESTAMP, PRIMARY KEY (entity_type, entity_id, field, locale));
+CREATE TABLE menu_versions (id TEXT PRIMARY KEY, restaurant_id TEXT NOT NULL, number INTEGER NOT NULL, status TEXT NOT NULL DEFAULT 'draft',
+	note TEXT, based_on TEXT, rows TEXT NOT NULL DEFAULT '[]', snapshot TEXT, publish_at TIMESTAMP, published_at TIMESTAMP, created_by TEXT,
+	created_at TIMESTAMP, updated_at TIMESTAMP, UNIQUE (restaurant_id, number));
+CREATE TABLE kitchen_stations (id TEXT PRIMARY KEY, restaurant_id TEXT, name TEXT NOT NULL, is_default BOOLEAN NOT NULL DEFAULT FALSE, created_at TIMESTAMP);
+CREATE TABLE kitchen_station_routes (id TEXT PRIMARY KEY, station_id TEXT NOT NULL, category TEXT, menu_item_id TEXT);
+CREATE TABLE orders (id TEXT PRIMARY KEY, customer_id TEXT NOT NULL, session_id TEXT, restaurant_id TEXT, status TEXT NOT NULL, type TEXT,
+	table_id TEXT, pickup_at TIMESTAMP, delivery_address TEXT, order_number INTEGER, business_date TEXT, parent_order_id TEXT, split_type TEXT,
+	merged_into_order_id TEXT, discount_id TEXT, currency TEXT, client_order_id TEXT, client_created_at TIMESTAMP, estimated_ready_at TIMESTAMP,
+	eta_manual BOOLEAN, subtotal INTEGER, discount_amount INTEGER, service_charge INTEGER, delivery_fee INTEGER, tax_amount INTEGER,
+	tax_inclusive BOOLEAN, rounding_adjustment INTEGER, total_amount INTEGER NOT NULL DEFAULT 0, menu_version_id TEXT,
+	created_at TIMESTAMP, updated_at TIMESTAMP);
+CREATE TABLE order_items (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, menu_item_id TEXT, name TEXT NOT NULL, price INTEGER NOT NULL,
+	quantity INTEGER NOT NULL, total_price INTEGER NOT NULL, special_instructions TEXT, variant_id TEXT, variant_name TEXT,
+	variant_price_delta INTEGER, addons TEXT, modifiers TEXT, ticket_id TEXT, course TEXT, allergens TEXT, allergen_alerts TEXT, combo_id TEXT,
+	parent_item_id TEXT, combo_slot TEXT, allocated_total INTEGER, voided_at TIMESTAMP, void_reason TEXT, void_note TEXT, voided_by TEXT,
+	void_approved_by TEXT);
+CREATE TABLE kitchen_tickets (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, station_id TEXT, course TEXT, status TEXT NOT NULL DEFAULT 'queued',
+	fired_at TIMESTAMP, created_at TIMESTAMP, started_at TIMESTAMP, bumped_at TIMESTAMP, recalled_at TIMESTAMP, recall_count INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP);
+CREATE TABLE payments (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, amount INTEGER NOT NULL, status TEXT NOT NULL);
+CREATE TABLE order_audits (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, action TEXT NOT NULL, user_id TEXT, details TEXT, created_at TIMESTAMP);
+`
+
+// newMenuTestDB returns a menu service on an empty sqlite database holding the menu tables
+func newMenuTestDB(t *testing.T) (*MenuSQLService, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("menu_test", filepath.Join(t.TempDir(), "menu.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sqlite takes one writer at a time
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range strings.Split(strings.TrimSpace(menuTestSchema), ";\n") {
+		if _, err := db.ExecContext(context.Background(), stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewMenuSQLService(db), db
+}
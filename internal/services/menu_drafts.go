@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// MenuEntry is a kind of menu entry, named by its table
+type MenuEntry string
+
+const (
+	MenuEntryCategory       MenuEntry = "menu_categories"
+	MenuEntryItem           MenuEntry = "menu_items"
+	MenuEntryVariant        MenuEntry = "menu_variants"
+	MenuEntryAddon          MenuEntry = "menu_addons"
+	MenuEntryModifierGroup  MenuEntry = "modifier_groups"
+	MenuEntryModifierOption MenuEntry = "modifier_options"
+	MenuEntryCombo          MenuEntry = "menu_combos"
+	MenuEntrySchedule       MenuEntry = "menu_schedules"
+)
+
+// menuEntryOwners find the restaurant whose menu holds a live entry; shared items have none
+var menuEntryOwners = map[MenuEntry]string{
+	MenuEntryCategory:       "SELECT restaurant_id FROM menu_categories WHERE id=$1",
+	MenuEntryItem:           "SELECT COALESCE(restaurant_id, '') FROM menu_items WHERE id=$1",
+	MenuEntryVariant:        "SELECT COALESCE(i.restaurant_id, '') FROM menu_variants x JOIN menu_items i ON i.id=x.item_id WHERE x.id=$1",
+	MenuEntryAddon:          "SELECT COALESCE(i.restaurant_id, '') FROM menu_addons x JOIN menu_items i ON i.id=x.item_id WHERE x.id=$1",
+	MenuEntryModifierGroup:  "SELECT COALESCE(i.restaurant_id, '') FROM modifier_groups x JOIN menu_items i ON i.id=x.item_id WHERE x.id=$1",
+	MenuEntryModifierOption: "SELECT COALESCE(i.restaurant_id, '') FROM modifier_options x JOIN modifier_groups g ON g.id=x.group_id JOIN menu_items i ON i.id=g.item_id WHERE x.id=$1",
+	MenuEntryCombo:          "SELECT restaurant_id FROM menu_combos WHERE id=$1",
+	MenuEntrySchedule:       "SELECT restaurant_id FROM menu_schedules WHERE id=$1",
+}
+
+// TranslatedEntry is the kind of entry a translation is for
+func TranslatedEntry(t models.TranslatableEntity) MenuEntry {
+	return MenuEntry(translationTables[t])
+}
+
+// EntryRestaurant returns the restaurant whose menu holds an entry, looking in the drafts for
+// entries that are not live yet. It is empty for entries of shared items.
+func (s *MenuSQLService) EntryRestaurant(ctx context.Context, entry MenuEntry, id string) (string, error) {
+	query, ok := menuEntryOwners[entry]
+	if !ok {
+		return "", ErrMenuNotFound
+	}
+	var restaurantID string
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&restaurantID)
+	if err != sql.ErrNoRows {
+		return restaurantID, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT restaurant_id, snapshot FROM menu_versions WHERE status=$1 AND snapshot IS NOT NULL ORDER BY number DESC",
+		string(models.MenuVersionDraft))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var snap models.MenuSnapshot
+		if err := rows.Scan(&restaurantID, &snap); err != nil {
+			return "", err
+		}
+		for _, row := range snap[string(entry)] {
+			if row["id"] == id {
+				return restaurantID, nil
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return "", ErrMenuNotFound
+}
+
+// EditEntryDraft is EditMenuDraft for the restaurant whose menu holds an entry
+func (s *MenuSQLService) EditEntryDraft(ctx context.Context, entry MenuEntry, id, userID string, edit func(d *MenuSQLService) error) (*models.MenuVersion, error) {
+	restaurantID, err := s.EntryRestaurant(ctx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.EditMenuDraft(ctx, restaurantID, userID, edit)
+}
+
+// EditMenuDraft makes a change to a restaurant's menu in its working draft, the newest of its
+// drafts, which is started from the live menu when there is none. edit makes the change on a
+// copy of the service that sees the menu as the draft has it; nothing goes live until the draft
+// is published. Changes belonging to no restaurant, such as to shared items, are made live.
+func (s *MenuSQLService) EditMenuDraft(ctx context.Context, restaurantID, userID string, edit func(d *MenuSQLService) error) (*models.MenuVersion, error) {
+	if restaurantID == "" {
+		return nil, edit(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := lockMenu(ctx, tx, restaurantID); err != nil {
+		return nil, err
+	}
+	v, err := scanMenuVersion(tx.QueryRowContext(ctx, "SELECT "+menuVersionColumns+", COALESCE(rows, '[]'), snapshot FROM menu_versions "+
+		"WHERE restaurant_id=$1 AND status=$2 ORDER BY number DESC LIMIT 1 FOR UPDATE", restaurantID, string(models.MenuVersionDraft)), true)
+	if err == sql.ErrNoRows {
+		v = &models.MenuVersion{RestaurantID: restaurantID, Status: models.MenuVersionDraft, CreatedBy: userID}
+		if v.Rows, v.Snapshot, err = (&MenuSQLService{db: s.db, draft: tx}).readMenu(ctx, restaurantID); err != nil {
+			return nil, err
+		}
+		err = insertMenuVersion(ctx, tx, v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.editDraft(ctx, tx, v, edit); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// editDraft applies v to the live menu inside tx, runs edit on a copy of the service working in
+// tx, and saves the menu it leaves as v's rows and snapshot. The live menu is then put back.
+func (s *MenuSQLService) editDraft(ctx context.Context, tx *sql.Tx, v *models.MenuVersion, edit func(d *MenuSQLService) error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT menu_draft"); err != nil {
+		return err
+	}
+	err := func() error {
+		res, err := applyMenuVersion(ctx, tx, v)
+		if err != nil {
+			return err
+		}
+		if err := menuRowErrors(res); err != nil {
+			return err
+		}
+		d := &MenuSQLService{db: s.db, draft: tx}
+		if err := edit(d); err != nil {
+			return err
+		}
+		v.Rows, v.Snapshot, err = d.readMenu(ctx, v.RestaurantID)
+		return err
+	}()
+	if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT menu_draft"); err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return err
+	}
+	v.UpdatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE menu_versions SET rows=$1, snapshot=$2, note=$3, updated_at=$4 WHERE id=$5",
+		v.Rows, v.Snapshot, nullIfEmpty(v.Note), v.UpdatedAt, v.ID)
+	return err
+}
+
+// readMenu returns a restaurant's menu as import rows and as a snapshot
+func (s *MenuSQLService) readMenu(ctx context.Context, restaurantID string) (models.MenuRows, models.MenuSnapshot, error) {
+	rows, err := s.ExportMenu(ctx, restaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	snap, err := snapshotMenu(ctx, s.conn(), restaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, snap, nil
+}
+
+// lockMenu holds a restaurant's row until tx ends, so its menu's drafts and versions are
+// changed one at a time
+func lockMenu(ctx context.Context, tx *sql.Tx, restaurantID string) error {
+	var id string
+	err := tx.QueryRowContext(ctx, "SELECT id FROM restaurants WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", restaurantID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrMenuNotFound
+	}
+	return err
+}
+
+// applyMenuVersion makes the restaurant's own menu match the version's snapshot, or its rows
+// when it has no snapshot
+func applyMenuVersion(ctx context.Context, q sqlQueryer, v *models.MenuVersion) (*models.MenuImportResult, error) {
+	if v.Snapshot == nil {
+		return syncMenu(ctx, q, v.RestaurantID, v.Rows)
+	}
+	res := &models.MenuImportResult{Created: map[string]int{}, Updated: map[string]int{}, Errors: []models.MenuImportError{}}
+	return res, applyMenuSnapshot(ctx, q, v.RestaurantID, v.Snapshot)
+}
+
+// menuRowErrors turns the row problems of an import into an error
+func menuRowErrors(res *models.MenuImportResult) error {
+	errs := make([]error, len(res.Errors))
+	for i, e := range res.Errors {
+		errs[i] = errors.New("row " + strconv.Itoa(e.Row) + ": " + e.Error)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/storage"
+)
+
+// MaxMenuImageBytes is the largest image upload accepted
+const MaxMenuImageBytes = 10 << 20
+
+// maxMenuImagePixels guards against small files that decode to huge images
+const maxMenuImagePixels = 50_000_000
+
+// menuImageSizes is the longest edge of each stored size; images are never enlarged
+var menuImageSizes = []struct {
+	name    string
+	maxEdge int
+}{
+	{models.ImageFull, 1600},
+	{models.ImageCard, 600},
+	{models.ImageThumbnail, 160},
+}
+
+// MenuImageService stores the photos of menu items and categories
+type MenuImageService struct {
+	db    *sql.DB
+	store storage.Store
+}
+
+func NewMenuImageService(db *sql.DB, store storage.Store) *MenuImageService {
+	return &MenuImageService{db: db, store: store}
+}
+
+// menuImageTable is a table of entries with images: menu_items or menu_categories
+type menuImageTable string
+
+const (
+	itemImages     menuImageTable = "menu_items"
+	categoryImages menuImageTable = "menu_categories"
+)
+
+func (s *MenuImageService) SetItemImage(ctx context.Context, id string, data []byte) (models.ImageVariants, error) {
+	return s.setImage(ctx, itemImages, "menu/items/"+id+"/", id, data)
+}
+
+func (s *MenuImageService) DeleteItemImage(ctx context.Context, id string) error {
+	return s.deleteImage(ctx, itemImages, "menu/items/"+id+"/", id)
+}
+
+func (s *MenuImageService) SetCategoryImage(ctx context.Context, id string, data []byte) (models.ImageVariants, error) {
+	return s.setImage(ctx, categoryImages, "menu/categories/"+id+"/", id, data)
+}
+
+func (s *MenuImageService) DeleteCategoryImage(ctx context.Context, id string) error {
+	return s.deleteImage(ctx, categoryImages, "menu/categories/"+id+"/", id)
+}
+
+// setImage stores the sizes of an upload under prefix and points the entry at them. Files of
+// an earlier upload in another format are removed once the entry no longer uses them.
+func (s *MenuImageService) setImage(ctx context.Context, table menuImageTable, prefix, id string, data []byte) (models.ImageVariants, error) {
+	sizes, ext, err := processMenuImage(data)
+	if err != nil {
+		return nil, err
+	}
+	// the version defeats caches, as each size keeps its key across uploads
+	version := "?v=" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	images := models.ImageVariants{}
+	var old models.ImageVariants
+	err = s.editImage(ctx, table, id, func(q sqlQueryer) error {
+		var err error
+		if old, err = currentImage(ctx, q, table, id); err != nil {
+			return err
+		}
+		for name, b := range sizes {
+			key := prefix + name + ext
+			if err := s.store.Put(ctx, key, "image/"+strings.TrimPrefix(ext, "."), b); err != nil {
+				return err
+			}
+			images[name] = s.store.URL(key) + version
+		}
+		return saveImage(ctx, q, table, id, images)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the entry already points at the new files, so a failed cleanup only leaves an orphan
+	for _, key := range imageKeys(prefix, old) {
+		if path.Ext(key) != ext {
+			if err := s.store.Delete(ctx, key); err != nil {
+				log.Printf("menu image: delete %s: %v", key, err)
+			}
+		}
+	}
+	return images, nil
+}
+
+func (s *MenuImageService) deleteImage(ctx context.Context, table menuImageTable, prefix, id string) error {
+	var old models.ImageVariants
+	err := s.editImage(ctx, table, id, func(q sqlQueryer) error {
+		var err error
+		if old, err = currentImage(ctx, q, table, id); err != nil {
+			return err
+		}
+		return saveImage(ctx, q, table, id, nil)
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range imageKeys(prefix, old) {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editImage runs edit on the live menu, where images change at once. An entry only a draft has
+// is edited in that draft instead.
+func (s *MenuImageService) editImage(ctx context.Context, table menuImageTable, id string, edit func(q sqlQueryer) error) error {
+	if err := edit(s.db); err != ErrMenuNotFound {
+		return err
+	}
+	_, err := (&MenuSQLService{db: s.db}).EditEntryDraft(ctx, MenuEntry(table), id, "", func(d *MenuSQLService) error {
+		return edit(d.conn())
+	})
+	return err
+}
+
+func currentImage(ctx context.Context, q sqlQueryer, table menuImageTable, id string) (models.ImageVariants, error) {
+	var images models.ImageVariants
+	err := q.QueryRowContext(ctx, "SELECT image_variants FROM "+string(table)+" WHERE id=$1 AND deleted_at IS NULL", id).Scan(&images)
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	return images, err
+}
+
+// saveImage points an entry at images, or at none when images is empty
+func saveImage(ctx context.Context, q sqlQueryer, table menuImageTable, id string, images models.ImageVariants) error {
+	res, err := q.ExecContext(ctx, "UPDATE "+string(table)+" SET image_url=$1, image_variants=$2, updated_at=NOW() WHERE id=$3 AND deleted_at IS NULL",
+		nullIfEmpty(images[models.ImageFull]), images, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// imageKeys returns the storage keys of an entry's uploaded sizes
+func imageKeys(prefix string, images models.ImageVariants) []string {
+	var keys []string
+	for name, url := range images {
+		url, _, _ = strings.Cut(url, "?")
+		keys = append(keys, prefix+name+path.Ext(url))
+	}
+	return keys
+}
+
+// processMenuImage checks an upload is a JPEG, PNG or GIF of sane dimensions and re-encodes it
+// in each stored size, which drops EXIF and other metadata. Images with transparency stay PNG;
+// everything else becomes JPEG. It returns the sizes by name and their file extension.
+func processMenuImage(data []byte) (map[string][]byte, string, error) {
+	if len(data) > MaxMenuImageBytes {
+		return nil, "", errors.New("image larger than " + strconv.Itoa(MaxMenuImageBytes>>20) + " MB")
+	}
+	switch ct := http.DetectContentType(data); ct {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, "", errors.New("unsupported image type " + ct + ": use JPEG, PNG or GIF")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.New("unreadable image: " + err.Error())
+	}
+	if cfg.Width*cfg.Height > maxMenuImagePixels {
+		return nil, "", errors.New("image dimensions too large")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.New("unreadable image: " + err.Error())
+	}
+
+	out := map[string][]byte{}
+	ext := ".jpg"
+	for _, size := range menuImageSizes {
+		// each size is scaled from the one before, the largest first
+		img := resizeImage(src, size.maxEdge)
+		if size.name == models.ImageFull && !img.Opaque() {
+			ext = ".png"
+		}
+		var buf bytes.Buffer
+		if ext == ".png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		out[size.name] = buf.Bytes()
+		src = img
+	}
+	return out, ext, nil
+}
+
+// fitWithin scales w×h down so its longest edge is at most maxEdge
+func fitWithin(w, h, maxEdge int) (int, int) {
+	if w <= maxEdge && h <= maxEdge {
+		return w, h
+	}
+	if w >= h {
+		return maxEdge, max(1, h*maxEdge/w)
+	}
+	return max(1, w*maxEdge/h), maxEdge
+}
+
+// resizeImage scales src to fit within maxEdge, averaging the source pixels under each
+// destination pixel
+func resizeImage(src image.Image, maxEdge int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := fitWithin(b.Dx(), b.Dy(), maxEdge)
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitWithin(t *testing.T) {
+	w, h := fitWithin(4000, 3000, 600)
+	assert.Equal(t, []int{600, 450}, []int{w, h})
+	w, h = fitWithin(1000, 3000, 600)
+	assert.Equal(t, []int{200, 600}, []int{w, h})
+	w, h = fitWithin(120, 80, 600)
+	assert.Equal(t, []int{120, 80}, []int{w, h})
+}
+
+func TestProcessMenuImage(t *testing.T) {
+	photo := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 2000; x++ {
+			photo.Set(x, y, color.RGBA{uint8(x), uint8(y), 80, 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, photo))
+
+	sizes, ext, err := processMenuImage(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, ".jpg", ext)
+	for name, edge := range map[string]int{models.ImageFull: 1600, models.ImageCard: 600, models.ImageThumbnail: 160} {
+		img, err := jpeg.Decode(bytes.NewReader(sizes[name]))
+		assert.NoError(t, err, name)
+		assert.Equal(t, image.Pt(edge, edge/2), img.Bounds().Size(), name)
+	}
+
+	logo := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	logo.Set(10, 10, color.NRGBA{255, 0, 0, 255})
+	buf.Reset()
+	assert.NoError(t, png.Encode(&buf, logo))
+	sizes, ext, err = processMenuImage(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, ".png", ext)
+	assert.Len(t, sizes, 3)
+
+	_, _, err = processMenuImage([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>"))
+	assert.Error(t, err)
+}
+
+func TestImageKeys(t *testing.T) {
+	keys := imageKeys("menu/items/i1/", models.ImageVariants{models.ImageCard: "/static/uploads/menu/items/i1/card.png?v=abc"})
+	assert.Equal(t, []string{"menu/items/i1/card.png"}, keys)
+}
+
+// encodeTestImage returns a small PNG, with a transparent corner when clear is set
+func encodeTestImage(t *testing.T, clear bool) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.NRGBA{200, 80, uint8(x), 255})
+		}
+	}
+	if clear {
+		img.Set(0, 0, color.NRGBA{})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestMenuImagesAndDrafts(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	dir := t.TempDir()
+	images := NewMenuImageService(db, storage.NewLocal(dir, "/static/uploads"))
+	exists := func(key string) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key)))
+		return err == nil
+	}
+
+	_, err := images.SetItemImage(ctx, "i1", encodeTestImage(t, false))
+	assert.NoError(t, err)
+	v := repriceDraft(t, s, "300")
+	// a new upload in another format replaces the files the draft still names
+	replaced, err := images.SetItemImage(ctx, "i1", encodeTestImage(t, true))
+	assert.NoError(t, err)
+	assert.False(t, exists("menu/items/i1/full.jpg"))
+
+	_, _, err = s.PublishMenuVersion(ctx, v.ID, nil)
+	assert.NoError(t, err)
+	it, err := s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Equal(t, replaced[models.ImageFull], it.ImageURL)
+	assert.Equal(t, replaced, it.ImageVariants)
+	assert.True(t, exists("menu/items/i1/full.png"))
+
+	// an item only a draft has gets its image in the draft
+	v, err = s.EditMenuDraft(ctx, "r1", "u1", func(d *MenuSQLService) error {
+		return d.CreateItem(ctx, &models.MenuItem{ID: "i2", CategoryID: "c1", Name: "Kitfo", Price: 300, Available: true})
+	})
+	assert.NoError(t, err)
+	uploaded, err := images.SetItemImage(ctx, "i2", encodeTestImage(t, false))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(strings.Split(uploaded[models.ImageFull], "?")[0], "/menu/items/i2/full.jpg"))
+	_, err = s.GetItem(ctx, "i2")
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+	_, _, err = s.PublishMenuVersion(ctx, v.ID, nil)
+	assert.NoError(t, err)
+	it, err = s.GetItem(ctx, "i2")
+	assert.NoError(t, err)
+	assert.Equal(t, uploaded, it.ImageVariants)
+
+	_, err = images.SetItemImage(ctx, "missing", encodeTestImage(t, false))
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+	assert.NoError(t, images.DeleteItemImage(ctx, "i2"))
+	assert.False(t, exists("menu/items/i2/full.jpg"))
+	it, err = s.GetItem(ctx, "i2")
+	assert.NoError(t, err)
+	assert.Empty(t, it.ImageURL)
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"restaurant-system/internal/models"
+)
+
+// CreateModifierGroup adds a group to an item, or nests it under one of the item's options
+// when ParentOptionID is set.
+func (s *MenuSQLService) CreateModifierGroup(ctx context.Context, g *models.ModifierGroup) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	if g.ParentOptionID != "" {
+		var itemID string
+		err := s.conn().QueryRowContext(ctx, "SELECT g.item_id FROM modifier_options o JOIN modifier_groups g ON g.id = o.group_id WHERE o.id=$1", g.ParentOptionID).Scan(&itemID)
+		if err == sql.ErrNoRows {
+			return ErrMenuNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if g.ItemID != "" && g.ItemID != itemID {
+			return errors.New("parent option belongs to another menu item")
+		}
+		g.ItemID = itemID
+	}
+	if _, err := s.GetItem(ctx, g.ItemID); err != nil {
+		return err
+	}
+	g.Options = []models.ModifierOption{}
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO modifier_groups (id, item_id, parent_option_id, name, min_select, max_select, free_quantity, position) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)",
+		g.ID, g.ItemID, nullIfEmpty(g.ParentOptionID), g.Name, g.MinSelect, g.MaxSelect, g.FreeQuantity, g.Position)
+	return err
+}
+
+// UpdateModifierGroup changes a group's name, rules and position; it stays where it is attached
+func (s *MenuSQLService) UpdateModifierGroup(ctx context.Context, g *models.ModifierGroup) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	var parent sql.NullString
+	err := s.conn().QueryRowContext(ctx, "UPDATE modifier_groups SET name=$1, min_select=$2, max_select=$3, free_quantity=$4, position=$5 WHERE id=$6 RETURNING item_id, parent_option_id",
+		g.Name, g.MinSelect, g.MaxSelect, g.FreeQuantity, g.Position, g.ID).Scan(&g.ItemID, &parent)
+	if err == sql.ErrNoRows {
+		return ErrMenuNotFound
+	}
+	g.ParentOptionID = parent.String
+	return err
+}
+
+// DeleteModifierGroup removes a group with its options and everything nested under them
+func (s *MenuSQLService) DeleteModifierGroup(ctx context.Context, id string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM modifier_groups WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+func (s *MenuSQLService) CreateModifierOption(ctx context.Context, o *models.ModifierOption) error {
+	if o.Name == "" {
+		return errors.New("name required")
+	}
+	if err := models.ValidateTags(o.Allergens, o.Dietary); err != nil {
+		return err
+	}
+	var exists bool
+	if err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM modifier_groups WHERE id=$1)", o.GroupID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrMenuNotFound
+	}
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO modifier_options (id, group_id, name, price_delta, is_default, allergens, dietary, position) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)",
+		o.ID, o.GroupID, o.Name, o.PriceDelta, o.IsDefault, o.Allergens, o.Dietary, o.Position)
+	return err
+}
+
+func (s *MenuSQLService) UpdateModifierOption(ctx context.Context, o *models.ModifierOption) error {
+	if o.Name == "" {
+		return errors.New("name required")
+	}
+	if err := models.ValidateTags(o.Allergens, o.Dietary); err != nil {
+		return err
+	}
+	err := s.conn().QueryRowContext(ctx, "UPDATE modifier_options SET name=$1, price_delta=$2, is_default=$3, allergens=$4, dietary=$5, position=$6 WHERE id=$7 RETURNING group_id",
+		o.Name, o.PriceDelta, o.IsDefault, o.Allergens, o.Dietary, o.Position, o.ID).Scan(&o.GroupID)
+	if err == sql.ErrNoRows {
+		return ErrMenuNotFound
+	}
+	return err
+}
+
+// DeleteModifierOption removes an option and the groups nested under it
+func (s *MenuSQLService) DeleteModifierOption(ctx context.Context, id string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM modifier_options WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// ListModifierGroups returns an item's modifier groups with their options and nested groups
+func (s *MenuSQLService) ListModifierGroups(ctx context.Context, itemID string) ([]models.ModifierGroup, error) {
+	if _, err := s.GetItem(ctx, itemID); err != nil {
+		return nil, err
+	}
+	trees, err := loadModifierGroups(ctx, s.conn(), "item_id = $1", itemID)
+	if err != nil {
+		return nil, err
+	}
+	if trees[itemID] == nil {
+		return []models.ModifierGroup{}, nil
+	}
+	return trees[itemID], nil
+}
+
+// loadModifierGroups loads the modifier groups of the items matched by where (a condition on
+// modifier_groups) and returns each item's top-level groups with everything nested under them,
+// in position order.
+func loadModifierGroups(ctx context.Context, q sqlQueryer, where string, args ...interface{}) (map[string][]models.ModifierGroup, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, item_id, COALESCE(parent_option_id, ''), name, min_select, max_select, free_quantity, position FROM modifier_groups WHERE "+where+
+		" ORDER BY position ASC, name ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	var groups []models.ModifierGroup
+	for rows.Next() {
+		var g models.ModifierGroup
+		if err := rows.Scan(&g.ID, &g.ItemID, &g.ParentOptionID, &g.Name, &g.MinSelect, &g.MaxSelect, &g.FreeQuantity, &g.Position); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return map[string][]models.ModifierGroup{}, nil
+	}
+
+	rows, err = q.QueryContext(ctx, "SELECT id, group_id, name, price_delta, is_default, COALESCE(allergens, '[]'), COALESCE(dietary, '[]'), position FROM modifier_options "+
+		"WHERE group_id IN (SELECT id FROM modifier_groups WHERE "+where+") ORDER BY position ASC, name ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	options := map[string][]models.ModifierOption{}
+	for rows.Next() {
+		var o models.ModifierOption
+		if err := rows.Scan(&o.ID, &o.GroupID, &o.Name, &o.PriceDelta, &o.IsDefault, &o.Allergens, &o.Dietary, &o.Position); err != nil {
+			return nil, err
+		}
+		options[o.GroupID] = append(options[o.GroupID], o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return assembleModifierGroups(groups, options), nil
+}
+
+// assembleModifierGroups nests groups under their parent options and returns each item's
+// top-level groups
+func assembleModifierGroups(groups []models.ModifierGroup, options map[string][]models.ModifierOption) map[string][]models.ModifierGroup {
+	top := map[string][]models.ModifierGroup{}
+	nested := map[string][]models.ModifierGroup{}
+	for _, g := range groups {
+		if g.ParentOptionID == "" {
+			top[g.ItemID] = append(top[g.ItemID], g)
+		} else {
+			nested[g.ParentOptionID] = append(nested[g.ParentOptionID], g)
+		}
+	}
+	var build func(in []models.ModifierGroup) []models.ModifierGroup
+	build = func(in []models.ModifierGroup) []models.ModifierGroup {
+		out := make([]models.ModifierGroup, len(in))
+		for i, g := range in {
+			g.Options = append([]models.ModifierOption{}, options[g.ID]...)
+			for j := range g.Options {
+				if sub := nested[g.Options[j].ID]; len(sub) > 0 {
+					g.Options[j].Groups = build(sub)
+				}
+			}
+			out[i] = g
+		}
+		return out
+	}
+	for itemID, gs := range top {
+		top[itemID] = build(gs)
+	}
+	return top
+}
+
+// resolveModifiers checks a line's choices against the item's groups and returns them as
+// snapshots, with the allergens they add. Groups left without a choice fall back to their
+// defaults; each group's min and max are then enforced and its first free_quantity choices
+// marked free. parentOptionID is the option the groups are nested under, empty at the top.
+func resolveModifiers(groups []models.ModifierGroup, sel []models.ModifierSelection, parentOptionID string) ([]models.OrderItemModifier, models.Allergens, error) {
+	type choice struct {
+		opt models.ModifierOption
+		sub []models.ModifierSelection
+	}
+	groupOf := map[string]int{}
+	for gi, g := range groups {
+		for _, o := range g.Options {
+			groupOf[o.ID] = gi
+		}
+	}
+	chosen := make([][]choice, len(groups))
+	seen := map[string]bool{}
+	for _, s := range sel {
+		gi, ok := groupOf[s.OptionID]
+		if !ok {
+			return nil, nil, errors.New("modifier option " + s.OptionID + " is not offered here")
+		}
+		if seen[s.OptionID] {
+			return nil, nil, errors.New("modifier option " + s.OptionID + " selected twice")
+		}
+		seen[s.OptionID] = true
+		for _, o := range groups[gi].Options {
+			if o.ID == s.OptionID {
+				chosen[gi] = append(chosen[gi], choice{opt: o, sub: s.Modifiers})
+			}
+		}
+	}
+
+	var out []models.OrderItemModifier
+	var allergens models.Allergens
+	for gi, g := range groups {
+		picks := chosen[gi]
+		if len(picks) == 0 {
+			for _, o := range g.Options {
+				if o.IsDefault {
+					picks = append(picks, choice{opt: o})
+				}
+			}
+		}
+		if len(picks) < g.MinSelect {
+			return nil, nil, errors.New(g.Name + ": choose at least " + strconv.Itoa(g.MinSelect))
+		}
+		if g.MaxSelect > 0 && len(picks) > g.MaxSelect {
+			return nil, nil, errors.New(g.Name + ": choose at most " + strconv.Itoa(g.MaxSelect))
+		}
+		for i, p := range picks {
+			out = append(out, models.OrderItemModifier{
+				GroupID: g.ID, GroupName: g.Name, OptionID: p.opt.ID, Name: p.opt.Name,
+				PriceDelta: models.MoneyFromFloat(p.opt.PriceDelta), Free: i < g.FreeQuantity, ParentOptionID: parentOptionID,
+			})
+			allergens = allergens.Union(p.opt.Allergens)
+			sub, subAllergens, err := resolveModifiers(p.opt.Groups, p.sub, p.opt.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, sub...)
+			allergens = allergens.Union(subAllergens)
+		}
+	}
+	return out, allergens, nil
+}
+
+// modifierSelections rebuilds the choices behind a line's modifier snapshots, for reorders
+func modifierSelections(mods []models.OrderItemModifier) []models.ModifierSelection {
+	var build func(parent string) []models.ModifierSelection
+	build = func(parent string) []models.ModifierSelection {
+		var out []models.ModifierSelection
+		for _, m := range mods {
+			if m.ParentOptionID == parent {
+				out = append(out, models.ModifierSelection{OptionID: m.OptionID, Modifiers: build(m.OptionID)})
+			}
+		}
+		return out
+	}
+	return build("")
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func burgerModifiers() []models.ModifierGroup {
+	return []models.ModifierGroup{
+		{ID: "sauce", Name: "Sauce", MinSelect: 1, MaxSelect: 1, Options: []models.ModifierOption{
+			{ID: "bbq", Name: "BBQ", IsDefault: true},
+			{ID: "chili", Name: "Chili", PriceDelta: 0.5, Allergens: models.Allergens{models.AllergenMustard}},
+		}},
+		{ID: "toppings", Name: "Toppings", MaxSelect: 3, FreeQuantity: 2, Options: []models.ModifierOption{
+			{ID: "cheese", Name: "Cheese", PriceDelta: 1, Allergens: models.Allergens{models.AllergenMilk}},
+			{ID: "bacon", Name: "Bacon", PriceDelta: 2},
+			{ID: "egg", Name: "Egg", PriceDelta: 1.5, Allergens: models.Allergens{models.AllergenEggs}, Groups: []models.ModifierGroup{
+				{ID: "egg-style", Name: "Egg style", MinSelect: 1, MaxSelect: 1, Options: []models.ModifierOption{
+					{ID: "fried", Name: "Fried", IsDefault: true},
+					{ID: "poached", Name: "Poached"},
+				}},
+			}},
+			{ID: "onion", Name: "Onion", PriceDelta: 0.5},
+		}},
+	}
+}
+
+func TestResolveModifiersDefaultsAndFreeQuantity(t *testing.T) {
+	mods, allergens, err := resolveModifiers(burgerModifiers(), []models.ModifierSelection{
+		{OptionID: "bacon"}, {OptionID: "cheese"}, {OptionID: "egg", Modifiers: []models.ModifierSelection{{OptionID: "poached"}}},
+	}, "")
+	assert.NoError(t, err)
+
+	var names []string
+	var charge models.Money
+	for _, m := range mods {
+		names = append(names, m.Name)
+		charge += m.Charge()
+	}
+	// the sauce falls back to its default; the first two toppings are free
+	assert.Equal(t, []string{"BBQ", "Bacon", "Cheese", "Egg", "Poached"}, names)
+	assert.Equal(t, models.Money(150), charge)
+	assert.Equal(t, "egg", mods[4].ParentOptionID)
+	assert.Equal(t, models.Allergens{models.AllergenEggs, models.AllergenMilk}, allergens)
+
+	// nested defaults apply too
+	mods, _, err = resolveModifiers(burgerModifiers(), []models.ModifierSelection{{OptionID: "chili"}, {OptionID: "egg"}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Fried", mods[2].Name)
+}
+
+func TestResolveModifiersRules(t *testing.T) {
+	_, _, err := resolveModifiers(burgerModifiers(), []models.ModifierSelection{{OptionID: "bbq"}, {OptionID: "chili"}}, "")
+	assert.EqualError(t, err, "Sauce: choose at most 1")
+
+	_, _, err = resolveModifiers(burgerModifiers(), []models.ModifierSelection{
+		{OptionID: "cheese"}, {OptionID: "bacon"}, {OptionID: "egg"}, {OptionID: "onion"},
+	}, "")
+	assert.EqualError(t, err, "Toppings: choose at most 3")
+
+	_, _, err = resolveModifiers(burgerModifiers(), []models.ModifierSelection{{OptionID: "poached"}}, "")
+	assert.Error(t, err, "nested options cannot be chosen at the top level")
+
+	_, _, err = resolveModifiers(burgerModifiers(), []models.ModifierSelection{{OptionID: "bacon"}, {OptionID: "bacon"}}, "")
+	assert.Error(t, err)
+
+	groups := burgerModifiers()
+	groups[0].Options[0].IsDefault = false
+	_, _, err = resolveModifiers(groups, nil, "")
+	assert.EqualError(t, err, "Sauce: choose at least 1")
+}
+
+func TestModifierSelectionsRoundTrip(t *testing.T) {
+	sel := []models.ModifierSelection{{OptionID: "chili"}, {OptionID: "egg", Modifiers: []models.ModifierSelection{{OptionID: "poached"}}}}
+	mods, _, err := resolveModifiers(burgerModifiers(), sel, "")
+	assert.NoError(t, err)
+	assert.Equal(t, sel, modifierSelections(mods))
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+const menuScheduleColumns = "id, restaurant_id, name, COALESCE(windows, '[]'), COALESCE(start_date, ''), COALESCE(end_date, ''), COALESCE(holidays, ''), created_at, updated_at"
+
+func scanMenuSchedule(row rowScanner) (*models.MenuSchedule, error) {
+	var ms models.MenuSchedule
+	if err := row.Scan(&ms.ID, &ms.RestaurantID, &ms.Name, &ms.Windows, &ms.StartDate, &ms.EndDate, &ms.Holidays, &ms.CreatedAt, &ms.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &ms, nil
+}
+
+func (s *MenuSQLService) CreateSchedule(ctx context.Context, ms *models.MenuSchedule) error {
+	if err := ms.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	ms.CreatedAt, ms.UpdatedAt = now, now
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO menu_schedules (id, restaurant_id, name, windows, start_date, end_date, holidays, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$8)",
+		ms.ID, ms.RestaurantID, ms.Name, ms.Windows, nullIfEmpty(ms.StartDate), nullIfEmpty(ms.EndDate), string(ms.Holidays), now)
+	return err
+}
+
+func (s *MenuSQLService) GetSchedule(ctx context.Context, id string) (*models.MenuSchedule, error) {
+	ms, err := scanMenuSchedule(s.conn().QueryRowContext(ctx, "SELECT "+menuScheduleColumns+" FROM menu_schedules WHERE id=$1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	return ms, err
+}
+
+func (s *MenuSQLService) ListSchedules(ctx context.Context, restaurantID string) ([]models.MenuSchedule, error) {
+	return listSchedules(ctx, s.conn(), restaurantID)
+}
+
+func listSchedules(ctx context.Context, q sqlQueryer, restaurantID string) ([]models.MenuSchedule, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+menuScheduleColumns+" FROM menu_schedules WHERE restaurant_id=$1 ORDER BY name ASC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.MenuSchedule{}
+	for rows.Next() {
+		ms, err := scanMenuSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *ms)
+	}
+	return out, rows.Err()
+}
+
+// UpdateSchedule replaces a schedule's name and rules; it stays with its restaurant
+func (s *MenuSQLService) UpdateSchedule(ctx context.Context, ms *models.MenuSchedule) error {
+	cur, err := s.GetSchedule(ctx, ms.ID)
+	if err != nil {
+		return err
+	}
+	ms.RestaurantID, ms.CreatedAt, ms.UpdatedAt = cur.RestaurantID, cur.CreatedAt, time.Now()
+	if err := ms.Validate(); err != nil {
+		return err
+	}
+	_, err = s.conn().ExecContext(ctx, "UPDATE menu_schedules SET name=$1, windows=$2, start_date=$3, end_date=$4, holidays=$5, updated_at=$6 WHERE id=$7",
+		ms.Name, ms.Windows, nullIfEmpty(ms.StartDate), nullIfEmpty(ms.EndDate), string(ms.Holidays), ms.UpdatedAt, ms.ID)
+	return err
+}
+
+// DeleteSchedule removes a schedule nothing uses; clearing it silently would put its
+// categories and items on the menu around the clock.
+func (s *MenuSQLService) DeleteSchedule(ctx context.Context, id string) error {
+	var used int
+	if err := s.conn().QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM menu_items WHERE schedule_id=$1 AND deleted_at IS NULL) + "+
+		"(SELECT COUNT(*) FROM menu_categories WHERE schedule_id=$1 AND deleted_at IS NULL)", id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return errors.New("schedule is still used by categories or items")
+	}
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM menu_schedules WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+func (s *MenuSQLService) AddHoliday(ctx context.Context, h *models.MenuHoliday) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO restaurant_holidays (restaurant_id, date, name) VALUES ($1,$2,$3) ON CONFLICT (restaurant_id, date) DO UPDATE SET name=EXCLUDED.name",
+		h.RestaurantID, h.Date, h.Name)
+	return err
+}
+
+func (s *MenuSQLService) ListHolidays(ctx context.Context, restaurantID string) ([]models.MenuHoliday, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT restaurant_id, date, COALESCE(name, '') FROM restaurant_holidays WHERE restaurant_id=$1 ORDER BY date ASC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.MenuHoliday{}
+	for rows.Next() {
+		var h models.MenuHoliday
+		if err := rows.Scan(&h.RestaurantID, &h.Date, &h.Name); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (s *MenuSQLService) DeleteHoliday(ctx context.Context, restaurantID, date string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM restaurant_holidays WHERE restaurant_id=$1 AND date=$2", restaurantID, date)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// checkSchedule checks that a category or item's schedule belongs to its restaurant
+func checkSchedule(ctx context.Context, q sqlQueryer, scheduleID, restaurantID string) error {
+	if scheduleID == "" {
+		return nil
+	}
+	if restaurantID == "" {
+		return errors.New("shared items cannot have a schedule")
+	}
+	var owner string
+	err := q.QueryRowContext(ctx, "SELECT restaurant_id FROM menu_schedules WHERE id=$1", scheduleID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return errors.New("schedule " + scheduleID + " not found")
+	}
+	if err != nil {
+		return err
+	}
+	if owner != restaurantID {
+		return errors.New("schedule belongs to another restaurant")
+	}
+	return nil
+}
+
+// menuClock answers whether a restaurant's schedules are open at one moment, taken in the
+// restaurant's time zone
+type menuClock struct {
+	local     time.Time
+	holiday   bool
+	schedules map[string]models.MenuSchedule
+}
+
+func loadMenuClock(ctx context.Context, q sqlQueryer, restaurantID string, at time.Time) (*menuClock, error) {
+	loc, err := restaurantLocation(ctx, q, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	c := &menuClock{local: at.In(loc), schedules: map[string]models.MenuSchedule{}}
+	if restaurantID == "" {
+		return c, nil
+	}
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM restaurant_holidays WHERE restaurant_id=$1 AND date=$2)",
+		restaurantID, c.local.Format("2006-01-02")).Scan(&c.holiday); err != nil {
+		return nil, err
+	}
+	list, err := listSchedules(ctx, q, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ms := range list {
+		c.schedules[ms.ID] = ms
+	}
+	return c, nil
+}
+
+// open reports whether every given schedule is open; empty IDs are unscheduled and a
+// schedule the restaurant does not have is treated as closed
+func (c *menuClock) open(scheduleIDs ...string) bool {
+	for _, id := range scheduleIDs {
+		if id == "" {
+			continue
+		}
+		ms, ok := c.schedules[id]
+		if !ok || !ms.OpenAt(c.local, c.holiday) {
+			return false
+		}
+	}
+	return true
+}
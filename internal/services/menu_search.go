@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"restaurant-system/internal/models"
+)
+
+const (
+	defaultMenuSearchLimit = 20
+	maxMenuSearchLimit     = 100
+)
+
+// MenuSearch is a guest's search of a branch's menu. Query is matched, forgiving typos, against
+// item names, descriptions and categories in every language they are written or translated in;
+// an empty query browses the whole menu. The other fields filter the matches, and each facet
+// counts them as if its own filter were unset. CategoryIDs holds category ids, or names for
+// items without a category row. Available nil keeps sold-out and unscheduled items, marked
+// unavailable. Tags' dietary tags filter like a facet and its allergens flag or hide as on
+// the menu. Limit defaults to 20 and is capped at 100.
+type MenuSearch struct {
+	Query       string
+	CategoryIDs []string
+	MinPrice    *float64
+	MaxPrice    *float64
+	Available   *bool
+	Tags        MenuTagFilter
+	Limit       int
+}
+
+// MenuSearchHit is a matching item, best first. Rating and Reviews come from reviews and
+// Favorites from the guests who saved the item; they lift it in the ranking.
+type MenuSearchHit struct {
+	Item      models.MenuItem `json:"item"`
+	Score     float64         `json:"score"`
+	Rating    float64         `json:"rating,omitempty"`
+	Reviews   int             `json:"reviews,omitempty"`
+	Favorites int             `json:"favorites,omitempty"`
+}
+
+// FacetCount is how many matches have a facet value; Label is the value as guests read it
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// PriceFacet is the price range of the matches, split into equal buckets. The last bucket
+// includes its upper bound.
+type PriceFacet struct {
+	Min     float64       `json:"min"`
+	Max     float64       `json:"max"`
+	Buckets []PriceBucket `json:"buckets"`
+}
+
+type PriceBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+type MenuSearchFacets struct {
+	Categories   []FacetCount `json:"categories"`
+	Dietary      []FacetCount `json:"dietary"`
+	Availability []FacetCount `json:"availability"`
+	Price        PriceFacet   `json:"price"`
+}
+
+type MenuSearchResult struct {
+	RestaurantID string           `json:"restaurant_id"`
+	Currency     string           `json:"currency"`
+	Query        string           `json:"query"`
+	Total        int              `json:"total"`
+	Hits         []MenuSearchHit  `json:"hits"`
+	Facets       MenuSearchFacets `json:"facets"`
+}
+
+// SearchMenu searches the branch's menu as one of its tables sees it now, showing text in the
+// first of locales it is translated to.
+func (s *MenuSQLService) SearchMenu(ctx context.Context, restaurantID, tableID string, locales []string, search MenuSearch) (*MenuSearchResult, error) {
+	currency, err := s.restaurantCurrency(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTable(ctx, restaurantID, tableID); err != nil {
+		return nil, err
+	}
+	clock, err := loadMenuClock(ctx, s.conn(), restaurantID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	tr, err := loadMenuTranslator(ctx, s.conn(), restaurantID, locales)
+	if err != nil {
+		return nil, err
+	}
+	items, err := branchItems(ctx, s.conn(), restaurantID, clock, tr)
+	if err != nil {
+		return nil, err
+	}
+	texts, err := s.searchTexts(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	pop, err := s.itemPopularity(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := searchWords(search.Query)
+	var hits []MenuSearchHit
+	for _, it := range items {
+		p := pop[it.ID]
+		hit := MenuSearchHit{Item: it, Rating: p.rating, Reviews: p.reviews, Favorites: p.favorites}
+		boost := popularity(p.favorites, p.reviews, p.rating)
+		if len(query) == 0 {
+			hit.Score = boost
+		} else {
+			fields := append([]searchField{{it.Name, 3}, {it.Category, 1.5}, {it.Description, 1}}, texts[it.ID]...)
+			fields = append(fields, texts[it.CategoryID]...)
+			text := matchScore(query, fields)
+			if text == 0 {
+				continue
+			}
+			hit.Score = text * (1 + boost)
+		}
+		hit.Score = math.Round(hit.Score*1000) / 1000
+		hit.Item.AllergenWarnings = it.Allergens.Intersect(search.Tags.Allergens)
+		if search.Tags.HideAllergens && len(hit.Item.AllergenWarnings) > 0 {
+			continue
+		}
+		hits = append(hits, hit)
+	}
+
+	res := &MenuSearchResult{RestaurantID: restaurantID, Currency: currency, Query: search.Query, Hits: []MenuSearchHit{}, Facets: search.facets(hits)}
+	for _, h := range hits {
+		if search.keep(h.Item, "") {
+			res.Hits = append(res.Hits, h)
+		}
+	}
+	sort.SliceStable(res.Hits, func(i, j int) bool {
+		if res.Hits[i].Score != res.Hits[j].Score {
+			return res.Hits[i].Score > res.Hits[j].Score
+		}
+		return res.Hits[i].Item.Name < res.Hits[j].Item.Name
+	})
+	res.Total = len(res.Hits)
+	limit := search.Limit
+	if limit <= 0 {
+		limit = defaultMenuSearchLimit
+	}
+	if limit > maxMenuSearchLimit {
+		limit = maxMenuSearchLimit
+	}
+	if len(res.Hits) > limit {
+		res.Hits = res.Hits[:limit]
+	}
+	page := make([]*models.MenuItem, len(res.Hits))
+	for i := range res.Hits {
+		page[i] = &res.Hits[i].Item
+	}
+	return res, fillItemOptions(ctx, s.conn(), restaurantID, tr, search.Tags.Dietary, page)
+}
+
+// categoryKey is the value an item's category is faceted and filtered by
+func categoryKey(it models.MenuItem) string {
+	if it.CategoryID != "" {
+		return it.CategoryID
+	}
+	return it.Category
+}
+
+// keep reports whether an item passes every filter but the one named by except
+func (m MenuSearch) keep(it models.MenuItem, except string) bool {
+	if except != "category" && len(m.CategoryIDs) > 0 {
+		found := false
+		for _, id := range m.CategoryIDs {
+			found = found || id == categoryKey(it)
+		}
+		if !found {
+			return false
+		}
+	}
+	if except != "price" && ((m.MinPrice != nil && it.Price < *m.MinPrice) || (m.MaxPrice != nil && it.Price > *m.MaxPrice)) {
+		return false
+	}
+	if except != "dietary" && !it.Dietary.HasAll(m.Tags.Dietary) {
+		return false
+	}
+	if except != "available" && m.Available != nil && it.Available != *m.Available {
+		return false
+	}
+	return true
+}
+
+// facets counts the hits for each facet, leaving that facet's own filter out
+func (m MenuSearch) facets(hits []MenuSearchHit) MenuSearchFacets {
+	categories := map[string]*FacetCount{}
+	dietary := map[string]int{}
+	available, unavailable := 0, 0
+	var prices []float64
+	for _, h := range hits {
+		it := h.Item
+		if m.keep(it, "category") {
+			key := categoryKey(it)
+			if categories[key] == nil {
+				categories[key] = &FacetCount{Value: key, Label: it.Category}
+			}
+			categories[key].Count++
+		}
+		if m.keep(it, "dietary") {
+			for _, d := range it.Dietary {
+				dietary[string(d)]++
+			}
+		}
+		if m.keep(it, "available") {
+			if it.Available {
+				available++
+			} else {
+				unavailable++
+			}
+		}
+		if m.keep(it, "price") {
+			prices = append(prices, it.Price)
+		}
+	}
+	f := MenuSearchFacets{Categories: []FacetCount{}, Dietary: []FacetCount{}, Price: priceFacet(prices, 4),
+		Availability: []FacetCount{{Value: "available", Count: available}, {Value: "unavailable", Count: unavailable}}}
+	for _, c := range categories {
+		f.Categories = append(f.Categories, *c)
+	}
+	sort.Slice(f.Categories, func(i, j int) bool { return f.Categories[i].Label < f.Categories[j].Label })
+	for d, n := range dietary {
+		f.Dietary = append(f.Dietary, FacetCount{Value: d, Count: n})
+	}
+	sort.Slice(f.Dietary, func(i, j int) bool {
+		if f.Dietary[i].Count != f.Dietary[j].Count {
+			return f.Dietary[i].Count > f.Dietary[j].Count
+		}
+		return f.Dietary[i].Value < f.Dietary[j].Value
+	})
+	return f
+}
+
+// priceFacet splits the price range into n buckets of a whole-unit width
+func priceFacet(prices []float64, n int) PriceFacet {
+	f := PriceFacet{Buckets: []PriceBucket{}}
+	if len(prices) == 0 {
+		return f
+	}
+	f.Min, f.Max = prices[0], prices[0]
+	for _, p := range prices {
+		f.Min, f.Max = math.Min(f.Min, p), math.Max(f.Max, p)
+	}
+	from := math.Floor(f.Min)
+	width := math.Max(1, math.Ceil((f.Max-from)/float64(n)))
+	for i := 0; i < n && from+float64(i)*width <= f.Max; i++ {
+		f.Buckets = append(f.Buckets, PriceBucket{From: from + float64(i)*width, To: from + float64(i+1)*width})
+	}
+	last := len(f.Buckets) - 1
+	for _, p := range prices {
+		i := min(int((p-from)/width), last)
+		f.Buckets[i].Count++
+	}
+	return f
+}
+
+// searchField is text an item can be found by, with how much a match in it counts
+type searchField struct {
+	text   string
+	weight float64
+}
+
+// searchTexts returns the translations items are also found by, keyed by item or category id
+func (s *MenuSQLService) searchTexts(ctx context.Context, restaurantID string) (map[string][]searchField, error) {
+	const items = "SELECT id FROM menu_items WHERE deleted_at IS NULL AND (restaurant_id = $1 OR restaurant_id IS NULL)"
+	rows, err := s.conn().QueryContext(ctx, "SELECT entity_type, entity_id, field, value FROM menu_translations WHERE "+
+		"(entity_type = 'item' AND entity_id IN ("+items+")) OR "+
+		"(entity_type = 'category' AND entity_id IN (SELECT category_id FROM menu_items WHERE id IN ("+items+")))", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]searchField{}
+	for rows.Next() {
+		var entityType models.TranslatableEntity
+		var id, field, value string
+		if err := rows.Scan(&entityType, &id, &field, &value); err != nil {
+			return nil, err
+		}
+		// weighted like the text they translate
+		weight := 1.0
+		if entityType == models.TranslateCategory {
+			weight = 1.5
+		} else if field == "name" {
+			weight = 3
+		}
+		out[id] = append(out[id], searchField{value, weight})
+	}
+	return out, rows.Err()
+}
+
+type itemPopularity struct {
+	favorites int
+	reviews   int
+	rating    float64
+}
+
+// itemPopularity returns the favorites and reviews of the branch's items
+func (s *MenuSQLService) itemPopularity(ctx context.Context, restaurantID string) (map[string]itemPopularity, error) {
+	const items = "SELECT id FROM menu_items WHERE deleted_at IS NULL AND (restaurant_id = $1 OR restaurant_id IS NULL)"
+	out := map[string]itemPopularity{}
+	rows, err := s.conn().QueryContext(ctx, "SELECT menu_item_id, COUNT(*), AVG(rating) FROM reviews WHERE menu_item_id IN ("+items+") GROUP BY 1", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var p itemPopularity
+		if err := rows.Scan(&id, &p.reviews, &p.rating); err != nil {
+			return nil, err
+		}
+		p.rating = math.Round(p.rating*10) / 10
+		out[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = s.conn().QueryContext(ctx, "SELECT menu_item_id, COUNT(*) FROM favorites WHERE menu_item_id IN ("+items+") GROUP BY 1", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		p := out[id]
+		p.favorites = n
+		out[id] = p
+	}
+	return out, rows.Err()
+}
+
+// popularity is an item's ranking boost: favorites count with diminishing returns, and an
+// average rating above or below three stars nudges up or down, trusted more as reviews add up
+func popularity(favorites, reviews int, rating float64) float64 {
+	b := 0.1 * math.Log1p(float64(favorites))
+	if reviews > 0 {
+		b += 0.1 * (rating - 3) / 2 * math.Min(float64(reviews), 10) / 10
+	}
+	return b
+}
+
+// searchWords lowercases text and splits it into words
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+}
+
+// matchScore scores how well every query word matches the fields, 0 when one does not match.
+// Each word counts its best match, weighted by the field it is in.
+func matchScore(query []string, fields []searchField) float64 {
+	var total float64
+	for _, q := range query {
+		best := 0.0
+		for _, f := range fields {
+			for _, w := range searchWords(f.text) {
+				best = math.Max(best, wordMatch(q, w)*f.weight)
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(query))
+}
+
+// wordMatch scores a query word against a word of the text: exact, a prefix of it as the guest
+// is still typing, or within the typos allowed for the query word's length
+func wordMatch(q, w string) float64 {
+	if q == w {
+		return 1
+	}
+	if len([]rune(q)) >= 2 && strings.HasPrefix(w, q) {
+		return 0.8
+	}
+	budget := typoBudget(q)
+	if budget == 0 {
+		return 0
+	}
+	if d := editDistance(q, w, budget); d <= budget {
+		return 0.8 - 0.2*float64(d)
+	}
+	// a typo in a word still being typed
+	if wr := []rune(w); len(wr) > len([]rune(q)) {
+		if d := editDistance(q, string(wr[:len([]rune(q))]), budget); d <= budget {
+			return 0.5 - 0.2*float64(d)
+		}
+	}
+	return 0
+}
+
+// typoBudget is how many typos a query word may contain: none up to 3 letters, one up to 6,
+// two beyond
+func typoBudget(q string) int {
+	switch n := len([]rune(q)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+	return 2
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of neighbouring
+// letters between a and b, giving up with limit+1 once it exceeds limit
+func editDistance(a, b string, limit int) int {
+	ar, br := []rune(a), []rune(b)
+	if d := len(ar) - len(br); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 1, editDistance("chiken", "chicken", 2))
+	assert.Equal(t, 1, editDistance("tibs", "tisb", 2)) // swapped letters
+	assert.Equal(t, 2, editDistance("burgr", "burgers", 2))
+	assert.Equal(t, 3, editDistance("pizza", "pasta", 2)) // gave up past the limit
+	assert.Equal(t, 1, editDistance("ሽሮ", "ሽሮው", 1))
+}
+
+func TestWordMatch(t *testing.T) {
+	assert.Equal(t, 1.0, wordMatch("tibs", "tibs"))
+	assert.Equal(t, 0.8, wordMatch("bur", "burger"))
+	assert.InDelta(t, 0.6, wordMatch("chiken", "chicken"), 1e-9)
+	assert.InDelta(t, 0.3, wordMatch("chikc", "chicken"), 1e-9) // typo while still typing
+	assert.Zero(t, wordMatch("tea", "pea"))                     // short words must be exact
+}
+
+func TestMatchScore(t *testing.T) {
+	fields := []searchField{{"Spicy Chicken Burger", 3}, {"Burgers", 1.5}, {"With fries", 1}, {"በርገር", 3}}
+	assert.InDelta(t, (0.6*3+1*3)/2, matchScore(searchWords("chiken BURGER"), fields), 1e-9)
+	assert.Equal(t, 1.0, matchScore(searchWords("fries"), fields))
+	assert.Equal(t, 3.0, matchScore(searchWords("በርገር"), fields))
+	assert.Zero(t, matchScore(searchWords("burger salad"), fields)) // every word must match
+}
+
+func TestPopularity(t *testing.T) {
+	assert.Zero(t, popularity(0, 0, 0))
+	assert.Greater(t, popularity(10, 0, 0), popularity(1, 0, 0))
+	assert.Greater(t, popularity(0, 10, 5), popularity(0, 1, 5)) // more reviews, more trust
+	assert.Less(t, popularity(0, 10, 1), 0.0)
+}
+
+func TestMenuSearchFacets(t *testing.T) {
+	hits := []MenuSearchHit{
+		{Item: models.MenuItem{ID: "a", CategoryID: "c1", Category: "Mains", Price: 120, Available: true, Dietary: models.DietaryTags{"vegan"}}},
+		{Item: models.MenuItem{ID: "b", CategoryID: "c1", Category: "Mains", Price: 300, Available: false}},
+		{Item: models.MenuItem{ID: "c", CategoryID: "c2", Category: "Drinks", Price: 40, Available: true, Dietary: models.DietaryTags{"vegan"}}},
+	}
+	yes := true
+	s := MenuSearch{CategoryIDs: []string{"c1"}, Available: &yes}
+	f := s.facets(hits)
+	// categories ignore the category filter but not availability
+	assert.Equal(t, []FacetCount{{Value: "c2", Label: "Drinks", Count: 1}, {Value: "c1", Label: "Mains", Count: 1}}, f.Categories)
+	assert.Equal(t, []FacetCount{{Value: "available", Count: 1}, {Value: "unavailable", Count: 1}}, f.Availability)
+	assert.Equal(t, []FacetCount{{Value: "vegan", Count: 1}}, f.Dietary)
+	assert.True(t, s.keep(hits[0].Item, ""))
+	assert.False(t, s.keep(hits[1].Item, ""))
+}
+
+func TestPriceFacet(t *testing.T) {
+	f := priceFacet([]float64{40, 120, 300, 299.5}, 4)
+	assert.Equal(t, 40.0, f.Min)
+	assert.Equal(t, 300.0, f.Max)
+	assert.Equal(t, []PriceBucket{{From: 40, To: 105, Count: 1}, {From: 105, To: 170, Count: 1}, {From: 170, To: 235}, {From: 235, To: 300, Count: 2}}, f.Buckets)
+	assert.Equal(t, []PriceBucket{{From: 9, To: 10, Count: 2}}, priceFacet([]float64{9.5, 9.5}, 4).Buckets)
+	assert.Empty(t, priceFacet(nil, 4).Buckets)
+}
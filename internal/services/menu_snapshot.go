@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"restaurant-system/internal/models"
+)
+
+// menuTable is a table holding part of a restaurant's own menu. Scope picks the restaurant's
+// rows, with the restaurant as $1.
+type menuTable struct {
+	name  string
+	key   []string
+	scope string
+	// softDelete tables keep deleted rows, so rows a snapshot lacks are marked deleted
+	softDelete bool
+	// late columns point at rows of later tables and are written once every table is
+	late []string
+	// live columns are changed on the live menu right away, such as availability and images,
+	// so rows already live keep them; the snapshot's values are for rows it brings in
+	live []string
+}
+
+const (
+	menuItemScope      = "item_id IN (SELECT id FROM menu_items WHERE restaurant_id=$1)"
+	modifierGroupScope = "group_id IN (SELECT id FROM modifier_groups WHERE " + menuItemScope + ")"
+	comboScope         = "combo_id IN (SELECT id FROM menu_combos WHERE restaurant_id=$1)"
+)
+
+// menuTables are the tables a menu version snapshots, parents first. Shared items belong to no
+// restaurant and are left out, but a branch's prices for them are kept.
+var menuTables = []menuTable{
+	{name: "menu_schedules", key: []string{"id"}, scope: "restaurant_id=$1"},
+	{name: "restaurant_holidays", key: []string{"restaurant_id", "date"}, scope: "restaurant_id=$1"},
+	{name: "menu_categories", key: []string{"id"}, scope: "restaurant_id=$1", softDelete: true, live: []string{"image_url", "image_variants"}},
+	{name: "menu_items", key: []string{"id"}, scope: "restaurant_id=$1", softDelete: true, live: []string{"available", "image_url", "image_variants"}},
+	{name: "menu_variants", key: []string{"id"}, scope: menuItemScope},
+	{name: "menu_addons", key: []string{"id"}, scope: menuItemScope},
+	// groups nested under an option point at an option of a later row
+	{name: "modifier_groups", key: []string{"id"}, scope: menuItemScope, late: []string{"parent_option_id"}},
+	{name: "modifier_options", key: []string{"id"}, scope: modifierGroupScope},
+	{name: "menu_combos", key: []string{"id"}, scope: "restaurant_id=$1", softDelete: true},
+	{name: "combo_slots", key: []string{"id"}, scope: comboScope},
+	{name: "combo_slot_items", key: []string{"slot_id", "menu_item_id"}, scope: "slot_id IN (SELECT id FROM combo_slots WHERE " + comboScope + ")"},
+	{name: "menu_item_overrides", key: []string{"restaurant_id", "menu_item_id"}, scope: "restaurant_id=$1", live: []string{"available"}},
+	{name: "menu_translations", key: []string{"entity_type", "entity_id", "field", "locale"}, scope: "" +
+		"(entity_type='category' AND entity_id IN (SELECT id FROM menu_categories WHERE restaurant_id=$1)) OR " +
+		"(entity_type='item' AND entity_id IN (SELECT id FROM menu_items WHERE restaurant_id=$1)) OR " +
+		"(entity_type='variant' AND entity_id IN (SELECT id FROM menu_variants WHERE " + menuItemScope + ")) OR " +
+		"(entity_type='addon' AND entity_id IN (SELECT id FROM menu_addons WHERE " + menuItemScope + ")) OR " +
+		"(entity_type='modifier_group' AND entity_id IN (SELECT id FROM modifier_groups WHERE " + menuItemScope + ")) OR " +
+		"(entity_type='modifier_option' AND entity_id IN (SELECT id FROM modifier_options WHERE " + modifierGroupScope + "))"},
+}
+
+// snapshotMenu reads every row of a restaurant's menu tables, deleted ones included
+func snapshotMenu(ctx context.Context, q sqlQueryer, restaurantID string) (models.MenuSnapshot, error) {
+	snap := models.MenuSnapshot{}
+	for _, t := range menuTables {
+		rows, err := q.QueryContext(ctx, "SELECT * FROM "+t.name+" WHERE "+t.scope+" ORDER BY "+strings.Join(t.key, ", "), restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		cols, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list := []map[string]interface{}{}
+		for rows.Next() {
+			vals := make([]interface{}, len(cols))
+			dest := make([]interface{}, len(cols))
+			for i := range vals {
+				dest[i] = &vals[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			row := make(map[string]interface{}, len(cols))
+			for i, c := range cols {
+				// numeric columns come back as text
+				if b, ok := vals[i].([]byte); ok {
+					vals[i] = string(b)
+				}
+				row[c] = vals[i]
+			}
+			list = append(list, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		snap[t.name] = list
+	}
+	return snap, nil
+}
+
+var columnName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// applyMenuSnapshot makes a restaurant's menu tables hold exactly the snapshot's rows, except
+// for the live columns of rows already on the live menu. Rows are written parents first; rows
+// the snapshot lacks are then removed children first, or marked deleted in tables that keep
+// deleted rows. Station routes follow renamed categories.
+func applyMenuSnapshot(ctx context.Context, q sqlQueryer, restaurantID string, snap models.MenuSnapshot) error {
+	names, err := categoryNames(ctx, q, restaurantID)
+	if err != nil {
+		return err
+	}
+	for _, t := range menuTables {
+		for _, row := range snap[t.name] {
+			if err := t.upsert(ctx, q, row); err != nil {
+				return err
+			}
+		}
+	}
+	for _, t := range menuTables {
+		for _, col := range t.late {
+			for _, row := range snap[t.name] {
+				if row[col] == nil {
+					continue
+				}
+				where, args := t.match(row, 2)
+				if _, err := q.ExecContext(ctx, "UPDATE "+t.name+" SET "+col+"=$1 WHERE "+where, append([]interface{}{row[col]}, args...)...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for i := len(menuTables) - 1; i >= 0; i-- {
+		if err := menuTables[i].prune(ctx, q, restaurantID, snap[menuTables[i].name]); err != nil {
+			return err
+		}
+	}
+	for _, row := range snap["menu_categories"] {
+		id, _ := row["id"].(string)
+		name, _ := row["name"].(string)
+		if old, ok := names[id]; ok && old != name && row["deleted_at"] == nil {
+			if _, err := q.ExecContext(ctx, "UPDATE kitchen_station_routes SET category=$1 WHERE category=$2 AND station_id IN (SELECT id FROM kitchen_stations WHERE restaurant_id=$3)",
+				name, old, restaurantID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// categoryNames maps a restaurant's categories to their names
+func categoryNames(ctx context.Context, q sqlQueryer, restaurantID string) (map[string]string, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, name FROM menu_categories WHERE restaurant_id=$1 AND deleted_at IS NULL", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// upsert writes one snapshot row, leaving its late columns empty. A row already live keeps its
+// live columns; one brought back from deleted takes the snapshot's.
+func (t menuTable) upsert(ctx context.Context, q sqlQueryer, row map[string]interface{}) error {
+	cols := make([]string, 0, len(row))
+	for c := range row {
+		if !columnName.MatchString(c) {
+			return fmt.Errorf("menu snapshot: bad column %q in %s", c, t.name)
+		}
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	marks := make([]string, len(cols))
+	args := make([]interface{}, len(cols))
+	var set []string
+	for i, c := range cols {
+		marks[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = row[c]
+		if hasColumn(t.late, c) {
+			args[i] = nil
+		}
+		switch {
+		case hasColumn(t.key, c):
+		case !hasColumn(t.live, c):
+			set = append(set, c+"=EXCLUDED."+c)
+		case t.softDelete:
+			set = append(set, c+"=CASE WHEN "+t.name+".deleted_at IS NULL THEN "+t.name+"."+c+" ELSE EXCLUDED."+c+" END")
+		}
+	}
+	conflict := "DO NOTHING"
+	if len(set) > 0 {
+		conflict = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+	_, err := q.ExecContext(ctx, "INSERT INTO "+t.name+" ("+strings.Join(cols, ", ")+") VALUES ("+strings.Join(marks, ", ")+") "+
+		"ON CONFLICT ("+strings.Join(t.key, ", ")+") "+conflict, args...)
+	return err
+}
+
+// prune removes the restaurant's rows that are not among keep
+func (t menuTable) prune(ctx context.Context, q sqlQueryer, restaurantID string, keep []map[string]interface{}) error {
+	kept := map[string]bool{}
+	for _, row := range keep {
+		kept[t.keyOf(row)] = true
+	}
+	query := "SELECT " + strings.Join(t.key, ", ") + " FROM " + t.name + " WHERE (" + t.scope + ")"
+	if t.softDelete {
+		query += " AND deleted_at IS NULL"
+	}
+	rows, err := q.QueryContext(ctx, query, restaurantID)
+	if err != nil {
+		return err
+	}
+	var stale []map[string]interface{}
+	for rows.Next() {
+		vals := make([]string, len(t.key))
+		dest := make([]interface{}, len(t.key))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		row := map[string]interface{}{}
+		for i, c := range t.key {
+			row[c] = vals[i]
+		}
+		if !kept[t.keyOf(row)] {
+			stale = append(stale, row)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, row := range stale {
+		where, args := t.match(row, 1)
+		query := "DELETE FROM " + t.name + " WHERE " + where
+		if t.softDelete {
+			query = "UPDATE " + t.name + " SET deleted_at=NOW() WHERE " + where
+		}
+		if _, err := q.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// match is the condition picking a row by its key, numbering placeholders from first
+func (t menuTable) match(row map[string]interface{}, first int) (string, []interface{}) {
+	conds := make([]string, len(t.key))
+	args := make([]interface{}, len(t.key))
+	for i, c := range t.key {
+		conds[i] = fmt.Sprintf("%s=$%d", c, first+i)
+		args[i] = row[c]
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (t menuTable) keyOf(row map[string]interface{}) string {
+	parts := make([]string, len(t.key))
+	for i, c := range t.key {
+		parts[i] = fmt.Sprint(row[c])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func hasColumn(cols []string, col string) bool {
+	for _, c := range cols {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
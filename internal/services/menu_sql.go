@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"time"
 
 	"restaurant-system/internal/models"
 )
@@ -13,94 +15,341 @@ type MenuCategoryDTO struct {
 	Items []models.MenuItem `json:"items"`
 }
 
+// MenuTagFilter narrows or annotates the QR menu by tag. Items containing any of Allergens are
+// dropped when HideAllergens is set and flagged with AllergenWarnings otherwise; items must carry
+// every tag in Dietary, and their variants and add-ons tagged without one are left out.
+type MenuTagFilter struct {
+	Allergens     models.Allergens
+	HideAllergens bool
+	Dietary       models.DietaryTags
+}
+
+// apply returns the items that pass the filter with their allergen warnings set
+func (f MenuTagFilter) apply(items []models.MenuItem) []models.MenuItem {
+	out := items[:0]
+	for _, it := range items {
+		if !it.Dietary.HasAll(f.Dietary) {
+			continue
+		}
+		it.AllergenWarnings = it.Allergens.Intersect(f.Allergens)
+		if f.HideAllergens && len(it.AllergenWarnings) > 0 {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
 type MenuSQLService struct {
 	db *sql.DB
+	// draft is set on the copy of the service that edits a draft menu version: it reads and
+	// writes the live tables inside a transaction whose changes are saved to the draft and
+	// then rolled back
+	draft *sql.Tx
 }
 
 func NewMenuSQLService(db *sql.DB) *MenuSQLService {
 	return &MenuSQLService{db: db}
 }
 
-// GetQRMenu groups items by category; restaurantID/tableID are accepted for route shape.
-func (s *MenuSQLService) GetQRMenu(ctx context.Context, restaurantID string, tableID string, lang string) ([]MenuCategoryDTO, error) {
-	// Fetch distinct categories
-	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT category FROM menu_items WHERE available = TRUE ORDER BY category ASC")
+// conn is where the service reads and writes the menu
+func (s *MenuSQLService) conn() sqlQueryer {
+	if s.draft != nil {
+		return s.draft
+	}
+	return s.db
+}
+
+// inTx runs fn in a transaction that is committed when fn succeeds. A draft's edits join the
+// draft's transaction instead.
+func (s *MenuSQLService) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if s.draft != nil {
+		return fn(s.draft)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetQRMenu returns the branch's menu as one of its tables sees it now, in the first of
+// locales each text is translated to.
+func (s *MenuSQLService) GetQRMenu(ctx context.Context, restaurantID string, tableID string, locales []string, tags MenuTagFilter) (*QRMenu, error) {
+	currency, err := s.restaurantCurrency(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTable(ctx, restaurantID, tableID); err != nil {
+		return nil, err
+	}
+	menu, err := branchMenu(ctx, s.conn(), restaurantID, currency, locales, tags, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	menu.TableID = tableID
+	return menu, nil
+}
+
+// PreviewMenu returns the branch's menu as it will look at a given time.
+func (s *MenuSQLService) PreviewMenu(ctx context.Context, restaurantID string, at time.Time, locales []string, tags MenuTagFilter) (*QRMenu, error) {
+	currency, err := s.restaurantCurrency(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	return branchMenu(ctx, s.conn(), restaurantID, currency, locales, tags, at)
+}
+
+// branchMenu groups the branch's available items by category, keeping those whose schedules
+// are open at the given time. Categories left empty by the tag filter are omitted. Text is
+// translated along the locale chain.
+func branchMenu(ctx context.Context, q sqlQueryer, restaurantID, currency string, locales []string, tags MenuTagFilter, at time.Time) (*QRMenu, error) {
+	clock, err := loadMenuClock(ctx, q, restaurantID, at)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := loadMenuTranslator(ctx, q, restaurantID, locales)
+	if err != nil {
+		return nil, err
+	}
+	items, err := branchItems(ctx, q, restaurantID, clock, tr)
+	if err != nil {
+		return nil, err
+	}
+	menu := &QRMenu{RestaurantID: restaurantID, Currency: currency, At: clock.local, Categories: []MenuCategoryDTO{}}
+	var cat *MenuCategoryDTO
+	flush := func() {
+		if cat != nil {
+			if cat.Items = tags.apply(cat.Items); len(cat.Items) > 0 {
+				menu.Categories = append(menu.Categories, *cat)
+			}
+		}
+	}
+	for _, it := range items {
+		if !it.Available {
+			continue
+		}
+		if cat == nil || cat.Name != it.Category {
+			flush()
+			cat = &MenuCategoryDTO{Name: it.Category}
+		}
+		cat.Items = append(cat.Items, it)
+	}
+	flush()
+	var offered []*models.MenuItem
+	onMenu := map[string]bool{}
+	for ci := range menu.Categories {
+		for ii := range menu.Categories[ci].Items {
+			it := &menu.Categories[ci].Items[ii]
+			offered = append(offered, it)
+			onMenu[it.ID] = true
+		}
+	}
+	if err := fillItemOptions(ctx, q, restaurantID, tr, tags.Dietary, offered); err != nil {
+		return nil, err
+	}
+	combos, err := listCombos(ctx, q, restaurantID, true)
+	if err != nil {
+		return nil, err
+	}
+	menu.Combos = offeredCombos(combos, onMenu)
+	if menu.VersionID, err = publishedMenuVersion(ctx, q, restaurantID); err != nil {
+		return nil, err
+	}
+	return menu, nil
+}
+
+// branchItems returns the branch's own items and the shared ones at the branch's price,
+// translated, in category and name order. An item is Available when the branch offers it and
+// its schedules are open at the clock's time.
+func branchItems(ctx context.Context, q sqlQueryer, restaurantID string, clock *menuClock, tr *menuTranslator) ([]models.MenuItem, error) {
+	rows, err := q.QueryContext(ctx, "SELECT m.id, m.name, m.description, COALESCE(o.price, m.price), COALESCE(o.available, m.available, FALSE), COALESCE(m.category_id, ''), COALESCE(m.category, ''), "+
+		"COALESCE(m.image_url, ''), m.image_variants, COALESCE(m.special_notes, ''), COALESCE(m.allergens, '[]'), COALESCE(m.dietary, '[]'), COALESCE(m.schedule_id, ''), COALESCE(c.schedule_id, '') FROM menu_items m "+branchMenuJoin+
+		" LEFT JOIN menu_categories c ON c.id = m.category_id AND c.deleted_at IS NULL"+
+		" WHERE m.deleted_at IS NULL AND (m.restaurant_id = $1 OR m.restaurant_id IS NULL) "+
+		"ORDER BY m.category ASC, m.name ASC", restaurantID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var categories []string
+	var items []models.MenuItem
 	for rows.Next() {
-		var cat string
-		if err := rows.Scan(&cat); err != nil {
+		var it models.MenuItem
+		var name, desc sql.NullString
+		var categorySchedule string
+		if err := rows.Scan(&it.ID, &name, &desc, &it.Price, &it.Available, &it.CategoryID, &it.Category, &it.ImageURL, &it.ImageVariants, &it.SpecialNotes,
+			&it.Allergens, &it.Dietary, &it.ScheduleID, &categorySchedule); err != nil {
 			return nil, err
 		}
-		categories = append(categories, cat)
+		it.Available = it.Available && clock.open(it.ScheduleID, categorySchedule)
+		it.Name = tr.get(models.TranslateItem, it.ID, "name", name.String)
+		it.Description = tr.get(models.TranslateItem, it.ID, "description", desc.String)
+		it.Category = tr.get(models.TranslateCategory, it.CategoryID, "name", it.Category)
+		items = append(items, it)
 	}
+	return items, rows.Err()
+}
 
-	// Fetch items per category
-	result := make([]MenuCategoryDTO, 0, len(categories))
-	for _, cat := range categories {
-		itemRows, err := s.db.QueryContext(ctx, "SELECT id, name, description, price, category, available, image_url, special_notes, name_am, description_am FROM menu_items WHERE available = TRUE AND category = $1 ORDER BY name ASC", cat)
-		if err != nil {
-			return nil, err
+// fillItemOptions sets the translated modifier groups, variants and add-ons of the branch's items.
+// Variants and add-ons tagged without every dietary tag in diet are left out.
+func fillItemOptions(ctx context.Context, q sqlQueryer, restaurantID string, tr *menuTranslator, diet models.DietaryTags, items []*models.MenuItem) error {
+	const branchWhere = "deleted_at IS NULL AND (restaurant_id = $1 OR restaurant_id IS NULL)"
+	groups, err := loadModifierGroups(ctx, q, "item_id IN (SELECT id FROM menu_items WHERE "+branchWhere+")", restaurantID)
+	if err != nil {
+		return err
+	}
+	variants, err := loadItemOptions(ctx, q, variantTable, branchWhere, restaurantID)
+	if err != nil {
+		return err
+	}
+	addons, err := loadItemOptions(ctx, q, addonTable, branchWhere, restaurantID)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		it.ModifierGroups = groups[it.ID]
+		tr.modifierGroups(it.ModifierGroups)
+		for _, v := range variants[it.ID] {
+			if !optionMeets(v.Dietary, diet) {
+				continue
+			}
+			v.Name = tr.get(models.TranslateVariant, v.ID, "name", v.Name)
+			it.Variants = append(it.Variants, v)
 		}
-		var items []models.MenuItem
-		for itemRows.Next() {
-			var it models.MenuItem
-			var name, desc, nameAm, descAm sql.NullString
-			if err := itemRows.Scan(&it.ID, &name, &desc, &it.Price, &it.Category, &it.Available, &it.ImageURL, &it.SpecialNotes, &nameAm, &descAm); err != nil {
-				itemRows.Close()
-				return nil, err
+		for _, a := range addons[it.ID] {
+			if !optionMeets(a.Dietary, diet) {
+				continue
 			}
-			// choose language
-			if lang == "am" && nameAm.Valid {
-				it.Name = nameAm.String
-			} else if name.Valid {
-				it.Name = name.String
+			a.Name = tr.get(models.TranslateAddon, a.ID, "name", a.Name)
+			it.Addons = append(it.Addons, models.MenuAddon(a))
+		}
+	}
+	return nil
+}
+
+// optionMeets reports whether a variant or add-on keeps the dietary claims in diet. An untagged
+// option changes nothing, so it keeps the item's claims.
+func optionMeets(tags, diet models.DietaryTags) bool {
+	return len(tags) == 0 || tags.HasAll(diet)
+}
+
+// offeredCombos narrows combos to the choices that are on the menu, dropping any combo left
+// with an empty slot
+func offeredCombos(combos []models.Combo, onMenu map[string]bool) []models.Combo {
+	out := []models.Combo{}
+	for _, c := range combos {
+		slots := make([]models.ComboSlot, 0, len(c.Slots))
+		for _, slot := range c.Slots {
+			var choices []models.ComboChoice
+			for _, ch := range slot.Choices {
+				if onMenu[ch.MenuItemID] {
+					choices = append(choices, ch)
+				}
 			}
-			if lang == "am" && descAm.Valid {
-				it.Description = descAm.String
-			} else if desc.Valid {
-				it.Description = desc.String
+			if len(choices) == 0 {
+				break
 			}
-			items = append(items, it)
+			slot.Choices = choices
+			slots = append(slots, slot)
+		}
+		if len(slots) == len(c.Slots) {
+			c.Slots = slots
+			out = append(out, c)
 		}
-		itemRows.Close()
-		result = append(result, MenuCategoryDTO{Name: cat, Items: items})
 	}
-	return result, nil
+	return out
 }
 
-// CRUD for items
+// CRUD for items; this is the one store both the QR menu and ordering read
 func (s *MenuSQLService) CreateItem(ctx context.Context, it *models.MenuItem) error {
-	if it.Name == "" || it.Category == "" || it.Price <= 0 {
-		return errors.New("invalid item")
+	if err := resolveCategory(ctx, s.conn(), it); err != nil {
+		return err
+	}
+	if it.RestaurantID == "" {
+		return errors.New("restaurant_id required")
 	}
-	_, err := s.db.ExecContext(ctx,
-		"INSERT INTO menu_items (id, name, description, price, category, available) VALUES ($1,$2,$3,$4,$5,$6)",
-		it.ID, it.Name, it.Description, it.Price, it.Category, it.Available,
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO menu_items (id, restaurant_id, category_id, schedule_id, name, description, price, category, available, image_url, special_notes, name_am, description_am, allergens, dietary, created_at, updated_at) "+
+			"VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,NOW(),NOW())",
+		it.ID, nullIfEmpty(it.RestaurantID), nullIfEmpty(it.CategoryID), nullIfEmpty(it.ScheduleID), it.Name, it.Description, it.Price, it.Category, it.Available,
+		it.ImageURL, it.SpecialNotes, it.NameAm, it.DescriptionAm, it.Allergens, it.Dietary,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return saveLegacyTranslations(ctx, s.conn(), it.ID, it.NameAm, it.DescriptionAm)
+}
+
+// PatchItem applies the fields present in a JSON body to a stored item, leaving the others as
+// they are, and returns the updated item.
+func (s *MenuSQLService) PatchItem(ctx context.Context, id string, patch []byte) (*models.MenuItem, error) {
+	it, err := s.GetItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := mergeItem(it, patch); err != nil {
+		return nil, err
+	}
+	if err := s.UpdateItem(ctx, it); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// mergeItem decodes patch over it. The ID never changes and a branch item never becomes shared.
+func mergeItem(it *models.MenuItem, patch []byte) error {
+	id, restaurantID := it.ID, it.RestaurantID
+	if err := json.Unmarshal(patch, it); err != nil {
+		return err
+	}
+	it.ID = id
+	if it.RestaurantID == "" {
+		it.RestaurantID = restaurantID
+	}
+	return nil
 }
 
+// UpdateItem replaces every field of an item; use PatchItem to change only some. An empty
+// RestaurantID keeps the stored one.
 func (s *MenuSQLService) UpdateItem(ctx context.Context, it *models.MenuItem) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE menu_items SET name=$1, description=$2, price=$3, category=$4, available=$5 WHERE id=$6",
-		it.Name, it.Description, it.Price, it.Category, it.Available, it.ID,
+	if err := resolveCategory(ctx, s.conn(), it); err != nil {
+		return err
+	}
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE menu_items SET restaurant_id=COALESCE($1, restaurant_id), category_id=$2, schedule_id=$3, name=$4, description=$5, price=$6, category=$7, available=$8, image_variants=CASE WHEN image_url IS NOT DISTINCT FROM $9 THEN image_variants END, image_url=$9, special_notes=$10, name_am=$11, description_am=$12, allergens=$13, dietary=$14, updated_at=NOW() "+
+			"WHERE id=$15 AND deleted_at IS NULL",
+		nullIfEmpty(it.RestaurantID), nullIfEmpty(it.CategoryID), nullIfEmpty(it.ScheduleID), it.Name, it.Description, it.Price, it.Category, it.Available,
+		it.ImageURL, it.SpecialNotes, it.NameAm, it.DescriptionAm, it.Allergens, it.Dietary, it.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return saveLegacyTranslations(ctx, s.conn(), it.ID, it.NameAm, it.DescriptionAm)
 }
 
+// DeleteItem retires an item; the row stays because order lines refer to it.
 func (s *MenuSQLService) DeleteItem(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM menu_items WHERE id=$1", id)
-	return err
+	res, err := s.conn().ExecContext(ctx, "UPDATE menu_items SET deleted_at=NOW(), available=FALSE WHERE id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
 }
 
 // Fetch average rating for an item
 func (s *MenuSQLService) GetAverageRating(ctx context.Context, menuItemID string) (float64, error) {
 	var avg sql.NullFloat64
-	if err := s.db.QueryRowContext(ctx, "SELECT AVG(rating) FROM reviews WHERE menu_item_id=$1", menuItemID).Scan(&avg); err != nil {
+	if err := s.conn().QueryRowContext(ctx, "SELECT AVG(rating) FROM reviews WHERE menu_item_id=$1", menuItemID).Scan(&avg); err != nil {
 		return 0, err
 	}
 	if avg.Valid {
@@ -114,12 +363,12 @@ func (s *MenuSQLService) AddFavorite(ctx context.Context, fav *models.Favorite)
 	if fav.ID == "" {
 		return errors.New("id required")
 	}
-	_, err := s.db.ExecContext(ctx, "INSERT INTO favorites (id, account_id, menu_item_id, created_at) VALUES ($1,$2,$3,now())", fav.ID, fav.AccountID, fav.MenuItemID)
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO favorites (id, account_id, menu_item_id, created_at) VALUES ($1,$2,$3,now())", fav.ID, fav.AccountID, fav.MenuItemID)
 	return err
 }
 
 func (s *MenuSQLService) RemoveFavorite(ctx context.Context, accountID, menuItemID string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM favorites WHERE account_id=$1 AND menu_item_id=$2", accountID, menuItemID)
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM favorites WHERE account_id=$1 AND menu_item_id=$2", accountID, menuItemID)
 	return err
 }
 
@@ -131,7 +380,7 @@ func (s *MenuSQLService) CreateReview(ctx context.Context, r *models.Review) err
 	if r.Rating < 1 || r.Rating > 5 {
 		return errors.New("rating must be between 1 and 5")
 	}
-	_, err := s.db.ExecContext(ctx, "INSERT INTO reviews (id, account_id, menu_item_id, rating, comment, created_at) VALUES ($1,$2,$3,$4,$5,now())", r.ID, r.AccountID, r.MenuItemID, r.Rating, r.Comment)
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO reviews (id, account_id, menu_item_id, rating, comment, created_at) VALUES ($1,$2,$3,$4,$5,now())", r.ID, r.AccountID, r.MenuItemID, r.Rating, r.Comment)
 	return err
 }
 
@@ -140,12 +389,12 @@ func (s *MenuSQLService) CreateCategory(ctx context.Context, cat *models.Categor
 	if cat.Name == "" {
 		return errors.New("name required")
 	}
-	_, err := s.db.ExecContext(ctx, "INSERT INTO categories (id, name, description, created_at, updated_at) VALUES ($1,$2,$3,now(),now())", cat.ID, cat.Name, cat.Description)
+	_, err := s.conn().ExecContext(ctx, "INSERT INTO categories (id, name, description, created_at, updated_at) VALUES ($1,$2,$3,now(),now())", cat.ID, cat.Name, cat.Description)
 	return err
 }
 
 func (s *MenuSQLService) ListCategories(ctx context.Context) ([]models.Category, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, name, description, created_at, updated_at FROM categories ORDER BY name ASC")
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, name, description, created_at, updated_at FROM categories ORDER BY name ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -162,11 +411,11 @@ func (s *MenuSQLService) ListCategories(ctx context.Context) ([]models.Category,
 }
 
 func (s *MenuSQLService) UpdateCategory(ctx context.Context, cat *models.Category) error {
-	_, err := s.db.ExecContext(ctx, "UPDATE categories SET name=$1, description=$2, updated_at=now() WHERE id=$3", cat.Name, cat.Description, cat.ID)
+	_, err := s.conn().ExecContext(ctx, "UPDATE categories SET name=$1, description=$2, updated_at=now() WHERE id=$3", cat.Name, cat.Description, cat.ID)
 	return err
 }
 
 func (s *MenuSQLService) DeleteCategory(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM categories WHERE id=$1", id)
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM categories WHERE id=$1", id)
 	return err
 }
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeItem(t *testing.T) {
+	stored := models.MenuItem{ID: "i1", RestaurantID: "r1", CategoryID: "c1", Name: "Tibs", Price: 250,
+		Category: "Mains", Available: true, ImageURL: "/static/uploads/menu/items/i1/full.jpg"}
+
+	it := stored
+	assert.NoError(t, mergeItem(&it, []byte(`{"id":"other","price":300}`)))
+	want := stored
+	want.Price = 300
+	assert.Equal(t, want, it) // availability, image and scoping are kept
+
+	it = stored
+	assert.NoError(t, mergeItem(&it, []byte(`{"restaurant_id":"","category_id":"","category":"Specials"}`)))
+	assert.Equal(t, "r1", it.RestaurantID) // never becomes a shared item
+	assert.Empty(t, it.CategoryID)
+	assert.Equal(t, "Specials", it.Category)
+
+	it = stored
+	assert.NoError(t, mergeItem(&it, []byte(`{"available":false}`)))
+	assert.False(t, it.Available)
+
+	assert.Error(t, mergeItem(&it, []byte(`{"price":"free"}`)))
+}
+
+func TestOptionMeets(t *testing.T) {
+	vegan := models.DietaryTags{models.DietVegan}
+	assert.True(t, optionMeets(nil, vegan)) // untagged options keep the item's claims
+	assert.True(t, optionMeets(models.DietaryTags{models.DietVegan, models.DietHalal}, vegan))
+	assert.False(t, optionMeets(models.DietaryTags{models.DietVegetarian}, vegan))
+	assert.True(t, optionMeets(models.DietaryTags{models.DietVegetarian}, nil))
+}
+
+func TestMenuRestaurantScoping(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	assert.NoError(t, s.CreateMenuCategory(ctx, &models.MenuCategory{ID: "c2", RestaurantID: "r2", Name: "Mains"}))
+	assert.NoError(t, s.CreateItem(ctx, &models.MenuItem{ID: "i2", CategoryID: "c2", Name: "Kitfo", Price: 300, Available: true}))
+	_, err := db.ExecContext(ctx, "INSERT INTO menu_items (id, name, price, category, available) VALUES ('s1', 'Buna', 60, 'Drinks', TRUE)")
+	assert.NoError(t, err)
+
+	items, err := s.ListItems(ctx, "r1", "")
+	assert.NoError(t, err)
+	var names []string
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	assert.Equal(t, []string{"Buna", "Tibs"}, names) // its own items and the shared ones
+
+	cats, err := s.ListMenuCategories(ctx, "r2")
+	assert.NoError(t, err)
+	if assert.Len(t, cats, 1) {
+		assert.Equal(t, "c2", cats[0].ID)
+	}
+
+	// an item takes its category's restaurant and cannot sit in another branch's category
+	it, err := s.GetItem(ctx, "i2")
+	assert.NoError(t, err)
+	assert.Equal(t, "r2", it.RestaurantID)
+	assert.EqualError(t, s.CreateItem(ctx, &models.MenuItem{ID: "i3", RestaurantID: "r1", CategoryID: "c2", Name: "Shiro", Price: 150}),
+		"category belongs to another restaurant")
+	assert.EqualError(t, s.CreateItem(ctx, &models.MenuItem{ID: "i3", Category: "Mains", Name: "Shiro", Price: 150}), "restaurant_id required")
+
+	// orders take their own branch's items and the shared ones; without a branch only shared ones
+	now := time.Now()
+	_, err = priceOrderLine(ctx, db, "o1", "r1", now, CreateOrderItemReq{MenuItemID: "i1", Quantity: 1})
+	assert.NoError(t, err)
+	_, err = priceOrderLine(ctx, db, "o1", "r2", now, CreateOrderItemReq{MenuItemID: "i1", Quantity: 1})
+	assert.EqualError(t, err, "menu item i1 is not available")
+	_, err = priceOrderLine(ctx, db, "o1", "", now, CreateOrderItemReq{MenuItemID: "i1", Quantity: 1})
+	assert.EqualError(t, err, "menu item i1 is not available")
+	_, err = priceOrderLine(ctx, db, "o1", "", now, CreateOrderItemReq{MenuItemID: "s1", Quantity: 1})
+	assert.NoError(t, err)
+}
+
+func TestUpdateItemKeepsUnsentFields(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	_, err := db.ExecContext(ctx, "UPDATE menu_items SET image_url='/static/uploads/menu/items/i1/full.jpg', special_notes='Spicy', allergens='[\"gluten\"]' WHERE id='i1'")
+	assert.NoError(t, err)
+	stored, err := s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+
+	it, err := s.PatchItem(ctx, "i1", []byte(`{"price":300}`))
+	assert.NoError(t, err)
+	got, err := s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Equal(t, it, got)
+	want := *stored
+	want.Price = 300
+	assert.Equal(t, &want, got)
+
+	// a full update without a restaurant keeps the stored one
+	got.RestaurantID = ""
+	got.Name = "Special Tibs"
+	assert.NoError(t, s.UpdateItem(ctx, got))
+	got, err = s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Equal(t, "r1", got.RestaurantID)
+	assert.Equal(t, "Special Tibs", got.Name)
+
+	_, err = s.PatchItem(ctx, "missing", []byte(`{"price":300}`))
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+	assert.ErrorIs(t, s.UpdateItem(ctx, &models.MenuItem{ID: "missing", RestaurantID: "r1", Category: "Mains", Name: "Shiro", Price: 150}), ErrMenuNotFound)
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ImportMenu applies rows to a restaurant's menu in one transaction. Categories, items,
+// variants and add-ons are matched by name, so an existing one is updated and the rest are
+// created; deleted categories and items of the same name are restored with their ids. Nothing
+// is deleted and shared items are never touched. Every row is checked, and when any fails or
+// on a dry run the transaction is rolled back and only the report returned.
+func (s *MenuSQLService) ImportMenu(ctx context.Context, restaurantID string, rows []models.MenuRow, dryRun bool) (*models.MenuImportResult, error) {
+	if _, err := s.restaurantCurrency(ctx, restaurantID); err != nil {
+		return nil, err
+	}
+	var res *models.MenuImportResult
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		imp, err := newMenuImport(ctx, tx, restaurantID)
+		if err != nil {
+			return err
+		}
+		imp.res.DryRun, res = dryRun, imp.res
+		if err := imp.apply(ctx, tx, rows); err != nil {
+			return err
+		}
+		if len(imp.res.Errors) > 0 || dryRun {
+			return errMenuNotApplied
+		}
+		return nil
+	})
+	if err == errMenuNotApplied {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Applied = true
+	return res, nil
+}
+
+// ImportMenuDraft is ImportMenu into the restaurant's working draft rather than the live menu
+func (s *MenuSQLService) ImportMenuDraft(ctx context.Context, restaurantID, userID string, rows []models.MenuRow, dryRun bool) (*models.MenuImportResult, error) {
+	var res *models.MenuImportResult
+	_, err := s.EditMenuDraft(ctx, restaurantID, userID, func(d *MenuSQLService) error {
+		var err error
+		if res, err = d.ImportMenu(ctx, restaurantID, rows, dryRun); err != nil {
+			return err
+		}
+		if !res.Applied {
+			return errMenuNotApplied
+		}
+		return nil
+	})
+	if err != nil && err != errMenuNotApplied {
+		return nil, err
+	}
+	return res, nil
+}
+
+// errMenuNotApplied rolls back a menu change that was only checked
+var errMenuNotApplied = errors.New("menu change not applied")
+
+// menuRowError is a problem with the row being imported rather than with the database
+type menuRowError string
+
+func (e menuRowError) Error() string { return string(e) }
+
+// menuImport writes import rows to one restaurant's menu, remembering what it wrote
+type menuImport struct {
+	restaurantID string
+	// categories and items map names to ids, preferring live rows over deleted ones
+	categories map[string]string
+	items      map[string]string
+	// deleted holds the ids of deleted categories and items until a row restores them
+	deleted map[string]bool
+	// written holds the ids of the categories, items, variants and add-ons the rows describe
+	written map[string]bool
+	res     *models.MenuImportResult
+}
+
+func newMenuImport(ctx context.Context, q sqlQueryer, restaurantID string) (*menuImport, error) {
+	imp := &menuImport{restaurantID: restaurantID, categories: map[string]string{}, items: map[string]string{}, deleted: map[string]bool{}, written: map[string]bool{},
+		res: &models.MenuImportResult{Created: map[string]int{}, Updated: map[string]int{}, Errors: []models.MenuImportError{}}}
+	// deleted rows come first so a live one of the same name wins
+	if err := imp.load(ctx, q, imp.categories, "SELECT name, id, deleted_at IS NOT NULL FROM menu_categories WHERE restaurant_id=$1 ORDER BY deleted_at IS NULL, deleted_at", restaurantID); err != nil {
+		return nil, err
+	}
+	if err := imp.load(ctx, q, imp.items, "SELECT name, id, deleted_at IS NOT NULL FROM menu_items WHERE restaurant_id=$1 ORDER BY deleted_at IS NULL, deleted_at", restaurantID); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// load maps the names a (name, id, deleted) query returns to their ids; later rows win
+func (m *menuImport) load(ctx context.Context, q sqlQueryer, names map[string]string, query string, args ...interface{}) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, id string
+		var deleted bool
+		if err := rows.Scan(&name, &id, &deleted); err != nil {
+			return err
+		}
+		names[name] = id
+		m.deleted[id] = deleted
+	}
+	return rows.Err()
+}
+
+// apply writes rows in order, recording row problems in the result
+func (m *menuImport) apply(ctx context.Context, q sqlQueryer, rows []models.MenuRow) error {
+	for i, row := range rows {
+		if err := row.Validate(); err != nil {
+			m.res.Errors = append(m.res.Errors, models.MenuImportError{Row: i + 1, Error: err.Error()})
+			continue
+		}
+		var created bool
+		var err error
+		switch row.Type {
+		case models.MenuRowCategory:
+			_, created, err = m.category(ctx, q, row.Name)
+		case models.MenuRowItem:
+			created, err = m.item(ctx, q, row)
+		case models.MenuRowVariant:
+			created, err = m.option(ctx, q, variantTable, row)
+		case models.MenuRowAddon:
+			created, err = m.option(ctx, q, addonTable, row)
+		}
+		var rowErr menuRowError
+		if errors.As(err, &rowErr) {
+			m.res.Errors = append(m.res.Errors, models.MenuImportError{Row: i + 1, Error: rowErr.Error()})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if created {
+			m.res.Created[string(row.Type)]++
+		} else if row.Type != models.MenuRowCategory {
+			m.res.Updated[string(row.Type)]++
+		}
+	}
+	return nil
+}
+
+func (m *menuImport) category(ctx context.Context, q sqlQueryer, name string) (string, bool, error) {
+	if id, ok := m.categories[name]; ok {
+		if m.deleted[id] {
+			if _, err := q.ExecContext(ctx, "UPDATE menu_categories SET deleted_at=NULL, updated_at=NOW() WHERE id=$1", id); err != nil {
+				return "", false, err
+			}
+			m.deleted[id] = false
+		}
+		m.written[id] = true
+		return id, false, nil
+	}
+	id := uuid.New().String()
+	if _, err := q.ExecContext(ctx, "INSERT INTO menu_categories (id, restaurant_id, name, created_at, updated_at) VALUES ($1,$2,$3,NOW(),NOW())", id, m.restaurantID, name); err != nil {
+		return "", false, err
+	}
+	m.categories[name] = id
+	m.written[id] = true
+	return id, true, nil
+}
+
+// item creates or updates one of the restaurant's items. Items left without an availability
+// stay as they are, or are available when new; an empty image keeps the current one, and a
+// different one drops the uploaded sizes of the current one.
+func (m *menuImport) item(ctx context.Context, q sqlQueryer, row models.MenuRow) (bool, error) {
+	categoryID, _, err := m.category(ctx, q, row.Category)
+	if err != nil {
+		return false, err
+	}
+	if id, ok := m.items[row.Name]; ok {
+		_, err := q.ExecContext(ctx, "UPDATE menu_items SET category_id=$1, category=$2, description=$3, price=$4, available=COALESCE($5, available), name_am=$6, description_am=$7, "+
+			"allergens=$8, dietary=$9, image_variants=CASE WHEN NULLIF($10, '') IS NULL OR image_url IS NOT DISTINCT FROM $10 THEN image_variants END, "+
+			"image_url=COALESCE(NULLIF($10, ''), image_url), special_notes=$11, deleted_at=NULL, updated_at=NOW() WHERE id=$12",
+			categoryID, row.Category, row.Description, row.Price, row.Available, row.NameAm, row.DescriptionAm, row.Allergens, row.Dietary, row.ImageURL, row.SpecialNotes, id)
+		if err != nil {
+			return false, err
+		}
+		m.deleted[id], m.written[id] = false, true
+		return false, saveLegacyTranslations(ctx, q, id, row.NameAm, row.DescriptionAm)
+	}
+	available := row.Available == nil || *row.Available
+	id := uuid.New().String()
+	if _, err := q.ExecContext(ctx, "INSERT INTO menu_items (id, restaurant_id, category_id, name, description, price, category, available, image_url, special_notes, name_am, description_am, allergens, dietary, created_at, updated_at) "+
+		"VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,NOW(),NOW())",
+		id, m.restaurantID, categoryID, row.Name, row.Description, row.Price, row.Category, available, row.ImageURL, row.SpecialNotes, row.NameAm, row.DescriptionAm,
+		row.Allergens, row.Dietary); err != nil {
+		return false, err
+	}
+	m.items[row.Name] = id
+	m.written[id] = true
+	return true, saveLegacyTranslations(ctx, q, id, row.NameAm, row.DescriptionAm)
+}
+
+// option creates or updates a variant or add-on of an item on the menu or earlier in the import
+func (m *menuImport) option(ctx context.Context, q sqlQueryer, table menuOptionTable, row models.MenuRow) (bool, error) {
+	itemID, ok := m.items[row.Item]
+	if !ok || m.deleted[itemID] {
+		return false, menuRowError("item " + row.Item + " is not on the menu")
+	}
+	var id string
+	err := q.QueryRowContext(ctx, "SELECT id FROM "+string(table)+" WHERE item_id=$1 AND name=$2 LIMIT 1", itemID, row.Name).Scan(&id)
+	if err == sql.ErrNoRows {
+		id = uuid.New().String()
+		_, err = q.ExecContext(ctx, "INSERT INTO "+string(table)+" (id, item_id, name, price_delta, allergens, dietary) VALUES ($1,$2,$3,$4,$5,$6)",
+			id, itemID, row.Name, row.Price, row.Allergens, row.Dietary)
+		m.written[id] = true
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = q.ExecContext(ctx, "UPDATE "+string(table)+" SET price_delta=$1, allergens=$2, dietary=$3 WHERE id=$4", row.Price, row.Allergens, row.Dietary, id)
+	m.written[id] = true
+	return false, err
+}
+
+// prune removes what the applied rows no longer describe, so the restaurant's own menu matches
+// them exactly: other items and then emptied categories are soft-deleted, and the remaining
+// items lose the variants and add-ons the rows left out. Shared items are not touched.
+func (m *menuImport) prune(ctx context.Context, q sqlQueryer) error {
+	steps := []struct{ list, remove string }{
+		{"SELECT id FROM menu_items WHERE restaurant_id=$1 AND deleted_at IS NULL", "UPDATE menu_items SET deleted_at=NOW() WHERE id=$1"},
+		{"SELECT id FROM menu_categories WHERE restaurant_id=$1 AND deleted_at IS NULL", "UPDATE menu_categories SET deleted_at=NOW() WHERE id=$1"},
+		{"SELECT id FROM menu_variants WHERE item_id IN (SELECT id FROM menu_items WHERE restaurant_id=$1 AND deleted_at IS NULL)", "DELETE FROM menu_variants WHERE id=$1"},
+		{"SELECT id FROM menu_addons WHERE item_id IN (SELECT id FROM menu_items WHERE restaurant_id=$1 AND deleted_at IS NULL)", "DELETE FROM menu_addons WHERE id=$1"},
+	}
+	for _, step := range steps {
+		rows, err := q.QueryContext(ctx, step.list, m.restaurantID)
+		if err != nil {
+			return err
+		}
+		var stale []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			if !m.written[id] {
+				stale = append(stale, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, id := range stale {
+			if _, err := q.ExecContext(ctx, step.remove, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ExportMenu returns a restaurant's categories and own items, each item followed by its
+// variants and add-ons, in the form ImportMenu reads. Shared items are left out since every
+// restaurant already has them.
+func (s *MenuSQLService) ExportMenu(ctx context.Context, restaurantID string) ([]models.MenuRow, error) {
+	if _, err := s.restaurantCurrency(ctx, restaurantID); err != nil {
+		return nil, err
+	}
+	cats, err := s.ListMenuCategories(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	out := []models.MenuRow{}
+	for _, c := range cats {
+		out = append(out, models.MenuRow{Type: models.MenuRowCategory, Name: c.Name})
+	}
+
+	options := map[string][]models.MenuRow{}
+	for _, table := range []menuOptionTable{variantTable, addonTable} {
+		typ := models.MenuRowVariant
+		if table == addonTable {
+			typ = models.MenuRowAddon
+		}
+		rows, err := s.conn().QueryContext(ctx, "SELECT item_id, name, price_delta, COALESCE(allergens, '[]'), COALESCE(dietary, '[]') FROM "+string(table)+
+			" WHERE item_id IN (SELECT id FROM menu_items WHERE restaurant_id=$1 AND deleted_at IS NULL) ORDER BY name ASC", restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var itemID string
+			r := models.MenuRow{Type: typ}
+			if err := rows.Scan(&itemID, &r.Name, &r.Price, &r.Allergens, &r.Dietary); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			options[itemID] = append(options[itemID], r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+menuItemColumns+" FROM menu_items WHERE restaurant_id=$1 AND deleted_at IS NULL ORDER BY category ASC, name ASC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		it, err := scanMenuItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		available := it.Available
+		out = append(out, models.MenuRow{
+			Type: models.MenuRowItem, Category: it.Category, Name: it.Name, Description: it.Description, Price: it.Price, Available: &available,
+			NameAm: it.NameAm, DescriptionAm: it.DescriptionAm, Allergens: it.Allergens, Dietary: it.Dietary, ImageURL: it.ImageURL, SpecialNotes: it.SpecialNotes,
+		})
+		for _, o := range options[it.ID] {
+			o.Item = it.Name
+			out = append(out, o)
+		}
+	}
+	return out, rows.Err()
+}
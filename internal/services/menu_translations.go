@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"restaurant-system/internal/models"
+)
+
+// translationTables are the tables holding each translatable entity
+var translationTables = map[models.TranslatableEntity]string{
+	models.TranslateCategory:       "menu_categories",
+	models.TranslateItem:           "menu_items",
+	models.TranslateVariant:        "menu_variants",
+	models.TranslateAddon:          "menu_addons",
+	models.TranslateModifierGroup:  "modifier_groups",
+	models.TranslateModifierOption: "modifier_options",
+}
+
+// SetTranslations creates or replaces translations, all or none
+func (s *MenuSQLService) SetTranslations(ctx context.Context, list []models.MenuTranslation) ([]models.MenuTranslation, error) {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for i := range list {
+			t := &list[i]
+			if err := t.Validate(); err != nil {
+				return err
+			}
+			var one int
+			err := tx.QueryRowContext(ctx, "SELECT 1 FROM "+translationTables[t.EntityType]+" WHERE id=$1", t.EntityID).Scan(&one)
+			if err == sql.ErrNoRows {
+				return ErrMenuNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.QueryRowContext(ctx, "INSERT INTO menu_translations (entity_type, entity_id, field, locale, value, updated_at) VALUES ($1,$2,$3,$4,$5,NOW()) "+
+				"ON CONFLICT (entity_type, entity_id, field, locale) DO UPDATE SET value=EXCLUDED.value, updated_at=NOW() RETURNING updated_at",
+				string(t.EntityType), t.EntityID, t.Field, t.Locale, t.Value).Scan(&t.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SetTranslationsDraft is SetTranslations in the working draft of the branch whose menu holds
+// the translated entries, which must all be on one branch's menu
+func (s *MenuSQLService) SetTranslationsDraft(ctx context.Context, userID string, list []models.MenuTranslation) ([]models.MenuTranslation, error) {
+	restaurantID := ""
+	for i := range list {
+		t := &list[i]
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		rid, err := s.EntryRestaurant(ctx, TranslatedEntry(t.EntityType), t.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && rid != restaurantID {
+			return nil, errors.New("translations must all be for one branch's menu")
+		}
+		restaurantID = rid
+	}
+	_, err := s.EditMenuDraft(ctx, restaurantID, userID, func(d *MenuSQLService) error {
+		var err error
+		list, err = d.SetTranslations(ctx, list)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListTranslations returns an entry's translations in every locale
+func (s *MenuSQLService) ListTranslations(ctx context.Context, entityType models.TranslatableEntity, entityID string) ([]models.MenuTranslation, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT entity_type, entity_id, field, locale, value, updated_at FROM menu_translations WHERE entity_type=$1 AND entity_id=$2 ORDER BY locale, field",
+		string(entityType), entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.MenuTranslation{}
+	for rows.Next() {
+		var t models.MenuTranslation
+		if err := rows.Scan(&t.EntityType, &t.EntityID, &t.Field, &t.Locale, &t.Value, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+func (s *MenuSQLService) DeleteTranslation(ctx context.Context, entityType models.TranslatableEntity, entityID, field, locale string) error {
+	res, err := s.conn().ExecContext(ctx, "DELETE FROM menu_translations WHERE entity_type=$1 AND entity_id=$2 AND field=$3 AND locale=$4",
+		string(entityType), entityID, field, models.NormalizeLocale(locale))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMenuNotFound
+	}
+	return nil
+}
+
+// MissingTranslations lists the text on a branch's menu, its own entries and the shared ones,
+// with no translation in exactly the given locale. Fallbacks such as "am" for "am-et" do not
+// count, so a regional locale reports everything it has not overridden.
+func (s *MenuSQLService) MissingTranslations(ctx context.Context, restaurantID, locale string) ([]models.MissingTranslation, error) {
+	const items = "SELECT id FROM menu_items WHERE deleted_at IS NULL AND (restaurant_id = $1 OR restaurant_id IS NULL)"
+	rows, err := s.conn().QueryContext(ctx, "SELECT e.entity_type, e.entity_id, e.field, e.source FROM ("+
+		"SELECT 'category' AS entity_type, id AS entity_id, 'name' AS field, name AS source FROM menu_categories WHERE restaurant_id = $1 AND deleted_at IS NULL"+
+		" UNION ALL SELECT 'item', id, 'name', name FROM menu_items WHERE id IN ("+items+")"+
+		" UNION ALL SELECT 'item', id, 'description', COALESCE(description, '') FROM menu_items WHERE id IN ("+items+")"+
+		" UNION ALL SELECT 'variant', id, 'name', name FROM menu_variants WHERE item_id IN ("+items+")"+
+		" UNION ALL SELECT 'addon', id, 'name', name FROM menu_addons WHERE item_id IN ("+items+")"+
+		" UNION ALL SELECT 'modifier_group', id, 'name', name FROM modifier_groups WHERE item_id IN ("+items+")"+
+		" UNION ALL SELECT 'modifier_option', o.id, 'name', o.name FROM modifier_options o JOIN modifier_groups g ON g.id = o.group_id WHERE g.item_id IN ("+items+")"+
+		") e WHERE e.source <> '' AND NOT EXISTS (SELECT 1 FROM menu_translations t WHERE t.entity_type = e.entity_type AND t.entity_id = e.entity_id AND t.field = e.field AND t.locale = $2) "+
+		"ORDER BY e.entity_type, e.source, e.field", restaurantID, models.NormalizeLocale(locale))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.MissingTranslation{}
+	for rows.Next() {
+		var m models.MissingTranslation
+		if err := rows.Scan(&m.EntityType, &m.EntityID, &m.Field, &m.Source); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// saveLegacyTranslations keeps an item's "am" translations in step with the name_am and
+// description_am that older clients still send
+func saveLegacyTranslations(ctx context.Context, q sqlQueryer, itemID, nameAm, descriptionAm string) error {
+	for field, value := range map[string]string{"name": nameAm, "description": descriptionAm} {
+		if value == "" {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, "INSERT INTO menu_translations (entity_type, entity_id, field, locale, value, updated_at) VALUES ($1,$2,$3,'am',$4,NOW()) "+
+			"ON CONFLICT (entity_type, entity_id, field, locale) DO UPDATE SET value=EXCLUDED.value, updated_at=NOW()",
+			string(models.TranslateItem), itemID, field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// menuTranslator looks up menu text along a locale chain, falling back to the text as written
+type menuTranslator struct {
+	chain []string
+	text  map[string]string
+}
+
+func translationKey(entityType models.TranslatableEntity, id, field, locale string) string {
+	return string(entityType) + "|" + id + "|" + field + "|" + locale
+}
+
+// loadMenuTranslator loads the translations in the chain's locales of the branch's menu entries
+func loadMenuTranslator(ctx context.Context, q sqlQueryer, restaurantID string, chain []string) (*menuTranslator, error) {
+	tr := &menuTranslator{chain: chain, text: map[string]string{}}
+	if len(chain) == 0 {
+		return tr, nil
+	}
+	marks := make([]string, len(chain))
+	args := []interface{}{restaurantID}
+	for i, l := range chain {
+		marks[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, l)
+	}
+	const items = "SELECT id FROM menu_items WHERE deleted_at IS NULL AND (restaurant_id = $1 OR restaurant_id IS NULL)"
+	rows, err := q.QueryContext(ctx, "SELECT entity_type, entity_id, field, locale, value FROM menu_translations WHERE locale IN ("+strings.Join(marks, ",")+") AND ("+
+		"(entity_type = 'category' AND entity_id IN (SELECT category_id FROM menu_items WHERE id IN ("+items+"))) OR "+
+		"(entity_type = 'item' AND entity_id IN ("+items+")) OR "+
+		"(entity_type = 'variant' AND entity_id IN (SELECT id FROM menu_variants WHERE item_id IN ("+items+"))) OR "+
+		"(entity_type = 'addon' AND entity_id IN (SELECT id FROM menu_addons WHERE item_id IN ("+items+"))) OR "+
+		"(entity_type = 'modifier_group' AND entity_id IN (SELECT id FROM modifier_groups WHERE item_id IN ("+items+"))) OR "+
+		"(entity_type = 'modifier_option' AND entity_id IN (SELECT o.id FROM modifier_options o JOIN modifier_groups g ON g.id = o.group_id WHERE g.item_id IN ("+items+"))))", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entityType models.TranslatableEntity
+		var id, field, locale, value string
+		if err := rows.Scan(&entityType, &id, &field, &locale, &value); err != nil {
+			return nil, err
+		}
+		tr.text[translationKey(entityType, id, field, locale)] = value
+	}
+	return tr, rows.Err()
+}
+
+// get returns the first translation along the chain, or fallback
+func (tr *menuTranslator) get(entityType models.TranslatableEntity, id, field, fallback string) string {
+	for _, l := range tr.chain {
+		if v, ok := tr.text[translationKey(entityType, id, field, l)]; ok {
+			return v
+		}
+	}
+	return fallback
+}
+
+// modifierGroups translates groups and their options, nested ones included
+func (tr *menuTranslator) modifierGroups(groups []models.ModifierGroup) {
+	for i := range groups {
+		g := &groups[i]
+		g.Name = tr.get(models.TranslateModifierGroup, g.ID, "name", g.Name)
+		for j := range g.Options {
+			o := &g.Options[j]
+			o.Name = tr.get(models.TranslateModifierOption, o.ID, "name", o.Name)
+			tr.modifierGroups(o.Groups)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const menuVersionColumns = "id, restaurant_id, number, status, COALESCE(note, ''), COALESCE(based_on, ''), publish_at, published_at, COALESCE(created_by, ''), created_at, updated_at"
+
+// scanMenuVersion scans menuVersionColumns, followed by the rows and snapshot columns when
+// withRows is set
+func scanMenuVersion(row rowScanner, withRows bool) (*models.MenuVersion, error) {
+	var v models.MenuVersion
+	var publishAt, publishedAt sql.NullTime
+	dest := []interface{}{&v.ID, &v.RestaurantID, &v.Number, &v.Status, &v.Note, &v.BasedOn, &publishAt, &publishedAt, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt}
+	if withRows {
+		dest = append(dest, &v.Rows, &v.Snapshot)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if publishAt.Valid {
+		v.PublishAt = &publishAt.Time
+	}
+	if publishedAt.Valid {
+		v.PublishedAt = &publishedAt.Time
+	}
+	return &v, nil
+}
+
+func getMenuVersion(ctx context.Context, q sqlQueryer, id string, lock bool) (*models.MenuVersion, error) {
+	query := "SELECT " + menuVersionColumns + ", COALESCE(rows, '[]'), snapshot FROM menu_versions WHERE id=$1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	v, err := scanMenuVersion(q.QueryRowContext(ctx, query, id), true)
+	if err == sql.ErrNoRows {
+		return nil, ErrMenuNotFound
+	}
+	return v, err
+}
+
+// publishedMenuVersion is the id of the restaurant's published menu version, empty when it has none
+func publishedMenuVersion(ctx context.Context, q sqlQueryer, restaurantID string) (string, error) {
+	var id string
+	err := q.QueryRowContext(ctx, "SELECT id FROM menu_versions WHERE restaurant_id=$1 AND status=$2", restaurantID, string(models.MenuVersionPublished)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// insertMenuVersion numbers a new version after the restaurant's latest and stores it
+func insertMenuVersion(ctx context.Context, q sqlQueryer, v *models.MenuVersion) error {
+	now := time.Now()
+	v.ID, v.CreatedAt, v.UpdatedAt = uuid.New().String(), now, now
+	return q.QueryRowContext(ctx, "INSERT INTO menu_versions (id, restaurant_id, number, status, note, based_on, rows, snapshot, created_by, created_at, updated_at) "+
+		"SELECT $1, $2, COALESCE(MAX(number), 0) + 1, $3, $4, $5, $6, $7, $8, $9, $9 FROM menu_versions WHERE restaurant_id=$2 RETURNING number",
+		v.ID, v.RestaurantID, string(v.Status), v.Note, nullIfEmpty(v.BasedOn), v.Rows, v.Snapshot, nullIfEmpty(v.CreatedBy), now).Scan(&v.Number)
+}
+
+// CreateMenuDraft starts a draft from another of the restaurant's versions, or from the live
+// menu when basedOn is empty.
+func (s *MenuSQLService) CreateMenuDraft(ctx context.Context, restaurantID, basedOn, note, userID string) (*models.MenuVersion, error) {
+	v := &models.MenuVersion{RestaurantID: restaurantID, Status: models.MenuVersionDraft, Note: note, BasedOn: basedOn, CreatedBy: userID}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := lockMenu(ctx, tx, restaurantID); err != nil {
+		return nil, err
+	}
+	if basedOn != "" {
+		src, err := getMenuVersion(ctx, tx, basedOn, false)
+		if err != nil {
+			return nil, err
+		}
+		if src.RestaurantID != restaurantID {
+			return nil, errors.New("version belongs to another restaurant")
+		}
+		v.Rows, v.Snapshot = src.Rows, src.Snapshot
+	} else if v.Rows, v.Snapshot, err = (&MenuSQLService{db: s.db, draft: tx}).readMenu(ctx, restaurantID); err != nil {
+		return nil, err
+	}
+	if err := insertMenuVersion(ctx, tx, v); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *MenuSQLService) GetMenuVersion(ctx context.Context, id string) (*models.MenuVersion, error) {
+	return getMenuVersion(ctx, s.db, id, false)
+}
+
+// ListMenuVersions lists a restaurant's versions, newest first, without their rows
+func (s *MenuSQLService) ListMenuVersions(ctx context.Context, restaurantID string) ([]models.MenuVersion, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+menuVersionColumns+" FROM menu_versions WHERE restaurant_id=$1 ORDER BY number DESC", restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.MenuVersion{}
+	for rows.Next() {
+		v, err := scanMenuVersion(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+// UpdateMenuDraft sets a draft's menu to rows, as an import followed by removing what the rows
+// leave out would, and replaces its note; other versions are fixed. The draft's modifier groups,
+// combos, branch prices and translations stay, less those of removed entries.
+func (s *MenuSQLService) UpdateMenuDraft(ctx context.Context, id string, rows []models.MenuRow, note string) (*models.MenuVersion, error) {
+	v, err := s.GetMenuVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := lockMenu(ctx, tx, v.RestaurantID); err != nil {
+		return nil, err
+	}
+	if v, err = getMenuVersion(ctx, tx, id, true); err != nil {
+		return nil, err
+	}
+	if v.Status != models.MenuVersionDraft {
+		return nil, errors.New("only drafts can be edited")
+	}
+	v.Note = note
+	err = s.editDraft(ctx, tx, v, func(d *MenuSQLService) error {
+		res, err := syncMenu(ctx, d.conn(), v.RestaurantID, rows)
+		if err != nil {
+			return err
+		}
+		return menuRowErrors(res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteMenuVersion discards a draft, or cancels a scheduled version; published history stays
+func (s *MenuSQLService) DeleteMenuVersion(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM menu_versions WHERE id=$1 AND status IN ($2, $3)", id, string(models.MenuVersionDraft), string(models.MenuVersionScheduled))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := s.GetMenuVersion(ctx, id); err != nil {
+			return err
+		}
+		return errors.New("published versions cannot be deleted")
+	}
+	return nil
+}
+
+// PreviewMenuVersion shows the branch's menu as guests would see it at the given time with the
+// version published. The version is applied in a transaction that is always rolled back; when
+// its rows have errors only the report is returned.
+func (s *MenuSQLService) PreviewMenuVersion(ctx context.Context, id string, at time.Time, locales []string, tags MenuTagFilter) (*QRMenu, *models.MenuImportResult, error) {
+	v, err := s.GetMenuVersion(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	currency, err := s.restaurantCurrency(ctx, v.RestaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	res, err := applyMenuVersion(ctx, tx, v)
+	if err != nil || len(res.Errors) > 0 {
+		return nil, res, err
+	}
+	menu, err := branchMenu(ctx, tx, v.RestaurantID, currency, locales, tags, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	menu.VersionID = v.ID
+	return menu, res, nil
+}
+
+// PublishMenuVersion makes a draft the live menu now, or schedules it when at is in the future.
+// A version is only scheduled once it applies cleanly. When the rows of a version saved without
+// a snapshot have errors nothing changes and the report is returned.
+func (s *MenuSQLService) PublishMenuVersion(ctx context.Context, id string, at *time.Time) (*models.MenuVersion, *models.MenuImportResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	v, err := getMenuVersion(ctx, tx, id, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := lockMenu(ctx, tx, v.RestaurantID); err != nil {
+		return nil, nil, err
+	}
+	if v, err = getMenuVersion(ctx, tx, id, true); err != nil {
+		return nil, nil, err
+	}
+	if v.Status != models.MenuVersionDraft && v.Status != models.MenuVersionScheduled {
+		return nil, nil, errors.New("only drafts and scheduled versions can be published")
+	}
+	res, err := publishMenuVersion(ctx, tx, v)
+	if err != nil || len(res.Errors) > 0 {
+		return nil, res, err
+	}
+	if at != nil && at.After(time.Now()) {
+		// the dry run above proved the version applies; undo it and only record the schedule
+		if err := tx.Rollback(); err != nil {
+			return nil, nil, err
+		}
+		v.Status, v.PublishAt = models.MenuVersionScheduled, at
+		if _, err := s.db.ExecContext(ctx, "UPDATE menu_versions SET status=$1, publish_at=$2, updated_at=NOW() WHERE id=$3 AND status IN ($4, $1)",
+			string(v.Status), at, id, string(models.MenuVersionDraft)); err != nil {
+			return nil, nil, err
+		}
+		return v, res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	res.Applied = true
+	return v, res, nil
+}
+
+// RollbackMenu publishes a copy of a previously published version as a new version, so the
+// history keeps moving forward.
+func (s *MenuSQLService) RollbackMenu(ctx context.Context, id, userID string) (*models.MenuVersion, *models.MenuImportResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	src, err := getMenuVersion(ctx, tx, id, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if src.PublishedAt == nil {
+		return nil, nil, errors.New("only previously published versions can be rolled back to")
+	}
+	if err := lockMenu(ctx, tx, src.RestaurantID); err != nil {
+		return nil, nil, err
+	}
+	v := &models.MenuVersion{RestaurantID: src.RestaurantID, Status: models.MenuVersionDraft, BasedOn: src.ID, Rows: src.Rows, Snapshot: src.Snapshot,
+		CreatedBy: userID, Note: "Rollback to version " + strconv.Itoa(src.Number)}
+	if err := insertMenuVersion(ctx, tx, v); err != nil {
+		return nil, nil, err
+	}
+	res, err := publishMenuVersion(ctx, tx, v)
+	if err != nil || len(res.Errors) > 0 {
+		return nil, res, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	res.Applied = true
+	return v, res, nil
+}
+
+// PublishDueMenuVersions publishes the scheduled versions whose time has come, oldest first.
+// A version that no longer applies stays scheduled and its error is returned with the others'.
+func (s *MenuSQLService) PublishDueMenuVersions(ctx context.Context, now time.Time) ([]models.MenuVersion, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM menu_versions WHERE status=$1 AND publish_at <= $2 ORDER BY publish_at ASC", string(models.MenuVersionScheduled), now)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var published []models.MenuVersion
+	var errs []error
+	for _, id := range ids {
+		v, res, err := s.PublishMenuVersion(ctx, id, nil)
+		if err == nil && len(res.Errors) > 0 {
+			err = errors.New("menu version " + id + " has " + strconv.Itoa(len(res.Errors)) + " invalid rows")
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		published = append(published, *v)
+	}
+	return published, errors.Join(errs...)
+}
+
+// publishMenuVersion makes the live menu v's and marks v published in place of the
+// restaurant's previous version
+func publishMenuVersion(ctx context.Context, tx *sql.Tx, v *models.MenuVersion) (*models.MenuImportResult, error) {
+	res, err := applyMenuVersion(ctx, tx, v)
+	if err != nil || len(res.Errors) > 0 {
+		return res, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE menu_versions SET status=$1, updated_at=NOW() WHERE restaurant_id=$2 AND status=$3",
+		string(models.MenuVersionArchived), v.RestaurantID, string(models.MenuVersionPublished)); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	v.Status, v.PublishedAt, v.UpdatedAt = models.MenuVersionPublished, &now, now
+	if _, err := tx.ExecContext(ctx, "UPDATE menu_versions SET status=$1, published_at=$2, updated_at=$2 WHERE id=$3", string(v.Status), now, v.ID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// syncMenu makes the restaurant's own menu match import rows
+func syncMenu(ctx context.Context, q sqlQueryer, restaurantID string, rows []models.MenuRow) (*models.MenuImportResult, error) {
+	imp, err := newMenuImport(ctx, q, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := imp.apply(ctx, q, rows); err != nil {
+		return nil, err
+	}
+	if len(imp.res.Errors) > 0 {
+		return imp.res, nil
+	}
+	return imp.res, imp.prune(ctx, q)
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seedMenu adds branches r1 and r2 and gives r1 a live menu of one item, Tibs at 250 in Mains
+func seedMenu(t *testing.T, db *sql.DB, s *MenuSQLService) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, "INSERT INTO restaurants (id, name) VALUES ('r1', 'Bole'), ('r2', 'Piassa')")
+	assert.NoError(t, err)
+	assert.NoError(t, s.CreateMenuCategory(ctx, &models.MenuCategory{ID: "c1", RestaurantID: "r1", Name: "Mains"}))
+	assert.NoError(t, s.CreateItem(ctx, &models.MenuItem{ID: "i1", CategoryID: "c1", Name: "Tibs", Price: 250, Available: true}))
+}
+
+func livePrice(t *testing.T, s *MenuSQLService) float64 {
+	t.Helper()
+	it, err := s.GetItem(context.Background(), "i1")
+	assert.NoError(t, err)
+	return it.Price
+}
+
+// repriceDraft sets Tibs to price in r1's working draft
+func repriceDraft(t *testing.T, s *MenuSQLService, price string) *models.MenuVersion {
+	t.Helper()
+	v, err := s.EditEntryDraft(context.Background(), MenuEntryItem, "i1", "u1", func(d *MenuSQLService) error {
+		_, err := d.PatchItem(context.Background(), "i1", []byte(`{"price":`+price+`}`))
+		return err
+	})
+	assert.NoError(t, err)
+	return v
+}
+
+func TestMenuDraftEdits(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+
+	branchPrice := 280.0
+	v, err := s.EditEntryDraft(ctx, MenuEntryItem, "i1", "u1", func(d *MenuSQLService) error {
+		if _, err := d.PatchItem(ctx, "i1", []byte(`{"price":300}`)); err != nil {
+			return err
+		}
+		if err := d.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "i1", Price: &branchPrice}); err != nil {
+			return err
+		}
+		if err := d.CreateModifierGroup(ctx, &models.ModifierGroup{ID: "g1", ItemID: "i1", Name: "Spice", MaxSelect: 1}); err != nil {
+			return err
+		}
+		if err := d.CreateCombo(ctx, &models.Combo{ID: "k1", RestaurantID: "r1", Name: "Lunch", Price: 320, Available: true,
+			Slots: []models.ComboSlot{{Name: "Main", Choices: []models.ComboChoice{{MenuItemID: "i1", IsDefault: true}}}}}); err != nil {
+			return err
+		}
+		_, err := d.SetTranslations(ctx, []models.MenuTranslation{{EntityType: models.TranslateItem, EntityID: "i1", Field: "name", Locale: "am", Value: "ጥብስ"}})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.MenuVersionDraft, v.Status)
+	assert.Equal(t, 1, v.Number)
+	for _, table := range []string{"menu_item_overrides", "modifier_groups", "menu_combos", "combo_slots", "combo_slot_items", "menu_translations"} {
+		assert.Len(t, v.Snapshot[table], 1, table)
+	}
+
+	// nothing is live yet
+	assert.Equal(t, 250.0, livePrice(t, s))
+	overrides, err := s.ListItemOverrides(ctx, "r1")
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+	groups, err := s.ListModifierGroups(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+	combos, err := s.ListCombos(ctx, "r1", false)
+	assert.NoError(t, err)
+	assert.Empty(t, combos)
+	translations, err := s.ListTranslations(ctx, models.TranslateItem, "i1")
+	assert.NoError(t, err)
+	assert.Empty(t, translations)
+
+	// entries only the draft has are found there, and edits keep going into the same draft
+	again, err := s.EditEntryDraft(ctx, MenuEntryModifierGroup, "g1", "u1", func(d *MenuSQLService) error {
+		return d.UpdateModifierGroup(ctx, &models.ModifierGroup{ID: "g1", Name: "Heat", MaxSelect: 1})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, v.ID, again.ID)
+	if assert.Len(t, again.Snapshot["modifier_groups"], 1) && assert.Len(t, again.Snapshot["menu_items"], 1) {
+		assert.Equal(t, "Heat", again.Snapshot["modifier_groups"][0]["name"])
+		assert.EqualValues(t, 300, again.Snapshot["menu_items"][0]["price"])
+	}
+	_, err = s.EntryRestaurant(ctx, MenuEntryModifierGroup, "missing")
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+
+	// a failed edit leaves the draft as it was
+	_, err = s.EditEntryDraft(ctx, MenuEntryItem, "i1", "u1", func(d *MenuSQLService) error {
+		return d.DeleteModifierGroup(ctx, "missing")
+	})
+	assert.Error(t, err)
+	got, err := s.GetMenuVersion(ctx, v.ID)
+	assert.NoError(t, err)
+	assert.Len(t, got.Snapshot["modifier_groups"], 1)
+}
+
+func TestPublishMenuVersion(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+
+	branchPrice := 280.0
+	v, err := s.EditMenuDraft(ctx, "r1", "u1", func(d *MenuSQLService) error {
+		if _, err := d.PatchItem(ctx, "i1", []byte(`{"price":300}`)); err != nil {
+			return err
+		}
+		if err := d.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "i1", Price: &branchPrice}); err != nil {
+			return err
+		}
+		if err := d.CreateModifierGroup(ctx, &models.ModifierGroup{ID: "g1", ItemID: "i1", Name: "Spice", MaxSelect: 1}); err != nil {
+			return err
+		}
+		if err := d.CreateModifierOption(ctx, &models.ModifierOption{ID: "o1", GroupID: "g1", Name: "Hot"}); err != nil {
+			return err
+		}
+		// a group nested under an option of the draft
+		if err := d.CreateModifierGroup(ctx, &models.ModifierGroup{ID: "g2", ParentOptionID: "o1", Name: "How hot", MaxSelect: 1}); err != nil {
+			return err
+		}
+		if err := d.CreateCombo(ctx, &models.Combo{ID: "k1", RestaurantID: "r1", Name: "Lunch", Price: 320, Available: true,
+			Slots: []models.ComboSlot{{Name: "Main", Choices: []models.ComboChoice{{MenuItemID: "i1", IsDefault: true}}}}}); err != nil {
+			return err
+		}
+		_, err := d.SetTranslations(ctx, []models.MenuTranslation{{EntityType: models.TranslateItem, EntityID: "i1", Field: "name", Locale: "am", Value: "ጥብስ"}})
+		return err
+	})
+	assert.NoError(t, err)
+
+	published, res, err := s.PublishMenuVersion(ctx, v.ID, nil)
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.Equal(t, models.MenuVersionPublished, published.Status)
+
+	assert.Equal(t, 300.0, livePrice(t, s))
+	overrides, err := s.ListItemOverrides(ctx, "r1")
+	assert.NoError(t, err)
+	if assert.Len(t, overrides, 1) {
+		assert.Equal(t, branchPrice, *overrides[0].Price)
+	}
+	groups, err := s.ListModifierGroups(ctx, "i1")
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) && assert.Len(t, groups[0].Options, 1) && assert.Len(t, groups[0].Options[0].Groups, 1) {
+		assert.Equal(t, "How hot", groups[0].Options[0].Groups[0].Name)
+	}
+	combo, err := s.GetCombo(ctx, "k1")
+	if assert.NoError(t, err) && assert.Len(t, combo.Slots, 1) && assert.Len(t, combo.Slots[0].Choices, 1) {
+		assert.Equal(t, "Tibs", combo.Slots[0].Choices[0].Name)
+	}
+	translations, err := s.ListTranslations(ctx, models.TranslateItem, "i1")
+	assert.NoError(t, err)
+	if assert.Len(t, translations, 1) {
+		assert.Equal(t, "ጥብስ", translations[0].Value)
+	}
+
+	// the next change starts a new draft from the published menu
+	next := repriceDraft(t, s, "310")
+	assert.NotEqual(t, v.ID, next.ID)
+	assert.Len(t, next.Snapshot["modifier_groups"], 2)
+	assert.Equal(t, 300.0, livePrice(t, s))
+}
+
+func TestScheduledMenuVersion(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	v := repriceDraft(t, s, "300")
+
+	at := time.Now().UTC().Add(time.Hour)
+	scheduled, res, err := s.PublishMenuVersion(ctx, v.ID, &at)
+	assert.NoError(t, err)
+	assert.False(t, res.Applied)
+	assert.Equal(t, models.MenuVersionScheduled, scheduled.Status)
+	assert.Equal(t, 250.0, livePrice(t, s))
+
+	due, err := s.PublishDueMenuVersions(ctx, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+	assert.Equal(t, 250.0, livePrice(t, s))
+
+	due, err = s.PublishDueMenuVersions(ctx, at.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, v.ID, due[0].ID)
+		assert.Equal(t, models.MenuVersionPublished, due[0].Status)
+	}
+	assert.Equal(t, 300.0, livePrice(t, s))
+}
+
+func TestRollbackMenu(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+
+	first, err := s.CreateMenuDraft(ctx, "r1", "", "", "u1")
+	assert.NoError(t, err)
+	_, _, err = s.PublishMenuVersion(ctx, first.ID, nil)
+	assert.NoError(t, err)
+
+	second, err := s.EditMenuDraft(ctx, "r1", "u1", func(d *MenuSQLService) error {
+		if _, err := d.PatchItem(ctx, "i1", []byte(`{"price":300}`)); err != nil {
+			return err
+		}
+		return d.CreateModifierGroup(ctx, &models.ModifierGroup{ID: "g1", ItemID: "i1", Name: "Spice", MaxSelect: 1})
+	})
+	assert.NoError(t, err)
+	_, _, err = s.PublishMenuVersion(ctx, second.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 300.0, livePrice(t, s))
+
+	rolled, res, err := s.RollbackMenu(ctx, first.ID, "u1")
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.Equal(t, 3, rolled.Number)
+	assert.Equal(t, first.ID, rolled.BasedOn)
+	assert.Equal(t, 250.0, livePrice(t, s))
+	groups, err := s.ListModifierGroups(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+
+	versions, err := s.ListMenuVersions(ctx, "r1")
+	assert.NoError(t, err)
+	status := map[int]models.MenuVersionStatus{}
+	for _, v := range versions {
+		status[v.Number] = v.Status
+	}
+	assert.Equal(t, map[int]models.MenuVersionStatus{1: models.MenuVersionArchived, 2: models.MenuVersionArchived, 3: models.MenuVersionPublished}, status)
+}
+
+func TestPublishKeepsLiveAvailability(t *testing.T) {
+	ctx := context.Background()
+	s, db := newMenuTestDB(t)
+	seedMenu(t, db, s)
+	_, err := db.ExecContext(ctx, "INSERT INTO menu_items (id, name, price, category, available) VALUES ('s1', 'Buna', 60, 'Drinks', TRUE)")
+	assert.NoError(t, err)
+
+	price := 75.0
+	v, err := s.EditMenuDraft(ctx, "r1", "u1", func(d *MenuSQLService) error {
+		if _, err := d.PatchItem(ctx, "i1", []byte(`{"price":300}`)); err != nil {
+			return err
+		}
+		return d.SetItemOverride(ctx, &models.MenuItemOverride{RestaurantID: "r1", MenuItemID: "s1", Price: &price})
+	})
+	assert.NoError(t, err)
+	// sold out after the draft was started
+	off := false
+	assert.NoError(t, s.SetItemAvailability(ctx, "i1", false))
+	assert.NoError(t, s.SetBranchAvailability(ctx, "r1", "s1", &off))
+
+	_, _, err = s.PublishMenuVersion(ctx, v.ID, nil)
+	assert.NoError(t, err)
+	it, err := s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+	assert.Equal(t, 300.0, it.Price)
+	assert.False(t, it.Available)
+	overrides, err := s.ListItemOverrides(ctx, "r1")
+	assert.NoError(t, err)
+	if assert.Len(t, overrides, 1) {
+		assert.Equal(t, &price, overrides[0].Price)
+		assert.Equal(t, &off, overrides[0].Available)
+	}
+
+	// an item brought back by a rollback is available as it was in the version
+	first, err := s.GetMenuVersion(ctx, v.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, s.SetItemAvailability(ctx, "i1", true))
+	deleted, err := s.EditEntryDraft(ctx, MenuEntryItem, "i1", "u1", func(d *MenuSQLService) error {
+		return d.DeleteItem(ctx, "i1")
+	})
+	assert.NoError(t, err)
+	_, _, err = s.PublishMenuVersion(ctx, deleted.ID, nil)
+	assert.NoError(t, err)
+	_, err = s.GetItem(ctx, "i1")
+	assert.ErrorIs(t, err, ErrMenuNotFound)
+	_, _, err = s.RollbackMenu(ctx, first.ID, "u1")
+	assert.NoError(t, err)
+	it, err = s.GetItem(ctx, "i1")
+	assert.NoError(t, err)
+	assert.True(t, it.Available)
+}
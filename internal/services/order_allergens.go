@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"restaurant-system/internal/models"
+)
+
+// AllergenWarning is returned when new lines contain allergens the customer declared and the
+// request did not acknowledge them. Nothing is written; the client shows the conflicts and
+// retries with the acknowledgement.
+type AllergenWarning struct {
+	Conflicts []models.AllergenConflict
+}
+
+func (e *AllergenWarning) Error() string {
+	return "order contains allergens the customer declared"
+}
+
+// customerAllergens returns the allergies saved on a customer's account
+func customerAllergens(ctx context.Context, q sqlQueryer, customerID string) (models.Allergens, error) {
+	var a models.Allergens
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(allergens, '[]') FROM accounts WHERE id=$1", customerID).Scan(&a)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// allergenConflicts marks each line with the declared allergens it contains and lists the
+// lines that have any
+func allergenConflicts(items []models.OrderItem, declared models.Allergens) []models.AllergenConflict {
+	var out []models.AllergenConflict
+	for i := range items {
+		hits := items[i].Allergens.Intersect(declared)
+		items[i].AllergenAlerts = hits
+		if len(hits) > 0 {
+			out = append(out, models.AllergenConflict{MenuItemID: items[i].MenuItemID, Name: items[i].Label(), Allergens: hits})
+		}
+	}
+	return out
+}
+
+// checkAllergens compares new lines against the customer's declared allergies. Conflicts are
+// rejected with an AllergenWarning unless acknowledged, in which case the acknowledgement is
+// audited and the lines keep their alerts for the kitchen.
+func checkAllergens(ctx context.Context, q sqlQueryer, orderID, customerID string, items []models.OrderItem, acknowledged bool, userID string) error {
+	if customerID == "" {
+		return nil
+	}
+	declared, err := customerAllergens(ctx, q, customerID)
+	if err != nil || len(declared) == 0 {
+		return err
+	}
+	conflicts := allergenConflicts(items, declared)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if !acknowledged {
+		return &AllergenWarning{Conflicts: conflicts}
+	}
+	return writeOrderAudit(ctx, q, orderID, "allergens_acknowledged", userID, map[string]interface{}{"conflicts": conflicts})
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllergenConflictsMarksLines(t *testing.T) {
+	items := []models.OrderItem{
+		{MenuItemID: "pasta", Name: "Pasta", Allergens: models.Allergens{models.AllergenGluten, models.AllergenMilk}},
+		{MenuItemID: "salad", Name: "Salad"},
+	}
+	conflicts := allergenConflicts(items, models.Allergens{models.AllergenMilk, models.AllergenPeanuts})
+
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "pasta", conflicts[0].MenuItemID)
+	assert.Equal(t, models.Allergens{models.AllergenMilk}, items[0].AllergenAlerts)
+	assert.Empty(t, items[1].AllergenAlerts)
+}
+
+func TestMenuTagFilter(t *testing.T) {
+	items := []models.MenuItem{
+		{ID: "a", Allergens: models.Allergens{models.AllergenMilk}, Dietary: models.DietaryTags{models.DietVegetarian}},
+		{ID: "b", Dietary: models.DietaryTags{models.DietVegetarian, models.DietVegan}},
+		{ID: "c", Allergens: models.Allergens{models.AllergenFish}},
+	}
+
+	flagged := MenuTagFilter{Allergens: models.Allergens{models.AllergenMilk}}.apply(append([]models.MenuItem{}, items...))
+	assert.Len(t, flagged, 3)
+	assert.Equal(t, models.Allergens{models.AllergenMilk}, flagged[0].AllergenWarnings)
+
+	hidden := MenuTagFilter{Allergens: models.Allergens{models.AllergenMilk}, HideAllergens: true, Dietary: models.DietaryTags{models.DietVegetarian}}.
+		apply(append([]models.MenuItem{}, items...))
+	assert.Len(t, hidden, 1)
+	assert.Equal(t, "b", hidden[0].ID)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+var (
+	// ErrCourseHeld is returned when the kitchen tries to work a ticket whose course is on hold.
+	ErrCourseHeld = errors.New("course is on hold")
+	// ErrCourseStarted is returned when holding a course the kitchen has already started.
+	ErrCourseStarted = errors.New("course has already started")
+)
+
+// courseFiring decides which courses of newly added lines go to the kitchen straight away.
+// Non-dine-in orders and lines without a course always fire. On a dine-in order a course fires
+// if it is no later than the latest course already fired, or, before anything has been fired,
+// if it is the earliest course on the order; later courses are held for the waiter to fire.
+func courseFiring(ctx context.Context, q sqlQueryer, orderID string, orderType models.OrderType, items []models.OrderItem) (func(models.Course) bool, error) {
+	if orderType != models.OrderTypeDineIn {
+		return func(models.Course) bool { return true }, nil
+	}
+	rows, err := q.QueryContext(ctx, "SELECT DISTINCT COALESCE(course, ''), fired_at IS NOT NULL FROM kitchen_tickets WHERE order_id=$1", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fired, first := 0, 0
+	for rows.Next() {
+		var c models.Course
+		var isFired bool
+		if err := rows.Scan(&c, &isFired); err != nil {
+			return nil, err
+		}
+		if isFired && c.Rank() > fired {
+			fired = c.Rank()
+		}
+		if r := c.Rank(); r > 0 && (first == 0 || r < first) {
+			first = r
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	current := fired
+	if current == 0 {
+		for _, it := range items {
+			if r := it.Course.Rank(); r > 0 && (first == 0 || r < first) {
+				first = r
+			}
+		}
+		current = first
+	}
+	return func(c models.Course) bool { return c.Rank() <= current }, nil
+}
+
+// lockCourseOrder locks an open order for a course change
+func lockCourseOrder(ctx context.Context, q sqlQueryer, orderID string, course models.Course) error {
+	if course == "" || !course.IsValid() {
+		return errors.New("unknown course " + string(course))
+	}
+	var st models.OrderStatus
+	err := q.QueryRowContext(ctx, "SELECT status FROM orders WHERE id=$1 FOR UPDATE", orderID).Scan(&st)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !isOpenStatus(st) {
+		return ErrOrderNotOpen
+	}
+	return nil
+}
+
+// FireCourse sends a held course to its stations and returns the tickets that were fired.
+func (s *OrderSQLService) FireCourse(ctx context.Context, orderID string, course models.Course, userID string) ([]models.KitchenTicket, error) {
+	return s.changeCourse(ctx, orderID, course, userID, "course_fired", nil,
+		"UPDATE kitchen_tickets SET fired_at=$3, updated_at=$3 WHERE order_id=$1 AND course=$2 AND fired_at IS NULL RETURNING id")
+}
+
+// HoldCourse takes a fired course back off the stations, as long as none of its tickets has
+// been started. It returns the tickets that were held.
+func (s *OrderSQLService) HoldCourse(ctx context.Context, orderID string, course models.Course, userID string) ([]models.KitchenTicket, error) {
+	notStarted := func(tx *sql.Tx) error {
+		var started int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM kitchen_tickets WHERE order_id=$1 AND course=$2 AND status<>$3",
+			orderID, string(course), string(models.TicketQueued)).Scan(&started); err != nil {
+			return err
+		}
+		if started > 0 {
+			return ErrCourseStarted
+		}
+		return nil
+	}
+	return s.changeCourse(ctx, orderID, course, userID, "course_held", notStarted,
+		"UPDATE kitchen_tickets SET fired_at=NULL, updated_at=$3 WHERE order_id=$1 AND course=$2 AND fired_at IS NOT NULL RETURNING id")
+}
+
+// changeCourse runs a fire or hold update over a course's tickets once check passes, audits it
+// and reloads the changed tickets
+func (s *OrderSQLService) changeCourse(ctx context.Context, orderID string, course models.Course, userID, action string, check func(tx *sql.Tx) error, update string) ([]models.KitchenTicket, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = lockCourseOrder(ctx, tx, orderID, course); err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err = check(tx); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := tx.QueryContext(ctx, update, orderID, string(course), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		err = errors.New("no " + string(course) + " tickets to change")
+		return nil, err
+	}
+	if err = writeOrderAudit(ctx, tx, orderID, action, userID, map[string]interface{}{"course": course, "tickets": ids}); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	tickets := make([]models.KitchenTicket, 0, len(ids))
+	for _, id := range ids {
+		t, err := s.GetTicket(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, *t)
+	}
+	return tickets, nil
+}
+
+// GetFiredOrderItems returns the lines the kitchen should see: those on fired tickets, plus
+// lines from before ticketing that have none.
+func (s *OrderSQLService) GetFiredOrderItems(ctx context.Context, orderID string) ([]models.OrderItem, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+orderItemColumns+" FROM order_items WHERE order_id=$1 AND "+
+		"(ticket_id IS NULL OR ticket_id IN (SELECT id FROM kitchen_tickets WHERE fired_at IS NOT NULL))", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []models.OrderItem
+	for rows.Next() {
+		it, err := scanOrderItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *it)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+const (
+	// DefaultPrepTime is assumed for menu items with no prep history yet.
+	DefaultPrepTime = 15 * time.Minute
+	// defaultKitchenCapacity applies to orders without a restaurant
+	defaultKitchenCapacity = 4
+	// prepProfileWindow caps how many past orders weigh on an item's prep time so it keeps adapting
+	prepProfileWindow = 50
+	// etaTolerance is the smallest change worth storing and announcing
+	etaTolerance = time.Minute
+	// minRemainingPrep keeps overdue orders from being estimated as ready in the past
+	minRemainingPrep = time.Minute
+)
+
+// stampStatusTime records when an order entered a status in the given column
+func stampStatusTime(column string) TransitionHook {
+	return func(ctx context.Context, q sqlQueryer, orderID string) error {
+		_, err := q.ExecContext(ctx, "UPDATE orders SET "+column+"=$1 WHERE id=$2", time.Now(), orderID)
+		return err
+	}
+}
+
+// stampFirstStatusTime records when an order first entered a status, keeping an earlier time
+func stampFirstStatusTime(column string) TransitionHook {
+	return func(ctx context.Context, q sqlQueryer, orderID string) error {
+		_, err := q.ExecContext(ctx, "UPDATE orders SET "+column+"=COALESCE("+column+", $1) WHERE id=$2", time.Now(), orderID)
+		return err
+	}
+}
+
+// learnPrepTimes folds an order's confirmed-to-ready duration into the prep profile of every
+// item on it. Items share the order's duration since they are prepared side by side. It runs
+// before ready_at is stamped and learns only the first time an order is ready.
+func learnPrepTimes(ctx context.Context, q sqlQueryer, orderID string) error {
+	var confirmedAt, readyAt sql.NullTime
+	if err := q.QueryRowContext(ctx, "SELECT confirmed_at, ready_at FROM orders WHERE id=$1", orderID).Scan(&confirmedAt, &readyAt); err != nil {
+		return err
+	}
+	now := time.Now()
+	if readyAt.Valid || !confirmedAt.Valid || !now.After(confirmedAt.Time) {
+		return nil
+	}
+	secs := now.Sub(confirmedAt.Time).Seconds()
+	_, err := q.ExecContext(ctx, "INSERT INTO menu_item_prep_profiles (menu_item_id, avg_seconds, samples, updated_at) "+
+		"SELECT DISTINCT menu_item_id, $2::float8, 1, $3::timestamptz FROM order_items WHERE order_id=$1 AND voided_at IS NULL AND menu_item_id IS NOT NULL "+
+		"ON CONFLICT (menu_item_id) DO UPDATE SET "+
+		"avg_seconds = (menu_item_prep_profiles.avg_seconds * LEAST(menu_item_prep_profiles.samples, $4) + EXCLUDED.avg_seconds) / (LEAST(menu_item_prep_profiles.samples, $4) + 1), "+
+		"samples = menu_item_prep_profiles.samples + 1, updated_at = EXCLUDED.updated_at",
+		orderID, secs, now, prepProfileWindow)
+	return err
+}
+
+// orderPrepTime is the longest learned prep time among the order's live lines
+func orderPrepTime(ctx context.Context, q sqlQueryer, orderID string) (time.Duration, error) {
+	def := DefaultPrepTime.Seconds()
+	var secs float64
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(MAX(COALESCE(p.avg_seconds, $2)), $2) FROM order_items oi "+
+		"LEFT JOIN menu_item_prep_profiles p ON p.menu_item_id=oi.menu_item_id WHERE oi.order_id=$1 AND oi.voided_at IS NULL AND oi.menu_item_id IS NOT NULL", orderID, def).Scan(&secs)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// queuedOrder is an order waiting for, or holding, a kitchen slot
+type queuedOrder struct {
+	ID   string
+	Prep time.Duration
+	// StartedAt is set once the order is being prepared
+	StartedAt *time.Time
+}
+
+// scheduleQueue estimates when each order will be ready if the kitchen works on at most slots
+// orders at once. Orders in preparation hold a slot for what is left of their prep time; the
+// rest start in queue order as slots free up.
+func scheduleQueue(now time.Time, slots int, queue []queuedOrder) map[string]time.Time {
+	if slots < 1 {
+		slots = 1
+	}
+	free := make([]time.Time, slots)
+	for i := range free {
+		free[i] = now
+	}
+	etas := make(map[string]time.Time, len(queue))
+	place := func(o queuedOrder, d time.Duration) {
+		next := 0
+		for i := range free {
+			if free[i].Before(free[next]) {
+				next = i
+			}
+		}
+		free[next] = free[next].Add(d)
+		etas[o.ID] = free[next]
+	}
+	for _, o := range queue {
+		if o.StartedAt == nil {
+			continue
+		}
+		left := o.Prep - now.Sub(*o.StartedAt)
+		if left < minRemainingPrep {
+			left = minRemainingPrep
+		}
+		place(o, left)
+	}
+	for _, o := range queue {
+		if o.StartedAt == nil {
+			place(o, o.Prep)
+		}
+	}
+	return etas
+}
+
+func kitchenCapacity(ctx context.Context, q sqlQueryer, restaurantID string) (int, error) {
+	if restaurantID == "" {
+		return defaultKitchenCapacity, nil
+	}
+	var n int
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(kitchen_capacity, 0) FROM restaurants WHERE id=$1", restaurantID).Scan(&n)
+	if err == sql.ErrNoRows || (err == nil && n < 1) {
+		return defaultKitchenCapacity, nil
+	}
+	return n, err
+}
+
+// RefreshETAs re-estimates ready times for the restaurant's kitchen queue (confirmed and
+// preparing orders) and returns the orders whose ETA moved. Manually set ETAs are kept but
+// the orders still take up kitchen time.
+func (s *OrderSQLService) RefreshETAs(ctx context.Context, restaurantID string) ([]*models.Order, error) {
+	capacity, err := kitchenCapacity(ctx, s.db, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT id, status, preparing_at, estimated_ready_at, COALESCE(eta_manual, FALSE) FROM orders "+
+		"WHERE COALESCE(restaurant_id, '')=$1 AND status IN ($2,$3) AND (split_type IS NULL OR split_type <> $4) "+
+		"ORDER BY confirmed_at NULLS LAST, created_at",
+		restaurantID, string(models.OrderStatusConfirmed), string(models.OrderStatusPreparing), string(models.SplitEvenly))
+	if err != nil {
+		return nil, err
+	}
+	type current struct {
+		eta    *time.Time
+		manual bool
+	}
+	now := time.Now()
+	var queue []queuedOrder
+	known := map[string]current{}
+	for rows.Next() {
+		var o queuedOrder
+		var st models.OrderStatus
+		var startedAt, eta sql.NullTime
+		var cur current
+		if err := rows.Scan(&o.ID, &st, &startedAt, &eta, &cur.manual); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if st == models.OrderStatusPreparing {
+			o.StartedAt = &now
+			if startedAt.Valid {
+				o.StartedAt = &startedAt.Time
+			}
+		}
+		if eta.Valid {
+			cur.eta = &eta.Time
+		}
+		queue = append(queue, o)
+		known[o.ID] = cur
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range queue {
+		if queue[i].Prep, err = orderPrepTime(ctx, s.db, queue[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []*models.Order
+	etas := scheduleQueue(now, capacity, queue)
+	for _, o := range queue {
+		id, eta := o.ID, etas[o.ID]
+		cur := known[id]
+		if cur.manual {
+			continue
+		}
+		if cur.eta != nil {
+			if d := eta.Sub(*cur.eta); d < etaTolerance && d > -etaTolerance {
+				continue
+			}
+		}
+		if _, err := s.db.ExecContext(ctx, "UPDATE orders SET estimated_ready_at=$1 WHERE id=$2 AND NOT COALESCE(eta_manual, FALSE)", eta, id); err != nil {
+			return nil, err
+		}
+		ord, err := s.GetOrder(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, ord)
+	}
+	return changed, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleQueueFillsFreeSlots(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	etas := scheduleQueue(now, 2, []queuedOrder{
+		{ID: "a", Prep: 10 * time.Minute},
+		{ID: "b", Prep: 20 * time.Minute},
+		{ID: "c", Prep: 5 * time.Minute},
+	})
+
+	assert.Equal(t, now.Add(10*time.Minute), etas["a"])
+	assert.Equal(t, now.Add(20*time.Minute), etas["b"])
+	// c waits for a's slot
+	assert.Equal(t, now.Add(15*time.Minute), etas["c"])
+}
+
+func TestScheduleQueueStartedOrdersGoFirst(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	started := now.Add(-8 * time.Minute)
+	overdue := now.Add(-time.Hour)
+	etas := scheduleQueue(now, 1, []queuedOrder{
+		{ID: "waiting", Prep: 10 * time.Minute},
+		{ID: "cooking", Prep: 10 * time.Minute, StartedAt: &started},
+		{ID: "late", Prep: 10 * time.Minute, StartedAt: &overdue},
+	})
+
+	assert.Equal(t, now.Add(2*time.Minute), etas["cooking"])
+	assert.Equal(t, now.Add(3*time.Minute), etas["late"])
+	assert.Equal(t, now.Add(13*time.Minute), etas["waiting"])
+}
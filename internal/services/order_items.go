@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// orderItemColumns is the select list understood by scanOrderItem
+const orderItemColumns = "id, order_id, COALESCE(menu_item_id, ''), name, price, quantity, total_price, COALESCE(special_instructions, ''), COALESCE(variant_id, ''), COALESCE(variant_name, ''), COALESCE(variant_price_delta, 0), COALESCE(addons, '[]'), COALESCE(modifiers, '[]'), " +
+	"voided_at, COALESCE(void_reason, ''), COALESCE(ticket_id, ''), COALESCE(course, ''), COALESCE(allergens, '[]'), COALESCE(allergen_alerts, '[]'), " +
+	"COALESCE(combo_id, ''), COALESCE(parent_item_id, ''), COALESCE(combo_slot, ''), COALESCE(allocated_total, 0)"
+
+func scanOrderItem(row rowScanner) (*models.OrderItem, error) {
+	var it models.OrderItem
+	var addons, modifiers []byte
+	var voidedAt sql.NullTime
+	if err := row.Scan(&it.ID, &it.OrderID, &it.MenuItemID, &it.Name, &it.Price, &it.Quantity, &it.TotalPrice, &it.SpecialInstructions,
+		&it.VariantID, &it.VariantName, &it.VariantPriceDelta, &addons, &modifiers, &voidedAt, &it.VoidReason, &it.TicketID, &it.Course, &it.Allergens, &it.AllergenAlerts,
+		&it.ComboID, &it.ParentItemID, &it.ComboSlot, &it.AllocatedTotal); err != nil {
+		return nil, err
+	}
+	if voidedAt.Valid {
+		it.VoidedAt = &voidedAt.Time
+	}
+	if err := json.Unmarshal(addons, &it.Addons); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(modifiers, &it.Modifiers); err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+func loadOrderItems(ctx context.Context, q sqlQueryer, orderID string) ([]models.OrderItem, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+orderItemColumns+" FROM order_items WHERE order_id=$1", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []models.OrderItem
+	for rows.Next() {
+		it, err := scanOrderItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *it)
+	}
+	return items, rows.Err()
+}
+
+func insertOrderItem(ctx context.Context, q sqlQueryer, oi models.OrderItem) error {
+	addons, err := json.Marshal(oi.Addons)
+	if err != nil {
+		return err
+	}
+	modifiers, err := json.Marshal(oi.Modifiers)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price, special_instructions, variant_id, variant_name, variant_price_delta, addons, modifiers, ticket_id, course, allergens, allergen_alerts, combo_id, parent_item_id, combo_slot, allocated_total) "+
+		"VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21)",
+		oi.ID, oi.OrderID, nullIfEmpty(oi.MenuItemID), oi.Name, oi.Price, oi.Quantity, oi.TotalPrice, oi.SpecialInstructions,
+		nullIfEmpty(oi.VariantID), oi.VariantName, oi.VariantPriceDelta, string(addons), string(modifiers), nullIfEmpty(oi.TicketID), nullIfEmpty(string(oi.Course)),
+		oi.Allergens, oi.AllergenAlerts, nullIfEmpty(oi.ComboID), nullIfEmpty(oi.ParentItemID), nullIfEmpty(oi.ComboSlot), oi.AllocatedTotal)
+	return err
+}
+
+// menuTime is when an order's lines must be on the menu: the pickup time for pre-orders,
+// otherwise now
+func menuTime(now time.Time, pickupAt *time.Time) time.Time {
+	if pickupAt != nil && pickupAt.After(now) {
+		return *pickupAt
+	}
+	return now
+}
+
+// priceOrderLine builds an order line from the restaurant's menu as served at the given time,
+// validating that the chosen variant, add-ons and modifiers belong to the item and snapshotting
+// their names, price deltas and allergens. An order without a restaurant can only have the
+// shared items.
+func priceOrderLine(ctx context.Context, q sqlQueryer, orderID, restaurantID string, at time.Time, it CreateOrderItemReq) (*models.OrderItem, error) {
+	if it.Quantity < 1 {
+		return nil, errors.New("quantity must be at least 1")
+	}
+	if !it.Course.IsValid() {
+		return nil, errors.New("unknown course " + string(it.Course))
+	}
+	// menu prices are still held in major units; convert once here
+	var mi models.MenuItem
+	var categorySchedule string
+	// the branch's override, if any, decides price and availability
+	if err := q.QueryRowContext(ctx, "SELECT m.id, m.name, COALESCE(o.price, m.price), COALESCE(m.allergens, '[]'), COALESCE(m.schedule_id, ''), COALESCE(c.schedule_id, '') FROM menu_items m "+branchMenuJoin+
+		" LEFT JOIN menu_categories c ON c.id = m.category_id AND c.deleted_at IS NULL"+
+		" WHERE m.id=$2 AND COALESCE(o.available, m.available, FALSE) AND m.deleted_at IS NULL "+
+		"AND (m.restaurant_id IS NULL OR m.restaurant_id=$1)", restaurantID, it.MenuItemID).
+		Scan(&mi.ID, &mi.Name, &mi.Price, &mi.Allergens, &mi.ScheduleID, &categorySchedule); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("menu item " + it.MenuItemID + " is not available")
+		}
+		return nil, err
+	}
+	if mi.ScheduleID != "" || categorySchedule != "" {
+		clock, err := loadMenuClock(ctx, q, restaurantID, at)
+		if err != nil {
+			return nil, err
+		}
+		if !clock.open(mi.ScheduleID, categorySchedule) {
+			return nil, errors.New("menu item " + it.MenuItemID + " is not served at this time")
+		}
+	}
+
+	oi := &models.OrderItem{
+		ID: uuid.New().String(), OrderID: orderID, MenuItemID: mi.ID, Name: mi.Name,
+		Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions, Course: it.Course, Addons: []models.OrderItemAddon{},
+		Allergens: mi.Allergens.Union(nil),
+	}
+	unit := models.MoneyFromFloat(mi.Price)
+
+	if it.VariantID != "" {
+		var delta float64
+		var allergens models.Allergens
+		err := q.QueryRowContext(ctx, "SELECT name, price_delta, COALESCE(allergens, '[]') FROM menu_variants WHERE id=$1 AND item_id=$2", it.VariantID, mi.ID).
+			Scan(&oi.VariantName, &delta, &allergens)
+		if err == sql.ErrNoRows {
+			return nil, errors.New("variant " + it.VariantID + " does not belong to menu item " + mi.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		oi.VariantID = it.VariantID
+		oi.VariantPriceDelta = models.MoneyFromFloat(delta)
+		oi.Allergens = oi.Allergens.Union(allergens)
+		unit += oi.VariantPriceDelta
+	}
+
+	seen := map[string]bool{}
+	for _, addonID := range it.AddonIDs {
+		if seen[addonID] {
+			return nil, errors.New("add-on " + addonID + " selected twice")
+		}
+		seen[addonID] = true
+		a := models.OrderItemAddon{ID: addonID}
+		var delta float64
+		var allergens models.Allergens
+		err := q.QueryRowContext(ctx, "SELECT name, price_delta, COALESCE(allergens, '[]') FROM menu_addons WHERE id=$1 AND item_id=$2", addonID, mi.ID).
+			Scan(&a.Name, &delta, &allergens)
+		if err == sql.ErrNoRows {
+			return nil, errors.New("add-on " + addonID + " does not belong to menu item " + mi.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		a.PriceDelta = models.MoneyFromFloat(delta)
+		oi.Addons = append(oi.Addons, a)
+		oi.Allergens = oi.Allergens.Union(allergens)
+		unit += a.PriceDelta
+	}
+
+	groups, err := loadModifierGroups(ctx, q, "item_id = $1", mi.ID)
+	if err != nil {
+		return nil, err
+	}
+	mods, allergens, err := resolveModifiers(groups[mi.ID], it.Modifiers, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mods {
+		unit += m.Charge()
+	}
+	oi.Modifiers = mods
+	oi.Allergens = oi.Allergens.Union(allergens)
+
+	oi.Price = unit
+	oi.TotalPrice = unit.Times(it.Quantity)
+	return oi, nil
+}
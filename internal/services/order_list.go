@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+const (
+	defaultOrderPageSize = 50
+	maxOrderPageSize     = 200
+)
+
+// ErrInvalidCursor is returned for a page cursor that was not produced by ListOrders.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter narrows ListOrders and SummarizeOrders; zero fields match everything.
+// From is inclusive and To exclusive, both on created_at.
+type OrderFilter struct {
+	Statuses     []models.OrderStatus
+	Type         models.OrderType
+	CustomerID   string
+	SessionID    string
+	RestaurantID string
+	// OrderNumber and BusinessDate (YYYY-MM-DD) look up tickets; numbers repeat every day
+	OrderNumber  int
+	BusinessDate string
+	From         *time.Time
+	To           *time.Time
+	// DueBy hides scheduled pickups whose pickup time is after it
+	DueBy *time.Time
+	// ScheduledAfter keeps only scheduled pickups due after it
+	ScheduledAfter *time.Time
+	// ExcludeBillShares drops even-split shares, which repeat their parent's lines and total
+	ExcludeBillShares bool
+	// FiredOnly drops orders whose kitchen tickets are all held
+	FiredOnly bool
+}
+
+// PageRequest selects a page of results; Limit defaults to 50 and is capped at 200.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// sqlArgs collects positional arguments and hands out their $n placeholders
+type sqlArgs []interface{}
+
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+func (f OrderFilter) conditions(args *sqlArgs) []string {
+	var conds []string
+	if len(f.Statuses) > 0 {
+		ph := make([]string, 0, len(f.Statuses))
+		for _, st := range f.Statuses {
+			ph = append(ph, args.add(string(st)))
+		}
+		conds = append(conds, "status IN ("+strings.Join(ph, ",")+")")
+	}
+	if f.Type != "" {
+		conds = append(conds, "type="+args.add(string(f.Type)))
+	}
+	if f.CustomerID != "" {
+		conds = append(conds, "customer_id="+args.add(f.CustomerID))
+	}
+	if f.SessionID != "" {
+		conds = append(conds, "session_id="+args.add(f.SessionID))
+	}
+	if f.RestaurantID != "" {
+		conds = append(conds, "restaurant_id="+args.add(f.RestaurantID))
+	}
+	if f.OrderNumber != 0 {
+		conds = append(conds, "order_number="+args.add(f.OrderNumber))
+	}
+	if f.BusinessDate != "" {
+		conds = append(conds, "business_date="+args.add(f.BusinessDate))
+	}
+	if f.From != nil {
+		conds = append(conds, "created_at >= "+args.add(*f.From))
+	}
+	if f.To != nil {
+		conds = append(conds, "created_at < "+args.add(*f.To))
+	}
+	if f.DueBy != nil {
+		conds = append(conds, "(pickup_at IS NULL OR pickup_at <= "+args.add(*f.DueBy)+")")
+	}
+	if f.ScheduledAfter != nil {
+		conds = append(conds, "pickup_at > "+args.add(*f.ScheduledAfter))
+	}
+	if f.ExcludeBillShares {
+		conds = append(conds, "(split_type IS NULL OR split_type <> "+args.add(string(models.SplitEvenly))+")")
+	}
+	if f.FiredOnly {
+		conds = append(conds, "(EXISTS (SELECT 1 FROM kitchen_tickets t WHERE t.order_id=orders.id AND t.fired_at IS NOT NULL) "+
+			"OR NOT EXISTS (SELECT 1 FROM kitchen_tickets t WHERE t.order_id=orders.id))")
+	}
+	return conds
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// encodeOrderCursor points just past the given order in created_at, id descending order
+func encodeOrderCursor(o *models.Order) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(o.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + o.ID))
+}
+
+func decodeOrderCursor(c string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return at, id, nil
+}
+
+// ListOrders returns one page of orders matching f, newest first. Pages are keyed on
+// (created_at, id) so orders created while paging do not shift later pages.
+func (s *OrderSQLService) ListOrders(ctx context.Context, f OrderFilter, page PageRequest) (*models.OrderPage, error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultOrderPageSize
+	}
+	if limit > maxOrderPageSize {
+		limit = maxOrderPageSize
+	}
+
+	var args sqlArgs
+	conds := f.conditions(&args)
+	res := &models.OrderPage{Orders: []*models.Order{}}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders"+whereClause(conds), args...).Scan(&res.Total); err != nil {
+		return nil, err
+	}
+
+	if page.Cursor != "" {
+		at, id, err := decodeOrderCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, "(created_at, id) < ("+args.add(at)+", "+args.add(id)+")")
+	}
+	// fetch one extra row to learn whether another page follows
+	q := "SELECT " + orderColumns + " FROM orders" + whereClause(conds) + " ORDER BY created_at DESC, id DESC LIMIT " + args.add(limit+1)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		res.Orders = append(res.Orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res.Orders) > limit {
+		res.Orders = res.Orders[:limit]
+		res.NextCursor = encodeOrderCursor(res.Orders[limit-1])
+	}
+	return res, nil
+}
+
+// SummarizeOrders totals the orders matching f per currency.
+func (s *OrderSQLService) SummarizeOrders(ctx context.Context, f OrderFilter) ([]models.SalesTotal, error) {
+	var args sqlArgs
+	where := whereClause(f.conditions(&args))
+	rows, err := s.db.QueryContext(ctx, "SELECT COALESCE(currency, ''), COUNT(*), COALESCE(SUM(total_amount), 0) FROM orders"+where+" GROUP BY 1 ORDER BY 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []models.SalesTotal{}
+	for rows.Next() {
+		var t models.SalesTotal
+		if err := rows.Scan(&t.Currency, &t.Orders, &t.Total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// ItemSales totals what each menu item sold on the matching orders, per currency and before
+// order-level discounts. Combo components count their allocated share of the combo; the combo
+// lines themselves are not items and are left out.
+func (s *OrderSQLService) ItemSales(ctx context.Context, f OrderFilter) ([]models.ItemSales, error) {
+	var args sqlArgs
+	where := whereClause(f.conditions(&args))
+	rows, err := s.db.QueryContext(ctx, "SELECT oi.menu_item_id, MAX(oi.name), COALESCE(o.currency, ''), SUM(oi.quantity), "+
+		"SUM(CASE WHEN oi.parent_item_id IS NULL THEN oi.total_price ELSE COALESCE(oi.allocated_total, 0) END) "+
+		"FROM order_items oi JOIN orders o ON o.id = oi.order_id "+
+		"WHERE oi.order_id IN (SELECT id FROM orders"+where+") AND oi.voided_at IS NULL AND oi.menu_item_id IS NOT NULL "+
+		"GROUP BY 1, 3 ORDER BY 5 DESC, 2 ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sales := []models.ItemSales{}
+	for rows.Next() {
+		var it models.ItemSales
+		if err := rows.Scan(&it.MenuItemID, &it.Name, &it.Currency, &it.Quantity, &it.Revenue); err != nil {
+			return nil, err
+		}
+		sales = append(sales, it)
+	}
+	return sales, rows.Err()
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	c := encodeOrderCursor(&models.Order{ID: "ord-1", CreatedAt: created})
+
+	at, id, err := decodeOrderCursor(c)
+	assert.NoError(t, err)
+	assert.True(t, at.Equal(created))
+	assert.Equal(t, "ord-1", id)
+
+	_, _, err = decodeOrderCursor("not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestOrderFilterConditions(t *testing.T) {
+	var args sqlArgs
+	f := OrderFilter{
+		Statuses:   []models.OrderStatus{models.OrderStatusPending, models.OrderStatusReady},
+		Type:       models.OrderTypeTakeaway,
+		CustomerID: "cust-1",
+	}
+
+	conds := f.conditions(&args)
+	assert.Equal(t, " WHERE status IN ($1,$2) AND type=$3 AND customer_id=$4", whereClause(conds))
+	assert.Equal(t, sqlArgs{"pending", "ready", "takeaway", "cust-1"}, args)
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+var (
+	// ErrOrderLocked is returned when an order's lines can no longer be changed in the requested way.
+	ErrOrderLocked = errors.New("order can no longer be modified")
+	// ErrApprovalRequired is returned when voiding a line the kitchen has started needs a manager.
+	ErrApprovalRequired = errors.New("manager approval required")
+)
+
+// LineChange describes why a line is removed or voided and who asked for it.
+// ApprovedBy is the manager who signed off, required once preparation has started.
+type LineChange struct {
+	Reason     models.VoidReason
+	Note       string
+	UserID     string
+	ApprovedBy string
+}
+
+// kitchenStarted reports whether the kitchen may already be working on the order's lines
+func kitchenStarted(st models.OrderStatus) bool {
+	return st == models.OrderStatusPreparing || st == models.OrderStatusReady
+}
+
+// lockModifiableOrder locks an open order whose lines can be edited; bill shares and
+// orders already divided into bill shares are fixed amounts and cannot be.
+func lockModifiableOrder(ctx context.Context, tx *sql.Tx, id string) (*models.Order, error) {
+	ord, err := lockOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenStatus(ord.Status) {
+		return nil, ErrOrderNotOpen
+	}
+	if ord.SplitType == models.SplitEvenly {
+		return nil, errors.New("bill shares have no lines to modify")
+	}
+	var shares int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE parent_order_id=$1 AND split_type=$2", id, string(models.SplitEvenly)).Scan(&shares); err != nil {
+		return nil, err
+	}
+	if shares > 0 {
+		return nil, errors.New("order has been split into bill shares")
+	}
+	return ord, nil
+}
+
+func lockOrderItem(ctx context.Context, tx *sql.Tx, orderID, itemID string) (*models.OrderItem, error) {
+	it, err := scanOrderItem(tx.QueryRowContext(ctx, "SELECT "+orderItemColumns+" FROM order_items WHERE id=$1 AND order_id=$2 FOR UPDATE", itemID, orderID))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("item " + itemID + " does not belong to order")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if it.VoidedAt != nil {
+		return nil, errors.New("item " + itemID + " is already voided")
+	}
+	if it.ParentItemID != "" {
+		return nil, errors.New("item " + itemID + " is part of a combo; change the combo line instead")
+	}
+	return it, nil
+}
+
+// lockComboComponents locks the component lines of a combo line; other lines have none
+func lockComboComponents(ctx context.Context, tx *sql.Tx, itemID string) ([]models.OrderItem, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+orderItemColumns+" FROM order_items WHERE parent_item_id=$1 FOR UPDATE", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []models.OrderItem
+	for rows.Next() {
+		it, err := scanOrderItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *it)
+	}
+	return items, rows.Err()
+}
+
+// modifyOrder runs fn on a locked, modifiable order, reprices it and returns the updated order.
+func (s *OrderSQLService) modifyOrder(ctx context.Context, orderID string, fn func(tx *sql.Tx, ord *models.Order) error) (*models.Order, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ord, err := lockModifiableOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err = fn(tx, ord); err != nil {
+		return nil, err
+	}
+	if _, err = repriceOrder(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, orderID)
+}
+
+// AddOrderItems adds priced lines to an order the kitchen has not finished. Lines with allergens
+// the customer declared need acknowledged, as when placing an order.
+func (s *OrderSQLService) AddOrderItems(ctx context.Context, orderID string, items []CreateOrderItemReq, acknowledged bool, userID string) (*models.Order, *models.OrderChange, error) {
+	if len(items) == 0 {
+		return nil, nil, errors.New("items required")
+	}
+	change := &models.OrderChange{Action: "items_added"}
+	ord, err := s.modifyOrder(ctx, orderID, func(tx *sql.Tx, ord *models.Order) error {
+		if ord.Status == models.OrderStatusReady {
+			return ErrOrderLocked
+		}
+		for _, it := range items {
+			lines, err := priceOrderLines(ctx, tx, orderID, ord.RestaurantID, menuTime(time.Now(), ord.PickupAt), it)
+			if err != nil {
+				return err
+			}
+			change.Items = append(change.Items, lines...)
+		}
+		if err := checkAllergens(ctx, tx, orderID, ord.CustomerID, change.Items, acknowledged, userID); err != nil {
+			return err
+		}
+		// added lines go out on fresh tickets; the original ones may already be in progress
+		fire, err := courseFiring(ctx, tx, orderID, ord.Type, change.Items)
+		if err != nil {
+			return err
+		}
+		if err := assignTickets(ctx, tx, orderID, ord.RestaurantID, change.Items, fire); err != nil {
+			return err
+		}
+		for _, oi := range change.Items {
+			if err := insertOrderItem(ctx, tx, oi); err != nil {
+				return err
+			}
+		}
+		return writeOrderAudit(ctx, tx, orderID, change.Action, userID, map[string]interface{}{"items": change.Items})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ord, change, nil
+}
+
+// RemoveOrderItem deletes a line before the kitchen has started on the order.
+func (s *OrderSQLService) RemoveOrderItem(ctx context.Context, orderID, itemID string, lc LineChange) (*models.Order, *models.OrderChange, error) {
+	if !lc.Reason.IsValid() {
+		return nil, nil, errors.New("unknown reason code")
+	}
+	change := &models.OrderChange{Action: "item_removed", Reason: lc.Reason, Note: lc.Note}
+	ord, err := s.modifyOrder(ctx, orderID, func(tx *sql.Tx, ord *models.Order) error {
+		if kitchenStarted(ord.Status) {
+			return ErrOrderLocked
+		}
+		it, err := lockOrderItem(ctx, tx, orderID, itemID)
+		if err != nil {
+			return err
+		}
+		components, err := lockComboComponents(ctx, tx, itemID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM order_items WHERE id=$1 OR parent_item_id=$1", itemID); err != nil {
+			return err
+		}
+		change.Items = append([]models.OrderItem{*it}, components...)
+		for _, removed := range change.Items {
+			if err := dropEmptyTicket(ctx, tx, removed.TicketID); err != nil {
+				return err
+			}
+		}
+		return writeOrderAudit(ctx, tx, orderID, change.Action, lc.UserID, map[string]interface{}{
+			"item": it, "reason": lc.Reason, "note": lc.Note,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ord, change, nil
+}
+
+// VoidOrderItem keeps a line on the order but takes it out of the totals. Once preparation
+// has started the void must carry a manager's approval.
+func (s *OrderSQLService) VoidOrderItem(ctx context.Context, orderID, itemID string, lc LineChange) (*models.Order, *models.OrderChange, error) {
+	if !lc.Reason.IsValid() {
+		return nil, nil, errors.New("unknown reason code")
+	}
+	change := &models.OrderChange{Action: "item_voided", Reason: lc.Reason, Note: lc.Note}
+	ord, err := s.modifyOrder(ctx, orderID, func(tx *sql.Tx, ord *models.Order) error {
+		if kitchenStarted(ord.Status) && lc.ApprovedBy == "" {
+			return ErrApprovalRequired
+		}
+		it, err := lockOrderItem(ctx, tx, orderID, itemID)
+		if err != nil {
+			return err
+		}
+		components, err := lockComboComponents(ctx, tx, itemID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		// voiding a combo voids its components with it
+		if _, err := tx.ExecContext(ctx, "UPDATE order_items SET voided_at=$1, void_reason=$2, void_note=$3, voided_by=$4, void_approved_by=$5 WHERE id=$6 OR parent_item_id=$6",
+			now, string(lc.Reason), lc.Note, lc.UserID, nullIfEmpty(lc.ApprovedBy), itemID); err != nil {
+			return err
+		}
+		change.Items = append([]models.OrderItem{*it}, components...)
+		for i := range change.Items {
+			change.Items[i].VoidedAt, change.Items[i].VoidReason = &now, lc.Reason
+		}
+		return writeOrderAudit(ctx, tx, orderID, change.Action, lc.UserID, map[string]interface{}{
+			"item_id": itemID, "reason": lc.Reason, "note": lc.Note, "approved_by": lc.ApprovedBy, "status": ord.Status,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ord, change, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoidOrderItemAfterPartialPayment(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOrderTestDB(t)
+	seedOrder(t, db, "o1", "s1", models.OrderStatusReady, orderLine("a", "Tibs", 25000, 1, "k1"), orderLine("b", "Buna", 6000, 1, "k1"))
+	// the guest paid for the Tibs and its service charge only
+	_, err := db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, status) VALUES ('p1', 'o1', 27500, $1), ('p2', 'o1', 6600, 'failed')",
+		string(models.PaymentStatusCompleted))
+	assert.NoError(t, err)
+
+	var te *TransitionError
+	assert.ErrorAs(t, s.UpdateOrderStatus(ctx, "o1", OrderTransition{To: models.OrderStatusCompleted, UserID: "u1"}), &te)
+
+	_, _, err = s.VoidOrderItem(ctx, "o1", "b", LineChange{Reason: "spilled", UserID: "u1"})
+	assert.EqualError(t, err, "unknown reason code")
+	_, _, err = s.VoidOrderItem(ctx, "o1", "b", LineChange{Reason: models.VoidCustomerRequest, UserID: "u1"})
+	assert.ErrorIs(t, err, ErrApprovalRequired)
+	_, _, err = s.RemoveOrderItem(ctx, "o1", "b", LineChange{Reason: models.VoidCustomerRequest, UserID: "u1"})
+	assert.ErrorIs(t, err, ErrOrderLocked)
+
+	ord, change, err := s.VoidOrderItem(ctx, "o1", "b", LineChange{Reason: models.VoidCustomerRequest, UserID: "u1", ApprovedBy: "m1"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(27500), ord.TotalAmount)
+	if assert.Len(t, change.Items, 1) {
+		assert.Equal(t, "b", change.Items[0].ID)
+		assert.NotNil(t, change.Items[0].VoidedAt)
+	}
+	// the voided line stays on the order for the record
+	for _, it := range ord.Items {
+		assert.Equal(t, it.ID == "b", it.VoidedAt != nil)
+	}
+	var approvedBy string
+	assert.NoError(t, db.QueryRowContext(ctx, "SELECT void_approved_by FROM order_items WHERE id='b'").Scan(&approvedBy))
+	assert.Equal(t, "m1", approvedBy)
+	_, _, err = s.VoidOrderItem(ctx, "o1", "b", LineChange{Reason: models.VoidCustomerRequest, UserID: "u1", ApprovedBy: "m1"})
+	assert.EqualError(t, err, "item b is already voided")
+
+	// the payment already taken now covers the order
+	assert.NoError(t, s.UpdateOrderStatus(ctx, "o1", OrderTransition{To: models.OrderStatusCompleted, UserID: "u1"}))
+	assert.Equal(t, []string{"item_voided", "status_changed"}, auditActions(t, db, "o1"))
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// restaurantLocation returns the restaurant's configured time zone, falling back to UTC
+// for orders without a restaurant or with a zone the server does not know.
+func restaurantLocation(ctx context.Context, q sqlQueryer, restaurantID string) (*time.Location, error) {
+	if restaurantID == "" {
+		return time.UTC, nil
+	}
+	var tz string
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(timezone, '') FROM restaurants WHERE id=$1", restaurantID).Scan(&tz)
+	if err == sql.ErrNoRows || tz == "" {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// allocateOrderNumber hands out the next ticket number for the restaurant's business day at now.
+// The upsert locks the day's counter row until the transaction ends, so concurrent orders queue
+// behind each other and a rolled-back order gives its number back.
+func allocateOrderNumber(ctx context.Context, q sqlQueryer, restaurantID string, now time.Time) (int, string, error) {
+	loc, err := restaurantLocation(ctx, q, restaurantID)
+	if err != nil {
+		return 0, "", err
+	}
+	day := now.In(loc).Format("2006-01-02")
+	var n int
+	err = q.QueryRowContext(ctx, "INSERT INTO order_number_counters (restaurant_id, business_date, last_number) VALUES ($1,$2,1) "+
+		"ON CONFLICT (restaurant_id, business_date) DO UPDATE SET last_number = order_number_counters.last_number + 1 RETURNING last_number",
+		restaurantID, day).Scan(&n)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, day, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PricingSettings are the restaurant-level rules applied on top of line totals.
+// Rates are percentages, e.g. 15 for 15%.
+type PricingSettings struct {
+	Currency          string
+	TaxRate           float64
+	TaxInclusive      bool
+	ServiceChargeRate float64
+	DeliveryFee       models.Money
+	RoundingIncrement models.Money
+	RoundingMode      models.RoundingMode
+}
+
+// roundTo rounds x to a multiple of inc using mode; inc <= 0 leaves x unchanged.
+func roundTo(x, inc models.Money, mode models.RoundingMode) models.Money {
+	if inc <= 0 {
+		return x
+	}
+	r := x % inc
+	if r < 0 {
+		r += inc
+	}
+	down := x - r
+	switch mode {
+	case models.RoundingUp:
+		if r == 0 {
+			return x
+		}
+		return down + inc
+	case models.RoundingDown:
+		return down
+	case models.RoundingNearest:
+		if 2*r >= inc {
+			return down + inc
+		}
+		return down
+	}
+	return x
+}
+
+// PriceOrder computes the full breakdown from a line subtotal and an order-level discount.
+// Service charge applies after discounts; tax covers the discounted subtotal plus service
+// and any delivery fee.
+func PriceOrder(subtotal, discount models.Money, st PricingSettings) models.OrderPricing {
+	p := models.OrderPricing{Subtotal: subtotal, TaxInclusive: st.TaxInclusive}
+	p.Discount = discount
+	if p.Discount < 0 {
+		p.Discount = 0
+	}
+	if p.Discount > p.Subtotal {
+		p.Discount = p.Subtotal
+	}
+	net := p.Subtotal - p.Discount
+	p.ServiceCharge = net.Percent(st.ServiceChargeRate)
+	p.DeliveryFee = st.DeliveryFee
+	taxable := net + p.ServiceCharge + p.DeliveryFee
+	if st.TaxInclusive {
+		p.Tax = taxable - models.Money(math.Round(float64(taxable)/(1+st.TaxRate/100)))
+		p.Total = taxable
+	} else {
+		p.Tax = taxable.Percent(st.TaxRate)
+		p.Total = taxable + p.Tax
+	}
+	rounded := roundTo(p.Total, st.RoundingIncrement, st.RoundingMode)
+	p.Rounding = rounded - p.Total
+	p.Total = rounded
+	return p
+}
+
+func loadPricingSettings(ctx context.Context, q sqlQueryer, restaurantID string) (PricingSettings, error) {
+	st := PricingSettings{Currency: models.DefaultCurrency}
+	if restaurantID == "" {
+		return st, nil
+	}
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(NULLIF(currency, ''), $2), COALESCE(tax_rate, 0), COALESCE(tax_inclusive, FALSE), COALESCE(service_charge_rate, 0), COALESCE(delivery_fee, 0), COALESCE(rounding_increment, 0), COALESCE(rounding_mode, '') FROM restaurants WHERE id=$1 AND deleted_at IS NULL", restaurantID, models.DefaultCurrency).
+		Scan(&st.Currency, &st.TaxRate, &st.TaxInclusive, &st.ServiceChargeRate, &st.DeliveryFee, &st.RoundingIncrement, &st.RoundingMode)
+	if err == sql.ErrNoRows {
+		return st, errors.New("restaurant " + restaurantID + " not found")
+	}
+	return st, err
+}
+
+// discountFor returns the amount a discount takes off subtotal
+func discountFor(d *models.Discount, subtotal models.Money) models.Money {
+	if d == nil {
+		return 0
+	}
+	switch d.Type {
+	case models.DiscountTypePercentage:
+		return subtotal.Percent(d.Value)
+	case models.DiscountTypeFixed:
+		return models.MoneyFromFloat(d.Value)
+	}
+	return 0
+}
+
+func loadDiscount(ctx context.Context, q sqlQueryer, id string) (*models.Discount, error) {
+	if id == "" {
+		return nil, nil
+	}
+	var d models.Discount
+	if err := q.QueryRowContext(ctx, "SELECT id, code, type, value FROM discounts WHERE id=$1", id).Scan(&d.ID, &d.Code, &d.Type, &d.Value); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// redeemDiscount validates a code for this customer and restaurant and records its use on the order.
+func redeemDiscount(ctx context.Context, q sqlQueryer, code, restaurantID, customerID, orderID string) (*models.Discount, error) {
+	var d models.Discount
+	var rid sql.NullString
+	now := time.Now()
+	err := q.QueryRowContext(ctx, "SELECT id, code, type, value, restaurant_id, usage_limit, per_user_limit, used_count FROM discounts WHERE code=$1 AND deleted_at IS NULL AND valid_from <= $2 AND valid_to >= $2 FOR UPDATE", code, now).
+		Scan(&d.ID, &d.Code, &d.Type, &d.Value, &rid, &d.UsageLimit, &d.PerUserLimit, &d.UsedCount)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid or expired discount code")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rid.Valid && rid.String != restaurantID {
+		return nil, errors.New("discount code is not valid at this restaurant")
+	}
+	if d.UsageLimit > 0 && d.UsedCount >= d.UsageLimit {
+		return nil, errors.New("discount code has been fully redeemed")
+	}
+	if d.PerUserLimit > 0 {
+		var used int
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM discount_usages WHERE discount_id=$1 AND account_id=$2", d.ID, customerID).Scan(&used); err != nil {
+			return nil, err
+		}
+		if used >= d.PerUserLimit {
+			return nil, errors.New("discount code already used")
+		}
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE discounts SET used_count = used_count + 1 WHERE id=$1", d.ID); err != nil {
+		return nil, err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE orders SET discount_id=$1 WHERE id=$2", d.ID, orderID); err != nil {
+		return nil, err
+	}
+	// the amount is filled in by repriceOrder once the subtotal is known
+	if _, err := q.ExecContext(ctx, "INSERT INTO discount_usages (id, discount_id, account_id, order_id, amount, created_at) VALUES ($1,$2,$3,$4,0,$5)",
+		uuid.New().String(), d.ID, customerID, orderID, now); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// repriceOrder recalculates the breakdown from the order's live (non-voided) lines and stores it.
+// Every path that changes lines goes through here so totals never drift.
+func repriceOrder(ctx context.Context, q sqlQueryer, orderID string) (models.OrderPricing, error) {
+	var restaurantID, discountID, parentID string
+	var orderType models.OrderType
+	var subtotal models.Money
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(restaurant_id, ''), COALESCE(discount_id, ''), COALESCE(type, ''), COALESCE(parent_order_id, ''), COALESCE((SELECT SUM(total_price) FROM order_items WHERE order_id=$1 AND voided_at IS NULL), 0) FROM orders WHERE id=$1", orderID).
+		Scan(&restaurantID, &discountID, &orderType, &parentID, &subtotal)
+	if err != nil {
+		return models.OrderPricing{}, err
+	}
+	st, err := loadPricingSettings(ctx, q, restaurantID)
+	if err != nil {
+		return models.OrderPricing{}, err
+	}
+	// the delivery fee is charged once, on the original order
+	if orderType != models.OrderTypeDelivery || parentID != "" {
+		st.DeliveryFee = 0
+	}
+	d, err := loadDiscount(ctx, q, discountID)
+	if err != nil {
+		return models.OrderPricing{}, err
+	}
+	p := PriceOrder(subtotal, discountFor(d, subtotal), st)
+	_, err = q.ExecContext(ctx, "UPDATE orders SET subtotal=$1, discount_amount=$2, service_charge=$3, delivery_fee=$4, tax_amount=$5, tax_inclusive=$6, rounding_adjustment=$7, total_amount=$8, currency=$9, updated_at=$10 WHERE id=$11",
+		p.Subtotal, p.Discount, p.ServiceCharge, p.DeliveryFee, p.Tax, p.TaxInclusive, p.Rounding, p.Total, st.Currency, time.Now(), orderID)
+	if err != nil {
+		return models.OrderPricing{}, err
+	}
+	if d != nil {
+		if _, err := q.ExecContext(ctx, "UPDATE discount_usages SET amount=$1 WHERE discount_id=$2 AND order_id=$3", p.Discount, d.ID, orderID); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
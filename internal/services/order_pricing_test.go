@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceOrderExclusiveTax(t *testing.T) {
+	p := PriceOrder(10000, 1000, PricingSettings{TaxRate: 15, ServiceChargeRate: 10})
+
+	assert.Equal(t, models.Money(10000), p.Subtotal)
+	assert.Equal(t, models.Money(1000), p.Discount)
+	assert.Equal(t, models.Money(900), p.ServiceCharge)
+	assert.Equal(t, models.Money(1485), p.Tax)
+	assert.Equal(t, models.Money(11385), p.Total)
+	assert.Equal(t, models.Money(0), p.Rounding)
+}
+
+func TestPriceOrderInclusiveTaxAndRounding(t *testing.T) {
+	p := PriceOrder(5730, 0, PricingSettings{TaxRate: 15, TaxInclusive: true, RoundingIncrement: 50, RoundingMode: models.RoundingUp})
+
+	assert.Equal(t, models.Money(747), p.Tax)
+	assert.Equal(t, models.Money(20), p.Rounding)
+	assert.Equal(t, models.Money(5750), p.Total)
+}
+
+func TestPriceOrderCapsDiscount(t *testing.T) {
+	p := PriceOrder(2000, 5000, PricingSettings{})
+
+	assert.Equal(t, models.Money(2000), p.Discount)
+	assert.Equal(t, models.Money(0), p.Total)
+}
+
+func TestPriceOrderTaxesDeliveryFee(t *testing.T) {
+	p := PriceOrder(10000, 0, PricingSettings{TaxRate: 15, ServiceChargeRate: 10, DeliveryFee: 500})
+
+	assert.Equal(t, models.Money(1000), p.ServiceCharge)
+	assert.Equal(t, models.Money(500), p.DeliveryFee)
+	assert.Equal(t, models.Money(1725), p.Tax)
+	assert.Equal(t, models.Money(13225), p.Total)
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrOrderNotOpen is returned when an order is already completed or cancelled.
+var ErrOrderNotOpen = errors.New("order is not open")
+
+func isOpenStatus(st models.OrderStatus) bool {
+	return st != models.OrderStatusCompleted && st != models.OrderStatusCancelled
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// lockOrder loads an order row and holds it for the rest of tx
+func lockOrder(ctx context.Context, tx *sql.Tx, id string) (*models.Order, error) {
+	ord, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id=$1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	return ord, err
+}
+
+// lockBranchOrder is lockOrder for an order of restaurantID; orders of other restaurants are
+// not found. An empty restaurantID accepts any order.
+func lockBranchOrder(ctx context.Context, tx *sql.Tx, id, restaurantID string) (*models.Order, error) {
+	ord, err := lockOrder(ctx, tx, id)
+	if err == nil && restaurantID != "" && ord.RestaurantID != restaurantID {
+		return nil, ErrOrderNotFound
+	}
+	return ord, err
+}
+
+// insertDerivedOrder creates an empty order that inherits customer, session, restaurant, fulfilment,
+// ticket number and status from parent
+func insertDerivedOrder(ctx context.Context, tx *sql.Tx, parent *models.Order, mode models.SplitType, total models.Money) (*models.Order, error) {
+	now := time.Now()
+	child := &models.Order{
+		ID: uuid.New().String(), CustomerID: parent.CustomerID, SessionID: parent.SessionID, RestaurantID: parent.RestaurantID,
+		Type: parent.Type, TableID: parent.TableID, PickupAt: parent.PickupAt, DeliveryAddress: parent.DeliveryAddress,
+		OrderNumber: parent.OrderNumber, BusinessDate: parent.BusinessDate,
+		TotalAmount: total, Currency: parent.Currency, Status: parent.Status, ParentOrderID: parent.ID, SplitType: mode,
+		MenuVersionID: parent.MenuVersionID, CreatedAt: now, UpdatedAt: now,
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, session_id, restaurant_id, type, table_id, pickup_at, delivery_address, order_number, business_date, total_amount, currency, status, parent_order_id, split_type, created_at, updated_at, menu_version_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)",
+		child.ID, child.CustomerID, nullIfEmpty(child.SessionID), nullIfEmpty(child.RestaurantID), string(child.Type), nullIfEmpty(child.TableID), child.PickupAt, nullIfEmpty(child.DeliveryAddress),
+		child.OrderNumber, nullIfEmpty(child.BusinessDate), child.TotalAmount, child.Currency, string(child.Status), child.ParentOrderID, string(mode), now, now, nullIfEmpty(child.MenuVersionID))
+	if err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// SplitOrder divides an open order by item, by quantity within lines, or evenly between guests.
+// Item and quantity splits move lines into new orders; an even split leaves the lines on the
+// parent and creates one bill share per guest. When restaurantID is set the order must be one
+// of that restaurant's.
+func (s *OrderSQLService) SplitOrder(ctx context.Context, restaurantID, orderID string, req models.SplitOrderRequest, userID string) ([]*models.Order, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	parent, err := lockBranchOrder(ctx, tx, orderID, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenStatus(parent.Status) {
+		err = ErrOrderNotOpen
+		return nil, err
+	}
+	if parent.SplitType == models.SplitEvenly {
+		err = errors.New("bill shares cannot be split again")
+		return nil, err
+	}
+	items, err := loadOrderItems(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.OrderItem, len(items))
+	for _, it := range items {
+		// voided lines stay on the parent for the record; combo components move with their combo
+		if it.VoidedAt == nil && it.ParentItemID == "" {
+			byID[it.ID] = it
+		}
+	}
+
+	var derived []*models.Order
+	switch req.Mode {
+	case models.SplitByItem:
+		derived, err = splitByItem(ctx, tx, parent, byID, req.Groups)
+	case models.SplitByQuantity:
+		derived, err = splitByQuantity(ctx, tx, parent, byID, req.Lines)
+	case models.SplitEvenly:
+		derived, err = splitEvenly(ctx, tx, parent, req.Guests)
+	default:
+		err = errors.New("unknown split mode")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = repriceOrder(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(derived))
+	for _, d := range derived {
+		ids = append(ids, d.ID)
+		if err = writeOrderAudit(ctx, tx, d.ID, "split_from", userID, map[string]interface{}{"parent_order_id": orderID, "mode": req.Mode}); err != nil {
+			return nil, err
+		}
+	}
+	if err = writeOrderAudit(ctx, tx, orderID, "order_split", userID, map[string]interface{}{"mode": req.Mode, "derived_order_ids": ids}); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	res := make([]*models.Order, 0, len(derived))
+	for _, d := range derived {
+		ord, gerr := s.GetOrder(ctx, d.ID)
+		if gerr != nil {
+			return nil, gerr
+		}
+		res = append(res, ord)
+	}
+	return res, nil
+}
+
+func splitByItem(ctx context.Context, tx *sql.Tx, parent *models.Order, byID map[string]models.OrderItem, groups [][]string) ([]*models.Order, error) {
+	if len(groups) == 0 {
+		return nil, errors.New("groups required")
+	}
+	moved := map[string]bool{}
+	var derived []*models.Order
+	for _, group := range groups {
+		if len(group) == 0 {
+			return nil, errors.New("empty group")
+		}
+		child, err := insertDerivedOrder(ctx, tx, parent, models.SplitByItem, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, itemID := range group {
+			if _, ok := byID[itemID]; !ok {
+				return nil, errors.New("item " + itemID + " does not belong to order")
+			}
+			if moved[itemID] {
+				return nil, errors.New("item " + itemID + " listed twice")
+			}
+			moved[itemID] = true
+			if _, err := tx.ExecContext(ctx, "UPDATE order_items SET order_id=$1 WHERE id=$2 OR parent_item_id=$2", child.ID, itemID); err != nil {
+				return nil, err
+			}
+		}
+		if err := rehomeTickets(ctx, tx, parent.ID, child.ID); err != nil {
+			return nil, err
+		}
+		if _, err := repriceOrder(ctx, tx, child.ID); err != nil {
+			return nil, err
+		}
+		derived = append(derived, child)
+	}
+	if len(moved) >= len(byID) {
+		return nil, errors.New("split must leave at least one item on the original order")
+	}
+	return derived, nil
+}
+
+func splitByQuantity(ctx context.Context, tx *sql.Tx, parent *models.Order, byID map[string]models.OrderItem, lines []models.SplitLine) ([]*models.Order, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("lines required")
+	}
+	child, err := insertDerivedOrder(ctx, tx, parent, models.SplitByQuantity, 0)
+	if err != nil {
+		return nil, err
+	}
+	remaining := 0
+	for _, it := range byID {
+		remaining += it.Quantity
+	}
+	seen := map[string]bool{}
+	for _, ln := range lines {
+		it, ok := byID[ln.OrderItemID]
+		if !ok {
+			return nil, errors.New("item " + ln.OrderItemID + " does not belong to order")
+		}
+		if seen[ln.OrderItemID] {
+			return nil, errors.New("item " + ln.OrderItemID + " listed twice")
+		}
+		seen[ln.OrderItemID] = true
+		if ln.Quantity < 1 || ln.Quantity > it.Quantity {
+			return nil, errors.New("invalid quantity for item " + ln.OrderItemID)
+		}
+		remaining -= ln.Quantity
+		if ln.Quantity == it.Quantity {
+			if _, err := tx.ExecContext(ctx, "UPDATE order_items SET order_id=$1 WHERE id=$2 OR parent_item_id=$2", child.ID, it.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if it.ComboID != "" {
+			return nil, errors.New("combo " + ln.OrderItemID + " can only be moved whole")
+		}
+		left := it.Quantity - ln.Quantity
+		if _, err := tx.ExecContext(ctx, "UPDATE order_items SET quantity=$1, total_price=$2 WHERE id=$3", left, it.Price.Times(left), it.ID); err != nil {
+			return nil, err
+		}
+		part := it
+		part.ID, part.OrderID = uuid.New().String(), child.ID
+		part.Quantity, part.TotalPrice = ln.Quantity, it.Price.Times(ln.Quantity)
+		if err := insertOrderItem(ctx, tx, part); err != nil {
+			return nil, err
+		}
+	}
+	if remaining <= 0 {
+		return nil, errors.New("split must leave at least one item on the original order")
+	}
+	if err := rehomeTickets(ctx, tx, parent.ID, child.ID); err != nil {
+		return nil, err
+	}
+	if _, err := repriceOrder(ctx, tx, child.ID); err != nil {
+		return nil, err
+	}
+	return []*models.Order{child}, nil
+}
+
+// rehomeTickets moves the kitchen tickets of lines split onto child over to it. A ticket whose
+// lines all moved follows them; a ticket left with lines on both orders is copied, keeping its
+// progress, so each order's ticket lists only its own lines.
+func rehomeTickets(ctx context.Context, tx *sql.Tx, parentID, childID string) error {
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT i.ticket_id, EXISTS (SELECT 1 FROM order_items p WHERE p.ticket_id=i.ticket_id AND p.order_id=$1) "+
+		"FROM order_items i JOIN kitchen_tickets t ON t.id=i.ticket_id WHERE i.order_id=$2 AND t.order_id=$1", parentID, childID)
+	if err != nil {
+		return err
+	}
+	shared := map[string]bool{}
+	for rows.Next() {
+		var ticketID string
+		var both bool
+		if err := rows.Scan(&ticketID, &both); err != nil {
+			rows.Close()
+			return err
+		}
+		shared[ticketID] = both
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for ticketID, both := range shared {
+		if !both {
+			if _, err := tx.ExecContext(ctx, "UPDATE kitchen_tickets SET order_id=$1, updated_at=NOW() WHERE id=$2", childID, ticketID); err != nil {
+				return err
+			}
+			continue
+		}
+		copyID := uuid.New().String()
+		if _, err := tx.ExecContext(ctx, "INSERT INTO kitchen_tickets (id, order_id, station_id, course, status, fired_at, created_at, started_at, bumped_at, recalled_at, recall_count, updated_at) "+
+			"SELECT $1, $2, station_id, course, status, fired_at, created_at, started_at, bumped_at, recalled_at, recall_count, NOW() FROM kitchen_tickets WHERE id=$3",
+			copyID, childID, ticketID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE order_items SET ticket_id=$1 WHERE order_id=$2 AND ticket_id=$3", copyID, childID, ticketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitEvenly(ctx context.Context, tx *sql.Tx, parent *models.Order, guests int) ([]*models.Order, error) {
+	if guests < 2 {
+		return nil, errors.New("guests must be at least 2")
+	}
+	var existing int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE parent_order_id=$1 AND split_type=$2", parent.ID, string(models.SplitEvenly)).Scan(&existing); err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, errors.New("order already split evenly")
+	}
+	// shares carry no lines, so they hold a slice of the parent's grand total rather than being repriced
+	var derived []*models.Order
+	for _, share := range parent.TotalAmount.Split(guests) {
+		child, err := insertDerivedOrder(ctx, tx, parent, models.SplitEvenly, share)
+		if err != nil {
+			return nil, err
+		}
+		derived = append(derived, child)
+	}
+	return derived, nil
+}
+
+// MergeOrders moves every line from the source orders onto target and cancels the sources.
+// All orders must be open and belong to the same table session, and to restaurantID when it
+// is set.
+func (s *OrderSQLService) MergeOrders(ctx context.Context, restaurantID, targetID string, sourceIDs []string, userID string) (*models.Order, error) {
+	ids := map[string]bool{}
+	for _, id := range sourceIDs {
+		if id != "" && id != targetID {
+			ids[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("at least one other order is required")
+	}
+	sources := make([]string, 0, len(ids))
+	for id := range ids {
+		sources = append(sources, id)
+	}
+	sort.Strings(sources)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// lock every order in id order, so merges sharing orders in either direction queue up
+	// instead of deadlocking
+	all := append([]string{targetID}, sources...)
+	sort.Strings(all)
+	locked := make(map[string]*models.Order, len(all))
+	for _, id := range all {
+		if locked[id], err = lockBranchOrder(ctx, tx, id, restaurantID); err != nil {
+			return nil, err
+		}
+	}
+	target := locked[targetID]
+	if !isOpenStatus(target.Status) {
+		err = ErrOrderNotOpen
+		return nil, err
+	}
+	if target.SessionID == "" {
+		err = errors.New("only orders attached to a session can be merged")
+		return nil, err
+	}
+	for _, id := range sources {
+		src := locked[id]
+		if src.SessionID != target.SessionID {
+			err = errors.New("order " + id + " belongs to a different session")
+			return nil, err
+		}
+		if !orderStates.Allowed(src.Status, models.OrderStatusCancelled) {
+			err = &TransitionError{OrderID: id, From: src.Status, To: models.OrderStatusCancelled, Reason: "order can no longer be merged"}
+			return nil, err
+		}
+		var paid int
+		if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM payments WHERE order_id=$1 AND status=$2", id, string(models.PaymentStatusCompleted)).Scan(&paid); err != nil {
+			return nil, err
+		}
+		if paid > 0 {
+			err = errors.New("order " + id + " already has payments")
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE order_items SET order_id=$1 WHERE order_id=$2", targetID, id); err != nil {
+			return nil, err
+		}
+		// the kitchen keeps working the same tickets, now under the target order
+		if _, err = tx.ExecContext(ctx, "UPDATE kitchen_tickets SET order_id=$1 WHERE order_id=$2", targetID, id); err != nil {
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE orders SET merged_into_order_id=$1 WHERE id=$2", targetID, id); err != nil {
+			return nil, err
+		}
+		if _, err = repriceOrder(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		if _, err = orderStates.Apply(ctx, tx, id, OrderTransition{To: models.OrderStatusCancelled, UserID: userID, Reason: "merged into " + targetID}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = repriceOrder(ctx, tx, targetID); err != nil {
+		return nil, err
+	}
+	if err = writeOrderAudit(ctx, tx, targetID, "order_merged", userID, map[string]interface{}{"source_order_ids": sources}); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, targetID)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOrderTestDB returns an order service on a sqlite database holding restaurants r1, which
+// charges 10% service, and r2
+func newOrderTestDB(t *testing.T) (*OrderSQLService, *sql.DB) {
+	t.Helper()
+	_, db := newMenuTestDB(t)
+	_, err := db.ExecContext(context.Background(), "INSERT INTO restaurants (id, name, currency, service_charge_rate) VALUES ('r1', 'Bole', 'ETB', 10), ('r2', 'Piassa', 'ETB', 0)")
+	assert.NoError(t, err)
+	return NewOrderSQLService(db), db
+}
+
+func orderLine(id, name string, price models.Money, qty int, ticketID string) models.OrderItem {
+	return models.OrderItem{ID: id, Name: name, Price: price, Quantity: qty, TotalPrice: price.Times(qty), TicketID: ticketID}
+}
+
+// seedOrder places a dine-in order at r1 holding lines and their kitchen tickets, priced as
+// CreateOrder would
+func seedOrder(t *testing.T, db *sql.DB, id, sessionID string, status models.OrderStatus, lines ...models.OrderItem) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	_, err := db.ExecContext(ctx, "INSERT INTO orders (id, customer_id, session_id, restaurant_id, status, type, created_at, updated_at) VALUES ($1, 'c1', $2, 'r1', $3, 'dine_in', $4, $4)",
+		id, nullIfEmpty(sessionID), string(status), now)
+	assert.NoError(t, err)
+	tickets := map[string]bool{}
+	for _, ln := range lines {
+		ln.OrderID = id
+		assert.NoError(t, insertOrderItem(ctx, db, ln))
+		if ln.TicketID != "" && !tickets[ln.TicketID] {
+			tickets[ln.TicketID] = true
+			_, err = db.ExecContext(ctx, "INSERT INTO kitchen_tickets (id, order_id, created_at) VALUES ($1, $2, $3)", ln.TicketID, id, now)
+			assert.NoError(t, err)
+		}
+	}
+	_, err = repriceOrder(ctx, db, id)
+	assert.NoError(t, err)
+}
+
+// auditActions lists the actions audited on an order, oldest first
+func auditActions(t *testing.T, db *sql.DB, orderID string) []string {
+	t.Helper()
+	rows, err := db.QueryContext(context.Background(), "SELECT action FROM order_audits WHERE order_id=$1 AND user_id='u1' ORDER BY rowid", orderID)
+	assert.NoError(t, err)
+	defer rows.Close()
+	var actions []string
+	for rows.Next() {
+		var a string
+		assert.NoError(t, rows.Scan(&a))
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+func ticketOrder(t *testing.T, db *sql.DB, ticketID string) string {
+	t.Helper()
+	var orderID string
+	assert.NoError(t, db.QueryRowContext(context.Background(), "SELECT order_id FROM kitchen_tickets WHERE id=$1", ticketID).Scan(&orderID))
+	return orderID
+}
+
+func TestSplitOrderByItem(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOrderTestDB(t)
+	seedOrder(t, db, "o1", "s1", models.OrderStatusPreparing,
+		orderLine("a", "Tibs", 25000, 1, "k1"), orderLine("b", "Buna", 6000, 2, "k1"), orderLine("c", "Shai", 3000, 1, "k2"))
+
+	_, err := s.SplitOrder(ctx, "r2", "o1", models.SplitOrderRequest{Mode: models.SplitByItem, Groups: [][]string{{"a"}}}, "u1")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByItem, Groups: [][]string{{"a", "b"}, {"c"}}}, "u1")
+	assert.EqualError(t, err, "split must leave at least one item on the original order")
+	_, err = s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByItem, Groups: [][]string{{"a"}, {"a"}}}, "u1")
+	assert.EqualError(t, err, "item a listed twice")
+
+	derived, err := s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByItem, Groups: [][]string{{"a"}, {"c"}}}, "u1")
+	assert.NoError(t, err)
+	if assert.Len(t, derived, 2) {
+		first, second := derived[0], derived[1]
+		assert.Equal(t, "o1", first.ParentOrderID)
+		assert.Equal(t, models.SplitByItem, first.SplitType)
+		assert.Equal(t, models.Money(27500), first.TotalAmount)
+		if assert.Len(t, first.Items, 1) {
+			// k1 still has Buna on the parent, so Tibs goes out on a copy of it
+			assert.Equal(t, "a", first.Items[0].ID)
+			assert.NotEqual(t, "k1", first.Items[0].TicketID)
+			assert.Equal(t, first.ID, ticketOrder(t, db, first.Items[0].TicketID))
+		}
+		assert.Equal(t, models.Money(3300), second.TotalAmount)
+		if assert.Len(t, second.Items, 1) {
+			assert.Equal(t, "k2", second.Items[0].TicketID)
+		}
+		assert.Equal(t, second.ID, ticketOrder(t, db, "k2"))
+		assert.Equal(t, []string{"split_from"}, auditActions(t, db, first.ID))
+	}
+
+	parent, err := s.GetOrder(ctx, "o1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(13200), parent.TotalAmount)
+	if assert.Len(t, parent.Items, 1) {
+		assert.Equal(t, "b", parent.Items[0].ID)
+	}
+	assert.Equal(t, "o1", ticketOrder(t, db, "k1"))
+	assert.Equal(t, []string{"order_split"}, auditActions(t, db, "o1"))
+}
+
+func TestSplitOrderByQuantity(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOrderTestDB(t)
+	seedOrder(t, db, "o1", "s1", models.OrderStatusConfirmed, orderLine("a", "Tibs", 25000, 1, "k1"), orderLine("b", "Buna", 6000, 3, "k1"))
+
+	_, err := s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByQuantity, Lines: []models.SplitLine{{OrderItemID: "b", Quantity: 4}}}, "u1")
+	assert.EqualError(t, err, "invalid quantity for item b")
+	_, err = s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByQuantity, Lines: []models.SplitLine{{OrderItemID: "a", Quantity: 1}, {OrderItemID: "b", Quantity: 3}}}, "u1")
+	assert.EqualError(t, err, "split must leave at least one item on the original order")
+
+	derived, err := s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitByQuantity, Lines: []models.SplitLine{{OrderItemID: "b", Quantity: 2}}}, "u1")
+	assert.NoError(t, err)
+	if assert.Len(t, derived, 1) {
+		child := derived[0]
+		assert.Equal(t, models.SplitByQuantity, child.SplitType)
+		assert.Equal(t, models.Money(13200), child.TotalAmount)
+		if assert.Len(t, child.Items, 1) {
+			it := child.Items[0]
+			assert.NotEqual(t, "b", it.ID)
+			assert.Equal(t, "Buna", it.Name)
+			assert.Equal(t, 2, it.Quantity)
+			assert.Equal(t, models.Money(12000), it.TotalPrice)
+			assert.Equal(t, child.ID, ticketOrder(t, db, it.TicketID))
+		}
+	}
+
+	parent, err := s.GetOrder(ctx, "o1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(34100), parent.TotalAmount)
+	for _, it := range parent.Items {
+		if it.ID == "b" {
+			assert.Equal(t, 1, it.Quantity)
+			assert.Equal(t, models.Money(6000), it.TotalPrice)
+		}
+	}
+}
+
+func TestSplitOrderEvenly(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOrderTestDB(t)
+	seedOrder(t, db, "o1", "s1", models.OrderStatusConfirmed, orderLine("a", "Tibs", 25000, 1, "k1"), orderLine("b", "Buna", 6000, 1, "k1"))
+
+	_, err := s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitEvenly, Guests: 1}, "u1")
+	assert.EqualError(t, err, "guests must be at least 2")
+
+	shares, err := s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitEvenly, Guests: 3}, "u1")
+	assert.NoError(t, err)
+	if assert.Len(t, shares, 3) {
+		// the shares add back up to the parent's 341.00 to the cent
+		for i, want := range []models.Money{11367, 11367, 11366} {
+			assert.Equal(t, want, shares[i].TotalAmount)
+			assert.Equal(t, models.SplitEvenly, shares[i].SplitType)
+			assert.Equal(t, "o1", shares[i].ParentOrderID)
+			assert.Empty(t, shares[i].Items)
+		}
+		_, err = s.SplitOrder(ctx, "r1", shares[0].ID, models.SplitOrderRequest{Mode: models.SplitEvenly, Guests: 2}, "u1")
+		assert.EqualError(t, err, "bill shares cannot be split again")
+	}
+
+	// the lines stay on the parent, which can no longer change
+	parent, err := s.GetOrder(ctx, "o1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(34100), parent.TotalAmount)
+	assert.Len(t, parent.Items, 2)
+	_, err = s.SplitOrder(ctx, "r1", "o1", models.SplitOrderRequest{Mode: models.SplitEvenly, Guests: 2}, "u1")
+	assert.EqualError(t, err, "order already split evenly")
+	_, _, err = s.VoidOrderItem(ctx, "o1", "b", LineChange{Reason: models.VoidCustomerRequest, UserID: "u1"})
+	assert.EqualError(t, err, "order has been split into bill shares")
+}
+
+func TestMergeOrders(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOrderTestDB(t)
+	seedOrder(t, db, "o1", "s1", models.OrderStatusConfirmed, orderLine("a", "Tibs", 25000, 1, "k1"))
+	seedOrder(t, db, "o2", "s1", models.OrderStatusPending, orderLine("b", "Buna", 6000, 2, "k2"))
+	seedOrder(t, db, "o3", "s2", models.OrderStatusPending, orderLine("c", "Shai", 3000, 1, ""))
+	seedOrder(t, db, "o4", "s1", models.OrderStatusPending, orderLine("d", "Shai", 3000, 1, ""))
+	seedOrder(t, db, "o5", "s1", models.OrderStatusCompleted, orderLine("e", "Shai", 3000, 1, ""))
+	_, err := db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, status) VALUES ('p1', 'o4', 3300, $1)", string(models.PaymentStatusCompleted))
+	assert.NoError(t, err)
+
+	_, err = s.MergeOrders(ctx, "r1", "o1", []string{"o1"}, "u1")
+	assert.EqualError(t, err, "at least one other order is required")
+	_, err = s.MergeOrders(ctx, "r2", "o1", []string{"o2"}, "u1")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = s.MergeOrders(ctx, "r1", "o1", []string{"o3"}, "u1")
+	assert.EqualError(t, err, "order o3 belongs to a different session")
+	_, err = s.MergeOrders(ctx, "r1", "o1", []string{"o4"}, "u1")
+	assert.EqualError(t, err, "order o4 already has payments")
+	_, err = s.MergeOrders(ctx, "r1", "o1", []string{"o5"}, "u1")
+	var te *TransitionError
+	assert.ErrorAs(t, err, &te)
+
+	merged, err := s.MergeOrders(ctx, "r1", "o1", []string{"o2", "o2"}, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, merged.Items, 2)
+	assert.Equal(t, models.Money(40700), merged.TotalAmount)
+	assert.Equal(t, "o1", ticketOrder(t, db, "k2"))
+	assert.Equal(t, []string{"order_merged"}, auditActions(t, db, "o1"))
+
+	src, err := s.GetOrder(ctx, "o2")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, src.Status)
+	assert.Equal(t, "o1", src.MergedIntoOrderID)
+	assert.Equal(t, models.Money(0), src.TotalAmount)
+	assert.Empty(t, src.Items)
+	assert.Equal(t, []string{"status_changed"}, auditActions(t, db, "o2"))
+}
@@ -18,17 +18,39 @@ type OrderSQLService struct {
 func NewOrderSQLService(db *sql.DB) *OrderSQLService { return &OrderSQLService{db: db} }
 
 type CreateOrderItemReq struct {
-	MenuItemID          string `json:"menu_item_id"`
-	Quantity            int    `json:"quantity"`
-	SpecialInstructions string `json:"special_instructions,omitempty"`
+	MenuItemID          string                     `json:"menu_item_id"`
+	Quantity            int                        `json:"quantity"`
+	SpecialInstructions string                     `json:"special_instructions,omitempty"`
+	VariantID           string                     `json:"variant_id,omitempty"`
+	AddonIDs            []string                   `json:"addon_ids,omitempty"`
+	Modifiers           []models.ModifierSelection `json:"modifiers,omitempty"`
+	ComboID             string                     `json:"combo_id,omitempty"`
+	ComboChoices        []models.ComboSelection    `json:"combo_choices,omitempty"`
+	Course              models.Course              `json:"course,omitempty"`
 }
 
-// CreateOrder accepts optional sessionID by passing it as last parameter
-func (s *OrderSQLService) CreateOrder(ctx context.Context, customerID string, items []CreateOrderItemReq, sessionID ...string) (*models.Order, error) {
-	if len(items) == 0 {
-		return nil, errors.New("items required")
-	}
+// NewOrder is everything needed to place an order. RestaurantID selects the tax, service
+// charge and rounding rules; DiscountCode is redeemed against the order when set.
+// ClientOrderID and ClientCreatedAt come from offline devices and make sync replays idempotent.
+// Type and the type-specific fields are checked by resolveOrderType. Lines containing allergens
+// the customer declared need AcknowledgeAllergens.
+type NewOrder struct {
+	CustomerID      string
+	SessionID       string
+	RestaurantID    string
+	DiscountCode    string
+	ClientOrderID   string
+	ClientCreatedAt *time.Time
+	Type            models.OrderType
+	TableID         string
+	PickupAt        *time.Time
+	DeliveryAddress string
+	Items           []CreateOrderItemReq
+	// AcknowledgeAllergens accepts the allergen warnings of an earlier attempt
+	AcknowledgeAllergens bool
+}
 
+func (s *OrderSQLService) CreateOrder(ctx context.Context, in NewOrder) (*models.Order, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -39,59 +61,116 @@ func (s *OrderSQLService) CreateOrder(ctx context.Context, customerID string, it
 		}
 	}()
 
+	orderID, err := createOrderTx(ctx, tx, in)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, orderID)
+}
+
+// createOrderTx inserts the order and its priced lines inside tx and stores the pricing breakdown.
+func createOrderTx(ctx context.Context, tx *sql.Tx, in NewOrder) (string, error) {
+	if len(in.Items) == 0 {
+		return "", errors.New("items required")
+	}
+	if err := resolveOrderType(ctx, tx, &in); err != nil {
+		return "", err
+	}
 	orderID := uuid.New().String()
 	now := time.Now()
 
-	var total float64
 	var orderItems []models.OrderItem
-	for _, it := range items {
-		var mi models.MenuItem
-		e := tx.QueryRowContext(ctx, "SELECT id, name, price FROM menu_items WHERE id=$1 AND available=TRUE", it.MenuItemID).
-			Scan(&mi.ID, &mi.Name, &mi.Price)
-		if e != nil {
-			err = e
-			return nil, err
+	for _, it := range in.Items {
+		lines, err := priceOrderLines(ctx, tx, orderID, in.RestaurantID, menuTime(now, in.PickupAt), it)
+		if err != nil {
+			return "", err
 		}
-		lineTotal := mi.Price * float64(it.Quantity)
-		total += lineTotal
-		orderItems = append(orderItems, models.OrderItem{
-			ID: uuid.New().String(), OrderID: orderID, MenuItemID: mi.ID,
-			Name: mi.Name, Price: mi.Price, Quantity: it.Quantity, TotalPrice: lineTotal,
-			SpecialInstructions: it.SpecialInstructions,
-		})
+		orderItems = append(orderItems, lines...)
 	}
 
-	if len(sessionID) > 0 && sessionID[0] != "" {
-		_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, session_id, total_amount, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)", orderID, customerID, sessionID[0], total, string(models.OrderStatusPending), now, now)
-	} else {
-		_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, total_amount, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6)", orderID, customerID, total, string(models.OrderStatusPending), now, now)
+	menuVersionID, err := publishedMenuVersion(ctx, tx, in.RestaurantID)
+	if err != nil {
+		return "", err
 	}
+	// allocated last so the counter stays locked for as little of the transaction as possible
+	number, day, err := allocateOrderNumber(ctx, tx, in.RestaurantID, now)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, session_id, restaurant_id, client_order_id, client_created_at, type, table_id, pickup_at, delivery_address, order_number, business_date, total_amount, status, created_at, updated_at, menu_version_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,0,$13,$14,$15,$16)",
+		orderID, in.CustomerID, nullIfEmpty(in.SessionID), nullIfEmpty(in.RestaurantID), nullIfEmpty(in.ClientOrderID), in.ClientCreatedAt,
+		string(in.Type), nullIfEmpty(in.TableID), in.PickupAt, nullIfEmpty(in.DeliveryAddress), number, day, string(models.OrderStatusPending), now, now, nullIfEmpty(menuVersionID))
+	if err != nil {
+		return "", err
+	}
+	if err := checkAllergens(ctx, tx, orderID, in.CustomerID, orderItems, in.AcknowledgeAllergens, in.CustomerID); err != nil {
+		return "", err
+	}
+	fire, err := courseFiring(ctx, tx, orderID, in.Type, orderItems)
+	if err != nil {
+		return "", err
+	}
+	if err := assignTickets(ctx, tx, orderID, in.RestaurantID, orderItems, fire); err != nil {
+		return "", err
 	}
-
 	for _, oi := range orderItems {
-		_, err = tx.ExecContext(ctx, "INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price, special_instructions) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)", oi.ID, oi.OrderID, oi.MenuItemID, oi.Name, oi.Price, oi.Quantity, oi.TotalPrice, oi.SpecialInstructions)
-		if err != nil {
-			return nil, err
+		if err := insertOrderItem(ctx, tx, oi); err != nil {
+			return "", err
 		}
 	}
+	if in.DiscountCode != "" {
+		if _, err := redeemDiscount(ctx, tx, in.DiscountCode, in.RestaurantID, in.CustomerID, orderID); err != nil {
+			return "", err
+		}
+	}
+	if _, err := repriceOrder(ctx, tx, orderID); err != nil {
+		return "", err
+	}
+	return orderID, nil
+}
 
-	if err = tx.Commit(); err != nil {
+// orderColumns is the select list understood by scanOrder
+const orderColumns = "id, customer_id, COALESCE(session_id, ''), total_amount, status, created_at, updated_at, COALESCE(parent_order_id, ''), COALESCE(split_type, ''), COALESCE(merged_into_order_id, ''), " +
+	"COALESCE(restaurant_id, ''), COALESCE(discount_id, ''), COALESCE(currency, ''), COALESCE(client_order_id, ''), client_created_at, " +
+	"COALESCE(type, ''), COALESCE(table_id, ''), pickup_at, COALESCE(delivery_address, ''), COALESCE(order_number, 0), COALESCE(to_char(business_date, 'YYYY-MM-DD'), ''), " +
+	"estimated_ready_at, COALESCE(eta_manual, FALSE), " +
+	"COALESCE(subtotal, 0), COALESCE(discount_amount, 0), COALESCE(service_charge, 0), COALESCE(delivery_fee, 0), COALESCE(tax_amount, 0), COALESCE(tax_inclusive, FALSE), COALESCE(rounding_adjustment, 0), " +
+	"COALESCE(menu_version_id, '')"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row rowScanner) (*models.Order, error) {
+	var o models.Order
+	p := &o.Pricing
+	var clientCreatedAt, pickupAt, eta sql.NullTime
+	if err := row.Scan(&o.ID, &o.CustomerID, &o.SessionID, &o.TotalAmount, &o.Status, &o.CreatedAt, &o.UpdatedAt, &o.ParentOrderID, &o.SplitType, &o.MergedIntoOrderID,
+		&o.RestaurantID, &o.DiscountID, &o.Currency, &o.ClientOrderID, &clientCreatedAt,
+		&o.Type, &o.TableID, &pickupAt, &o.DeliveryAddress, &o.OrderNumber, &o.BusinessDate,
+		&eta, &o.ETAManual,
+		&p.Subtotal, &p.Discount, &p.ServiceCharge, &p.DeliveryFee, &p.Tax, &p.TaxInclusive, &p.Rounding,
+		&o.MenuVersionID); err != nil {
 		return nil, err
 	}
-
-	ord := &models.Order{ID: orderID, CustomerID: customerID, Items: orderItems, TotalAmount: total, Status: models.OrderStatusPending, CreatedAt: now, UpdatedAt: now}
-	if len(sessionID) > 0 {
-		ord.SessionID = sessionID[0]
+	if clientCreatedAt.Valid {
+		o.ClientCreatedAt = &clientCreatedAt.Time
 	}
-	return ord, nil
+	if pickupAt.Valid {
+		o.PickupAt = &pickupAt.Time
+	}
+	if eta.Valid {
+		o.EstimatedReadyAt = &eta.Time
+	}
+	p.Total = o.TotalAmount
+	return &o, nil
 }
 
 func (s *OrderSQLService) GetOrder(ctx context.Context, id string) (*models.Order, error) {
-	var ord models.Order
-	err := s.db.QueryRowContext(ctx, "SELECT id, customer_id, session_id, total_amount, status, created_at, updated_at FROM orders WHERE id=$1", id).
-		Scan(&ord.ID, &ord.CustomerID, &ord.SessionID, &ord.TotalAmount, &ord.Status, &ord.CreatedAt, &ord.UpdatedAt)
+	ord, err := scanOrder(s.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id=$1", id))
 	if err != nil {
 		return nil, err
 	}
@@ -100,46 +179,52 @@ func (s *OrderSQLService) GetOrder(ctx context.Context, id string) (*models.Orde
 		return nil, err
 	}
 	ord.Items = items
-	return &ord, nil
+	return ord, nil
 }
 
 func (s *OrderSQLService) GetOrderItems(ctx context.Context, orderID string) ([]models.OrderItem, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, order_id, menu_item_id, name, price, quantity, total_price, special_instructions FROM order_items WHERE order_id=$1", orderID)
+	return loadOrderItems(ctx, s.db, orderID)
+}
+
+// GetReceipt renders an order as printable receipt lines
+func (s *OrderSQLService) GetReceipt(ctx context.Context, id string) (*models.Receipt, error) {
+	ord, err := s.GetOrder(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var items []models.OrderItem
-	for rows.Next() {
-		var it models.OrderItem
-		if err := rows.Scan(&it.ID, &it.OrderID, &it.MenuItemID, &it.Name, &it.Price, &it.Quantity, &it.TotalPrice, &it.SpecialInstructions); err != nil {
-			return nil, err
+	r := &models.Receipt{OrderID: ord.ID, OrderNumber: ord.OrderNumber, CreatedAt: ord.CreatedAt, TotalAmount: ord.TotalAmount, Currency: ord.Currency, Pricing: ord.Pricing, Lines: []models.ReceiptLine{}}
+	for _, it := range ord.Items {
+		if it.VoidedAt != nil {
+			continue
 		}
-		items = append(items, it)
+		r.Lines = append(r.Lines, models.ReceiptLine{
+			Label: it.Label(), Quantity: it.Quantity, UnitPrice: it.Price, TotalPrice: it.TotalPrice,
+			SpecialInstructions: it.SpecialInstructions,
+		})
 	}
-	return items, nil
+	return r, nil
 }
 
 // List orders by customer id
 func (s *OrderSQLService) ListOrdersByCustomer(ctx context.Context, customerID string) ([]*models.Order, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, customer_id, session_id, total_amount, status, created_at, updated_at FROM orders WHERE customer_id=$1 ORDER BY created_at DESC", customerID)
+	rows, err := s.db.QueryContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE customer_id=$1 ORDER BY created_at DESC", customerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var res []*models.Order
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.SessionID, &o.TotalAmount, &o.Status, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
-		res = append(res, &o)
+		res = append(res, o)
 	}
 	return res, nil
 }
 
 // Reorder creates a new order from a previous order id (quick reorder)
-func (s *OrderSQLService) Reorder(ctx context.Context, orderID string) (*models.Order, error) {
+func (s *OrderSQLService) Reorder(ctx context.Context, orderID string, req models.ReorderRequest) (*models.Order, error) {
 	ord, err := s.GetOrder(ctx, orderID)
 	if err != nil {
 		return nil, err
@@ -147,94 +232,66 @@ func (s *OrderSQLService) Reorder(ctx context.Context, orderID string) (*models.
 	// build create items
 	var items []CreateOrderItemReq
 	for _, it := range ord.Items {
-		items = append(items, CreateOrderItemReq{MenuItemID: it.MenuItemID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions})
+		// combo components are rebuilt from their combo line
+		if it.VoidedAt != nil || it.ParentItemID != "" {
+			continue
+		}
+		if it.ComboID != "" {
+			combo, err := getCombo(ctx, s.db, it.ComboID)
+			if err != nil {
+				return nil, errors.New("combo " + it.ComboID + " is no longer on the menu")
+			}
+			var components []models.OrderItem
+			for _, c := range ord.Items {
+				if c.ParentItemID == it.ID {
+					components = append(components, c)
+				}
+			}
+			items = append(items, CreateOrderItemReq{ComboID: it.ComboID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions,
+				ComboChoices: comboSelections(combo, components)})
+			continue
+		}
+		var addonIDs []string
+		for _, a := range it.Addons {
+			addonIDs = append(addonIDs, a.ID)
+		}
+		items = append(items, CreateOrderItemReq{MenuItemID: it.MenuItemID, Quantity: it.Quantity, SpecialInstructions: it.SpecialInstructions, VariantID: it.VariantID, AddonIDs: addonIDs,
+			Modifiers: modifierSelections(it.Modifiers)})
 	}
-	return s.CreateOrder(ctx, ord.CustomerID, items, ord.SessionID)
-}
-
-func (s *OrderSQLService) ListOrders(ctx context.Context) ([]*models.Order, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, customer_id, session_id, total_amount, status, created_at, updated_at FROM orders ORDER BY created_at DESC")
-	if err != nil {
-		return nil, err
+	in := NewOrder{
+		CustomerID: ord.CustomerID, SessionID: req.SessionID, RestaurantID: ord.RestaurantID,
+		Type: ord.Type, TableID: req.TableID, DeliveryAddress: ord.DeliveryAddress, Items: items,
+		AcknowledgeAllergens: req.AcknowledgeAllergens,
 	}
-	defer rows.Close()
-	var res []*models.Order
-	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.SessionID, &o.TotalAmount, &o.Status, &o.CreatedAt, &o.UpdatedAt); err != nil {
-			return nil, err
-		}
-		res = append(res, &o)
+	// the earlier order's session is closed: a repeat is eaten in only at the session or table
+	// given now, and a dine-in order repeated without one becomes takeaway
+	if in.SessionID != "" || in.TableID != "" {
+		in.Type, in.DeliveryAddress = models.OrderTypeDineIn, ""
+	} else if in.Type == models.OrderTypeDineIn {
+		in.Type = models.OrderTypeTakeaway
 	}
-	return res, nil
+	return s.CreateOrder(ctx, in)
 }
 
-func (s *OrderSQLService) UpdateOrderStatus(ctx context.Context, id string, status models.OrderStatus) error {
-	_, err := s.db.ExecContext(ctx, "UPDATE orders SET status=$1, updated_at=$2 WHERE id=$3", status, time.Now(), id)
-	return err
+// ConfirmPaidOrder confirms a pending order after a successful payment; see confirmPaidOrder
+func (s *OrderSQLService) ConfirmPaidOrder(ctx context.Context, orderID string, t OrderTransition) error {
+	return confirmPaidOrder(ctx, s.db, orderID, t)
 }
 
-// SetOrderETA updates the estimated ready time for an order
+// UpdateOrderStatus moves an order through the shared state machine and audits the change
+func (s *OrderSQLService) UpdateOrderStatus(ctx context.Context, id string, t OrderTransition) error {
+	return transitionOrder(ctx, s.db, id, t)
+}
+
+// SetOrderETA pins the estimated ready time for an order; RefreshETAs leaves it alone until
+// ClearOrderETA hands it back to the estimator.
 func (s *OrderSQLService) SetOrderETA(ctx context.Context, id string, eta time.Time) error {
-	_, err := s.db.ExecContext(ctx, "UPDATE orders SET estimated_ready_at=$1, updated_at=now() WHERE id=$2", eta, id)
+	_, err := s.db.ExecContext(ctx, "UPDATE orders SET estimated_ready_at=$1, eta_manual=TRUE, updated_at=now() WHERE id=$2", eta, id)
 	return err
 }
 
-// SyncOrders accepts a batch of orders (for offline sync) and creates them in a transaction.
-func (s *OrderSQLService) SyncOrders(ctx context.Context, customerID string, itemsBatch [][]CreateOrderItemReq, sessionID string) ([]*models.Order, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
-	var created []*models.Order
-	for _, items := range itemsBatch {
-		// use same logic as CreateOrder but within tx
-		orderID := uuid.New().String()
-		now := time.Now()
-		var total float64
-		var orderItems []models.OrderItem
-		for _, it := range items {
-			var mi models.MenuItem
-			e := tx.QueryRowContext(ctx, "SELECT id, name, price FROM menu_items WHERE id=$1 AND available=TRUE", it.MenuItemID).
-				Scan(&mi.ID, &mi.Name, &mi.Price)
-			if e != nil {
-				err = e
-				return nil, err
-			}
-			lineTotal := mi.Price * float64(it.Quantity)
-			total += lineTotal
-			orderItems = append(orderItems, models.OrderItem{ID: uuid.New().String(), OrderID: orderID, MenuItemID: mi.ID, Name: mi.Name, Price: mi.Price, Quantity: it.Quantity, TotalPrice: lineTotal, SpecialInstructions: it.SpecialInstructions})
-		}
-
-		if sessionID != "" {
-			_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, session_id, total_amount, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)", orderID, customerID, sessionID, total, string(models.OrderStatusPending), now, now)
-		} else {
-			_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, total_amount, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6)", orderID, customerID, total, string(models.OrderStatusPending), now, now)
-		}
-		if err != nil {
-			return nil, err
-		}
-		for _, oi := range orderItems {
-			_, err = tx.ExecContext(ctx, "INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price, special_instructions) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)", oi.ID, oi.OrderID, oi.MenuItemID, oi.Name, oi.Price, oi.Quantity, oi.TotalPrice, oi.SpecialInstructions)
-			if err != nil {
-				return nil, err
-			}
-		}
-		ord := &models.Order{ID: orderID, CustomerID: customerID, Items: orderItems, TotalAmount: total, Status: models.OrderStatusPending, CreatedAt: now, UpdatedAt: now}
-		if sessionID != "" {
-			ord.SessionID = sessionID
-		}
-		created = append(created, ord)
-	}
-
-	if err = tx.Commit(); err != nil {
-		return nil, err
-	}
-	return created, nil
+// ClearOrderETA removes a manual ETA override
+func (s *OrderSQLService) ClearOrderETA(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE orders SET eta_manual=FALSE, updated_at=now() WHERE id=$1", id)
+	return err
 }
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrOrderNotFound is returned when a status change targets an unknown order.
+var ErrOrderNotFound = errors.New("order not found")
+
+// TransitionError is returned when an order cannot move to the requested status,
+// either because the jump is not allowed or because a guard rejected it.
+type TransitionError struct {
+	OrderID string
+	From    models.OrderStatus
+	To      models.OrderStatus
+	Reason  string
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("invalid status transition from %s to %s: %s", e.From, e.To, e.Reason)
+	}
+	return fmt.Sprintf("invalid status transition from %s to %s", e.From, e.To)
+}
+
+// OrderTransition describes a requested status change and who asked for it.
+type OrderTransition struct {
+	To     models.OrderStatus
+	UserID string
+	Reason string
+	// Recall follows a recall edge instead of the normal graph; only the kitchen recall path sets it
+	Recall bool
+}
+
+// sqlQueryer is satisfied by both *sql.DB and *sql.Tx
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// TransitionGuard runs before a transition is written; returning an error rejects it.
+type TransitionGuard func(ctx context.Context, q sqlQueryer, orderID string) error
+
+// TransitionHook runs inside the transaction after an order has entered a status.
+type TransitionHook func(ctx context.Context, q sqlQueryer, orderID string) error
+
+// OrderStateMachine is the single source of truth for order status changes.
+type OrderStateMachine struct {
+	transitions map[models.OrderStatus][]models.OrderStatus
+	recalls     map[models.OrderStatus][]models.OrderStatus
+	guards      map[models.OrderStatus][]TransitionGuard
+	hooks       map[models.OrderStatus][]TransitionHook
+}
+
+func NewOrderStateMachine() *OrderStateMachine {
+	m := &OrderStateMachine{
+		transitions: map[models.OrderStatus][]models.OrderStatus{
+			models.OrderStatusPending:   {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+			models.OrderStatusConfirmed: {models.OrderStatusPreparing, models.OrderStatusCancelled},
+			models.OrderStatusPreparing: {models.OrderStatusReady, models.OrderStatusCancelled},
+			models.OrderStatusReady:     {models.OrderStatusCompleted},
+			models.OrderStatusCompleted: {},
+			models.OrderStatusCancelled: {},
+		},
+		// ready orders go back to preparing only when the kitchen recalls a ticket
+		recalls: map[models.OrderStatus][]models.OrderStatus{
+			models.OrderStatusReady: {models.OrderStatusPreparing},
+		},
+		guards: map[models.OrderStatus][]TransitionGuard{},
+		hooks:  map[models.OrderStatus][]TransitionHook{},
+	}
+	m.Guard(models.OrderStatusCompleted, requireFullyPaid)
+	m.OnEnter(models.OrderStatusConfirmed, stampStatusTime("confirmed_at"))
+	m.OnEnter(models.OrderStatusPreparing, stampStatusTime("preparing_at"))
+	// prep times are learned before ready_at is stamped, so a recalled order readied again is
+	// neither learned twice nor given a later ready time
+	m.OnEnter(models.OrderStatusReady, learnPrepTimes, stampFirstStatusTime("ready_at"))
+	return m
+}
+
+// orderStates is shared by every service that changes order status.
+var orderStates = NewOrderStateMachine()
+
+// Guard registers a check that must pass before an order can enter status to.
+func (m *OrderStateMachine) Guard(to models.OrderStatus, g TransitionGuard) {
+	m.guards[to] = append(m.guards[to], g)
+}
+
+// OnEnter registers hooks that run after an order enters status to.
+func (m *OrderStateMachine) OnEnter(to models.OrderStatus, hooks ...TransitionHook) {
+	m.hooks[to] = append(m.hooks[to], hooks...)
+}
+
+// Allowed reports whether the graph permits moving from one status to another.
+func (m *OrderStateMachine) Allowed(from, to models.OrderStatus) bool {
+	return hasStatus(m.transitions[from], to)
+}
+
+// Recallable reports whether a recall may take an order from one status back to another.
+func (m *OrderStateMachine) Recallable(from, to models.OrderStatus) bool {
+	return hasStatus(m.recalls[from], to)
+}
+
+func hasStatus(list []models.OrderStatus, st models.OrderStatus) bool {
+	for _, s := range list {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply validates and writes a transition inside tx, recording it in order_audits.
+// It returns the status the order had before the change.
+func (m *OrderStateMachine) Apply(ctx context.Context, tx *sql.Tx, orderID string, t OrderTransition) (models.OrderStatus, error) {
+	var current models.OrderStatus
+	err := tx.QueryRowContext(ctx, "SELECT status FROM orders WHERE id=$1 FOR UPDATE", orderID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return "", ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	allowed := m.Allowed(current, t.To)
+	if t.Recall {
+		allowed = m.Recallable(current, t.To)
+	}
+	if !allowed {
+		return current, &TransitionError{OrderID: orderID, From: current, To: t.To}
+	}
+	for _, g := range m.guards[t.To] {
+		if gerr := g(ctx, tx, orderID); gerr != nil {
+			return current, &TransitionError{OrderID: orderID, From: current, To: t.To, Reason: gerr.Error()}
+		}
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status=$1, updated_at=$2 WHERE id=$3", string(t.To), now, orderID); err != nil {
+		return current, err
+	}
+	if err := writeOrderAudit(ctx, tx, orderID, "status_changed", t.UserID, map[string]interface{}{
+		"from":   current,
+		"to":     t.To,
+		"reason": t.Reason,
+	}); err != nil {
+		return current, err
+	}
+	for _, h := range m.hooks[t.To] {
+		if err := h(ctx, tx, orderID); err != nil {
+			return current, err
+		}
+	}
+	return current, nil
+}
+
+// writeOrderAudit appends a row to order_audits with details encoded as JSON.
+func writeOrderAudit(ctx context.Context, q sqlQueryer, orderID, action, userID string, details interface{}) error {
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT INTO order_audits (id, order_id, action, user_id, details, created_at) VALUES ($1,$2,$3,$4,$5,$6)",
+		uuid.New().String(), orderID, action, userID, string(payload), time.Now())
+	return err
+}
+
+// requireFullyPaid rejects completion while completed payments are short of the order total.
+func requireFullyPaid(ctx context.Context, q sqlQueryer, orderID string) error {
+	var total, paid models.Money
+	if err := q.QueryRowContext(ctx, "SELECT total_amount FROM orders WHERE id=$1", orderID).Scan(&total); err != nil {
+		return err
+	}
+	// payments taken against even-split bill shares count towards the parent order
+	if err := q.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM payments WHERE status=$2 AND (order_id=$1 OR order_id IN (SELECT id FROM orders WHERE parent_order_id=$1 AND split_type=$3))",
+		orderID, string(models.PaymentStatusCompleted), string(models.SplitEvenly)).Scan(&paid); err != nil {
+		return err
+	}
+	if paid < total {
+		return fmt.Errorf("order is not fully paid (%s of %s)", paid, total)
+	}
+	return nil
+}
+
+// confirmPaidOrder confirms a pending order once a payment for it succeeds. Orders already past
+// pending are left alone, so repeated payment notifications are harmless.
+func confirmPaidOrder(ctx context.Context, db *sql.DB, orderID string, t OrderTransition) error {
+	var current models.OrderStatus
+	err := db.QueryRowContext(ctx, "SELECT status FROM orders WHERE id=$1", orderID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
+	if err != nil || current != models.OrderStatusPending {
+		return err
+	}
+	t.To = models.OrderStatusConfirmed
+	return transitionOrder(ctx, db, orderID, t)
+}
+
+// transitionOrder runs a single transition in its own transaction.
+func transitionOrder(ctx context.Context, db *sql.DB, orderID string, t OrderTransition) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := orderStates.Apply(ctx, tx, orderID, t); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
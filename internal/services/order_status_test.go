@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"restaurant-system/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderStateMachineAllowed(t *testing.T) {
+	m := NewOrderStateMachine()
+
+	assert.True(t, m.Allowed(models.OrderStatusPending, models.OrderStatusConfirmed))
+	assert.True(t, m.Allowed(models.OrderStatusPreparing, models.OrderStatusCancelled))
+	assert.True(t, m.Allowed(models.OrderStatusReady, models.OrderStatusCompleted))
+
+	assert.False(t, m.Allowed(models.OrderStatusPending, models.OrderStatusCompleted))
+	assert.False(t, m.Allowed(models.OrderStatusReady, models.OrderStatusCancelled))
+	assert.False(t, m.Allowed(models.OrderStatusCompleted, models.OrderStatusPending))
+	assert.False(t, m.Allowed(models.OrderStatusCancelled, models.OrderStatusConfirmed))
+	// only a kitchen recall takes a ready order back
+	assert.False(t, m.Allowed(models.OrderStatusReady, models.OrderStatusPreparing))
+	assert.True(t, m.Recallable(models.OrderStatusReady, models.OrderStatusPreparing))
+	assert.False(t, m.Recallable(models.OrderStatusCompleted, models.OrderStatusPreparing))
+}
+
+func TestTransitionErrorMessage(t *testing.T) {
+	err := &TransitionError{From: models.OrderStatusReady, To: models.OrderStatusCompleted, Reason: "order is not fully paid"}
+	assert.Equal(t, "invalid status transition from ready to completed: order is not fully paid", err.Error())
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"restaurant-system/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// isInfraError reports whether err came from the database or the request context rather than
+// from validating the order. Those abort a sync so the device retries the whole batch.
+func isInfraError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// findClientOrder returns the order a customer already synced under clientOrderID, or nil.
+func (s *OrderSQLService) findClientOrder(ctx context.Context, customerID, clientOrderID string) (*models.Order, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM orders WHERE customer_id=$1 AND client_order_id=$2", customerID, clientOrderID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, id)
+}
+
+// SyncOrders creates orders queued on offline devices. Each order commits on its own, so one
+// unavailable item only rejects that order. Orders are keyed by ClientOrderID: replaying a batch
+// reports already-synced orders as duplicates instead of creating them again. An error is only
+// returned for database failures, after which the batch can safely be retried.
+func (s *OrderSQLService) SyncOrders(ctx context.Context, batch []NewOrder) ([]models.SyncResult, error) {
+	results := make([]models.SyncResult, 0, len(batch))
+	seen := map[string]bool{}
+	for _, in := range batch {
+		res := models.SyncResult{ClientOrderID: in.ClientOrderID}
+		if in.ClientOrderID == "" {
+			res.Status, res.Reason = models.SyncRejected, "client_order_id required"
+			results = append(results, res)
+			continue
+		}
+		if seen[in.ClientOrderID] {
+			res.Status, res.Reason = models.SyncRejected, "client_order_id repeated in batch"
+			results = append(results, res)
+			continue
+		}
+		seen[in.ClientOrderID] = true
+
+		existing, err := s.findClientOrder(ctx, in.CustomerID, in.ClientOrderID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			res.Status, res.Order = models.SyncDuplicate, existing
+			results = append(results, res)
+			continue
+		}
+
+		ord, err := s.CreateOrder(ctx, in)
+		switch {
+		case err == nil:
+			res.Status, res.Order = models.SyncCreated, ord
+		case isUniqueViolation(err):
+			// another request synced the same order concurrently
+			if existing, err = s.findClientOrder(ctx, in.CustomerID, in.ClientOrderID); err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				res.Status, res.Reason = models.SyncRejected, "client_order_id conflict"
+				break
+			}
+			res.Status, res.Order = models.SyncDuplicate, existing
+		case isInfraError(err):
+			return nil, err
+		default:
+			res.Status, res.Reason = models.SyncRejected, err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
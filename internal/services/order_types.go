@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// maxPickupAhead bounds how far in the future a scheduled pickup may be placed.
+const maxPickupAhead = 7 * 24 * time.Hour
+
+// KitchenFireLead is how long before a scheduled pickup its order joins the kitchen queue.
+const KitchenFireLead = 30 * time.Minute
+
+// resolveOrderType defaults the order type and checks the fields each type requires.
+// Dine-in orders take their table from the session when one is given.
+func resolveOrderType(ctx context.Context, q sqlQueryer, in *NewOrder) error {
+	if in.Type == "" {
+		in.Type = models.OrderTypeTakeaway
+		if in.SessionID != "" || in.TableID != "" {
+			in.Type = models.OrderTypeDineIn
+		}
+	}
+	if !in.Type.IsValid() {
+		return errors.New("unknown order type " + string(in.Type))
+	}
+
+	switch in.Type {
+	case models.OrderTypeDineIn:
+		if in.PickupAt != nil || in.DeliveryAddress != "" {
+			return errors.New("dine-in orders cannot have a pickup time or delivery address")
+		}
+		if in.SessionID != "" {
+			var tableID string
+			var status models.SessionStatus
+			err := q.QueryRowContext(ctx, "SELECT table_id, status FROM sessions WHERE id=$1", in.SessionID).Scan(&tableID, &status)
+			if err == sql.ErrNoRows {
+				return errors.New("session " + in.SessionID + " not found")
+			}
+			if err != nil {
+				return err
+			}
+			if status != models.SessionStatusActive {
+				return errors.New("session " + in.SessionID + " is not active")
+			}
+			if in.TableID != "" && in.TableID != tableID {
+				return errors.New("table does not match the session's table")
+			}
+			in.TableID = tableID
+		}
+		if in.TableID == "" {
+			return errors.New("dine-in orders need a session or table")
+		}
+	case models.OrderTypeTakeaway:
+		if in.DeliveryAddress != "" {
+			return errors.New("takeaway orders cannot have a delivery address")
+		}
+		if in.PickupAt != nil {
+			now := time.Now()
+			if !in.PickupAt.After(now) {
+				return errors.New("pickup time must be in the future")
+			}
+			if in.PickupAt.After(now.Add(maxPickupAhead)) {
+				return errors.New("pickup time is too far ahead")
+			}
+		}
+	case models.OrderTypeDelivery:
+		if in.DeliveryAddress == "" {
+			return errors.New("delivery orders need a delivery address")
+		}
+		if in.PickupAt != nil {
+			return errors.New("delivery orders cannot have a pickup time")
+		}
+	}
+	return nil
+}
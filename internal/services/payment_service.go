@@ -16,14 +16,14 @@ import (
 
 // PaymentService is required by handlers/payment_handler.go
 type PaymentService interface {
-	CreatePayment(ctx context.Context, restaurantID uint, orderID uint, amountCents int64, provider string) (*models.Payment, error)
+	CreatePayment(ctx context.Context, restaurantID uint, orderID uint, amount models.Money, provider string) (*models.Payment, error)
 	GetPayment(ctx context.Context, restaurantID uint, id uint) (*models.Payment, error)
 	// callback handlers used by notify endpoints
 	HandleTelebirrCallback(payload map[string]string) error
 	HandleChapaCallback(payload map[string]string) error
 	HandleMpesaCallback(payload map[string]string) error
-	RequestRefund(ctx context.Context, paymentID string, amount float64, reason string) (*models.Refund, error)
-	ApplyPartialPayment(ctx context.Context, orderID string, amount float64) error
+	RequestRefund(ctx context.Context, paymentID string, amount models.Money, reason string) (*models.Refund, error)
+	ApplyPartialPayment(ctx context.Context, orderID string, amount models.Money) error
 }
 
 type PaymentSQLService struct {
@@ -45,27 +45,35 @@ func normalizeMethod(provider string) models.PaymentMethod {
 	}
 }
 
-func (s *PaymentSQLService) CreatePayment(ctx context.Context, restaurantID uint, orderID uint, amountCents int64, provider string) (*models.Payment, error) {
-	if amountCents <= 0 {
+// orderCurrency returns the currency payments against orderID are taken in.
+func orderCurrency(ctx context.Context, q sqlQueryer, orderID string) string {
+	var currency string
+	if err := q.QueryRowContext(ctx, "SELECT COALESCE(currency, '') FROM orders WHERE id=$1", orderID).Scan(&currency); err != nil || currency == "" {
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+func (s *PaymentSQLService) CreatePayment(ctx context.Context, restaurantID uint, orderID uint, amount models.Money, provider string) (*models.Payment, error) {
+	if amount <= 0 {
 		return nil, errors.New("amount must be positive")
 	}
-	// Basic existence check for order
-	var exists bool
-	_ = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)", strconv.FormatUint(uint64(orderID), 10)).Scan(&exists)
+	oid := strconv.FormatUint(uint64(orderID), 10)
 
 	id := uuid.New().String()
 	p := &models.Payment{
 		ID:            id,
-		OrderID:       strconv.FormatUint(uint64(orderID), 10),
-		Amount:        float64(amountCents) / 100.0,
+		OrderID:       oid,
+		Amount:        amount,
+		Currency:      orderCurrency(ctx, s.db, oid),
 		Method:        normalizeMethod(provider),
 		Status:        models.PaymentStatusCompleted,
 		TransactionID: "",
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
-	_, err := s.db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, method, status, transaction_id, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)",
-		p.ID, p.OrderID, p.Amount, string(p.Method), string(p.Status), p.TransactionID, p.CreatedAt, p.UpdatedAt,
+	_, err := s.db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, currency, method, status, transaction_id, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		p.ID, p.OrderID, p.Amount, p.Currency, string(p.Method), string(p.Status), p.TransactionID, p.CreatedAt, p.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -75,9 +83,9 @@ func (s *PaymentSQLService) CreatePayment(ctx context.Context, restaurantID uint
 
 func (s *PaymentSQLService) GetPayment(ctx context.Context, restaurantID uint, id uint) (*models.Payment, error) {
 	var p models.Payment
-	err := s.db.QueryRowContext(ctx, "SELECT id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at FROM payments WHERE id = $1",
+	err := s.db.QueryRowContext(ctx, "SELECT id, order_id, amount, COALESCE(currency, ''), method, status, transaction_id, phone_number, created_at, updated_at FROM payments WHERE id = $1",
 		strconv.FormatUint(uint64(id), 10),
-	).Scan(&p.ID, &p.OrderID, &p.Amount, &p.Method, &p.Status, &p.TransactionID, &p.PhoneNumber, &p.CreatedAt, &p.UpdatedAt)
+	).Scan(&p.ID, &p.OrderID, &p.Amount, &p.Currency, &p.Method, &p.Status, &p.TransactionID, &p.PhoneNumber, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -115,9 +123,12 @@ func (s *PaymentSQLService) HandleTelebirrCallback(payload map[string]string) er
 		return err
 	}
 
-	// If payment completed, mark order as completed
+	// A completed payment confirms the order, as Telebirr B2B payments do; the order is completed
+	// by staff once served
 	if status == "completed" {
-		_, _ = s.db.ExecContext(context.Background(), "UPDATE orders SET status=$1, updated_at=now() WHERE id=$2", string(models.OrderStatusCompleted), oid)
+		if err := confirmPaidOrder(context.Background(), s.db, oid, OrderTransition{UserID: "payment_callback", Reason: "payment " + tid + " completed"}); err != nil {
+			return errors.New("payment " + tid + " recorded but order " + oid + " not confirmed: " + err.Error())
+		}
 	}
 
 	return nil
@@ -133,30 +144,63 @@ func (s *PaymentSQLService) HandleMpesaCallback(payload map[string]string) error
 	return s.HandleTelebirrCallback(payload)
 }
 
-// RequestRefund creates a refund record; actual refund processing (gateway) is out of scope
-func (s *PaymentSQLService) RequestRefund(ctx context.Context, paymentID string, amount float64, reason string) (*models.Refund, error) {
+// RequestRefund creates a refund record; actual refund processing (gateway) is out of scope.
+// Refunds that are not rejected may not add up to more than the payment.
+func (s *PaymentSQLService) RequestRefund(ctx context.Context, paymentID string, amount models.Money, reason string) (*models.Refund, error) {
 	if amount <= 0 {
 		return nil, errors.New("invalid amount")
 	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var paid, refunded models.Money
+	var currency string
+	err = tx.QueryRowContext(ctx, "SELECT amount, COALESCE(currency, ''), COALESCE((SELECT SUM(amount) FROM refunds WHERE payment_id=$1 AND status<>$2), 0) FROM payments WHERE id=$1 FOR UPDATE",
+		paymentID, string(models.RefundStatusRejected)).Scan(&paid, &currency, &refunded)
+	if err == sql.ErrNoRows {
+		err = errors.New("payment not found")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if amount > paid-refunded {
+		err = errors.New("refund exceeds remaining payment amount of " + (paid - refunded).String())
+		return nil, err
+	}
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
 	id := uuid.New().String()
-	_, err := s.db.ExecContext(ctx, "INSERT INTO refunds (id, payment_id, amount, reason, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,now(),now())",
-		id, paymentID, amount, reason, string(models.RefundStatusPending))
+	_, err = tx.ExecContext(ctx, "INSERT INTO refunds (id, payment_id, amount, currency, reason, status, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,now(),now())",
+		id, paymentID, amount, currency, reason, string(models.RefundStatusPending))
 	if err != nil {
 		return nil, err
 	}
-	r := &models.Refund{ID: id, PaymentID: paymentID, Amount: amount, Reason: reason, Status: models.RefundStatusPending}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	r := &models.Refund{ID: id, PaymentID: paymentID, Amount: amount, Currency: currency, Reason: reason, Status: models.RefundStatusPending}
 	return r, nil
 }
 
 // ApplyPartialPayment records a partial payment against an order by creating a payment record and marking payments partial
-func (s *PaymentSQLService) ApplyPartialPayment(ctx context.Context, orderID string, amount float64) error {
+func (s *PaymentSQLService) ApplyPartialPayment(ctx context.Context, orderID string, amount models.Money) error {
 	if amount <= 0 {
 		return errors.New("invalid amount")
 	}
 	// Create a payment record marked as partial
 	id := uuid.New().String()
-	_, err := s.db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, method, status, transaction_id, refunded_amount, is_partial, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,now(),now())",
-		id, orderID, amount, string(models.PaymentMethodCard), string(models.PaymentStatusCompleted), "", 0, true)
+	_, err := s.db.ExecContext(ctx, "INSERT INTO payments (id, order_id, amount, currency, method, status, transaction_id, refunded_amount, is_partial, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,now(),now())",
+		id, orderID, amount, orderCurrency(ctx, s.db, orderID), string(models.PaymentMethodCard), string(models.PaymentStatusCompleted), "", 0, true)
 	if err != nil {
 		return err
 	}
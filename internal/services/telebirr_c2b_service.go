@@ -102,7 +102,7 @@ type C2BQueryResponse struct {
 	GmtPayment  string `json:"gmt_payment"`
 }
 
-func (s *TelebirrC2BService) CreateH5Payment(orderID string, amount float64, subject, body string) (*models.TelebirrC2BOrder, error) {
+func (s *TelebirrC2BService) CreateH5Payment(orderID string, amount models.Money, subject, body string) (*models.TelebirrC2BOrder, error) {
 	outTradeNo := fmt.Sprintf("REST_C2B_%s_%d", orderID, time.Now().Unix())
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 
@@ -111,7 +111,7 @@ func (s *TelebirrC2BService) CreateH5Payment(orderID string, amount float64, sub
 		OutTradeNo:     outTradeNo,
 		Subject:        subject,
 		Body:           body,
-		TotalAmount:    fmt.Sprintf("%.2f", amount),
+		TotalAmount:    amount.String(),
 		TimeoutExpress: "30m",
 		PassbackParams: fmt.Sprintf("order_id=%s", orderID),
 	}
@@ -216,11 +216,11 @@ func (s *TelebirrC2BService) callH5PayAPI(orderReq models.TelebirrC2BOrderReques
 }
 
 // RefundC2B performs a refund through Telebirr and updates local state.
-func (s *TelebirrC2BService) RefundC2B(outTradeNo string, refundAmount float64, refundReason string) error {
+func (s *TelebirrC2BService) RefundC2B(outTradeNo string, refundAmount models.Money, refundReason string) error {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	biz := C2BRefundBiz{
 		OutTradeNo:   outTradeNo,
-		RefundAmount: fmt.Sprintf("%.2f", refundAmount),
+		RefundAmount: refundAmount.String(),
 		RefundReason: refundReason,
 	}
 	bizJSON, err := json.Marshal(biz)
@@ -394,9 +394,9 @@ func (s *TelebirrC2BService) ProcessC2BNotification(notification map[string]stri
 	}
 
 	// Parse total amount
-	totalAmount := 0.0
+	var totalAmount models.Money
 	if amountStr, ok := notification["total_amount"]; ok {
-		fmt.Sscanf(amountStr, "%f", &totalAmount)
+		totalAmount, _ = models.ParseMoney(amountStr)
 	}
 
 	// Create notification record
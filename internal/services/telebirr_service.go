@@ -122,7 +122,7 @@ func (s *TelebirrService) acquireNewToken() (*models.TelebirrToken, error) {
 	return &token, nil
 }
 
-func (s *TelebirrService) CreatePrepaidOrder(orderID string, amount float64, subject, body string) (*models.TelebirrOrder, error) {
+func (s *TelebirrService) CreatePrepaidOrder(orderID string, amount models.Money, subject, body string) (*models.TelebirrOrder, error) {
 	token, err := s.GetValidToken()
 	if err != nil {
 		return nil, err
@@ -135,7 +135,7 @@ func (s *TelebirrService) CreatePrepaidOrder(orderID string, amount float64, sub
 	orderReq := OrderRequest{
 		AppID:          s.config.AppID,
 		MerchOrderID:   merchOrderID,
-		TotalAmount:    fmt.Sprintf("%.2f", amount),
+		TotalAmount:    amount.String(),
 		Subject:        subject,
 		Body:           body,
 		NotifyURL:      s.config.NotifyURL,
@@ -255,9 +255,9 @@ func (s *TelebirrService) ProcessNotification(notification map[string]string) er
 		return err
 	}
 
-	totalAmount := 0.0
+	var totalAmount models.Money
 	if amountStr, ok := notification["total_amount"]; ok {
-		fmt.Sscanf(amountStr, "%f", &totalAmount)
+		totalAmount, _ = models.ParseMoney(amountStr)
 	}
 
 	notif := models.TelebirrNotification{
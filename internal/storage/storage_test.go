@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st := NewLocal(dir, "/static/uploads/")
+
+	assert.NoError(t, st.Put(ctx, "menu/items/i1/card.jpg", "image/jpeg", []byte("one")))
+	assert.NoError(t, st.Put(ctx, "menu/items/i1/card.jpg", "image/jpeg", []byte("two")))
+	b, err := os.ReadFile(filepath.Join(dir, "menu", "items", "i1", "card.jpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+	assert.Equal(t, "/static/uploads/menu/items/i1/card.jpg", st.URL("menu/items/i1/card.jpg"))
+
+	assert.NoError(t, st.Delete(ctx, "menu/items/i1/card.jpg"))
+	assert.NoError(t, st.Delete(ctx, "menu/items/i1/card.jpg"))
+	_, err = os.Stat(filepath.Join(dir, "menu", "items", "i1", "card.jpg"))
+	assert.True(t, os.IsNotExist(err))
+
+	for _, key := range []string{"", "../escape.jpg", "menu/../../escape.jpg", "/abs.jpg"} {
+		assert.Error(t, st.Put(ctx, key, "image/jpeg", []byte("x")), key)
+	}
+}
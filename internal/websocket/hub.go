@@ -16,10 +16,13 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// Client is one websocket connection. Kitchen displays connect with station set and then only
+// receive that station's tickets.
 type Client struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
-	role string
+	conn    *websocket.Conn
+	mu      sync.Mutex
+	role    string
+	station string
 }
 
 type Hub struct {
@@ -39,6 +42,9 @@ func (h *Hub) Run() {
 	for msg := range h.broadcast {
 		h.mu.RLock()
 		for c := range h.clients {
+			if c.station != "" {
+				continue
+			}
 			c.send(msg)
 		}
 		h.mu.RUnlock()
@@ -71,6 +77,34 @@ func (h *Hub) BroadcastToKitchen(v interface{}) {
 	h.mu.RUnlock()
 }
 
+// BroadcastToStation sends a ticket message to the displays of one station and to expo clients.
+// Tickets with no station go to kitchen clients that are not bound to a station.
+func (h *Hub) BroadcastToStation(stationID string, v interface{}) {
+	h.mu.RLock()
+	for c := range h.clients {
+		switch {
+		case c.role == "expo":
+		case stationID != "" && c.station == stationID:
+		case stationID == "" && c.role == "kitchen" && c.station == "":
+		default:
+			continue
+		}
+		c.send(v)
+	}
+	h.mu.RUnlock()
+}
+
+// BroadcastToExpo sends a message only to clients with role expo
+func (h *Hub) BroadcastToExpo(v interface{}) {
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.role == "expo" {
+			c.send(v)
+		}
+	}
+	h.mu.RUnlock()
+}
+
 // HandleWebSocket upgrades the connection and registers the client
 func HandleWebSocket(h *Hub, w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -79,7 +113,7 @@ func HandleWebSocket(h *Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	role := r.URL.Query().Get("role")
-	client := &Client{conn: conn, role: role}
+	client := &Client{conn: conn, role: role, station: r.URL.Query().Get("station")}
 
 	h.mu.Lock()
 	h.clients[client] = true
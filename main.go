@@ -10,9 +10,11 @@ import (
 	"restaurant-system/internal/handlers"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
+	"restaurant-system/internal/storage"
 	"restaurant-system/internal/websocket"
 
 	"os"
+	"time"
 
 	_ "restaurant-system/docs" // Import generated docs
 	"github.com/gin-gonic/gin"
@@ -58,8 +60,20 @@ func (a *OrderServiceAdapter) GetOrder(orderID string) (*models.Order, error) {
 	return a.service.GetOrder(context.Background(), orderID)
 }
 
+// UpdateOrderStatus maps Telebirr payment states onto the order state machine.
+// A successful payment confirms a pending order; "pending_payment" leaves it untouched.
 func (a *OrderServiceAdapter) UpdateOrderStatus(orderID, status string) error {
-	return a.service.UpdateOrderStatus(context.Background(), orderID, models.OrderStatus(status))
+	ctx := context.Background()
+	t := services.OrderTransition{UserID: "telebirr", Reason: "telebirr payment " + status}
+	switch status {
+	case "paid":
+		return a.service.ConfirmPaidOrder(ctx, orderID, t)
+	case "pending_payment":
+		return nil
+	default:
+		t.To = models.OrderStatus(status)
+	}
+	return a.service.UpdateOrderStatus(ctx, orderID, t)
 }
 
 func main() {
@@ -83,6 +97,8 @@ func main() {
 	authService := services.NewAuthService(db)
 	authBasicService := services.NewAuthBasicService(db.Conn())
 	menuService := services.NewMenuSQLService(db.Conn())
+	// uploaded menu images are kept under the static directory served below
+	menuImageService := services.NewMenuImageService(db.Conn(), storage.NewLocal("./web/static/uploads", "/static/uploads"))
 	tableService := services.NewTableService(db.Conn())
 	sessionService := services.NewSessionService(db.Conn())
 	reservationService := services.NewReservationService(db.Conn())
@@ -121,13 +137,13 @@ func main() {
 	reviewsAPI := handlers.NewReviewsAPI(menuService)
 	tablesAPI := handlers.NewTablesAPI(tableService)
 	sessionsAPI := handlers.NewSessionsAPI(sessionService)
-	kitchenAPI := handlers.NewKitchenAPI(orderService)
+	kitchenAPI := handlers.NewKitchenAPI(orderService, hub)
 	reservationsAPI := handlers.NewReservationsAPI(reservationService)
 	notificationsAPI := handlers.NewNotificationsAPI(notificationService)
 	// New grouped APIs
-	staffAPI := handlers.NewStaffAPI()
+	staffAPI := handlers.NewStaffAPI(orderService, hub)
 	customerAPI := handlers.NewCustomerAPI()
-	enterpriseAPI := handlers.NewEnterpriseAPI(gdb, hub)
+	enterpriseAPI := handlers.NewEnterpriseAPI(gdb, hub, orderService)
 	orderWSHandler := handlers.NewOrderWSHandler(hub)
 
 	// Initialize Telebirr B2B service and handler
@@ -205,17 +221,25 @@ func main() {
 			orders.POST("", orderAPI.CreateOrder)
 			orders.POST("/sync", orderAPI.SyncOrders)
 			orders.GET("/:id", orderAPI.GetOrder)
+			orders.GET("/:id/receipt", orderAPI.GetReceipt)
 			orders.GET("", orderAPI.ListOrders)
 			orders.GET("/customer/:customer_id", orderAPI.ListOrdersByCustomer)
 			orders.POST("/:id/reorder", orderAPI.Reorder)
 			orders.PUT("/:id/status", orderAPI.UpdateOrderStatus)
 			orders.PUT("/:id/eta", orderAPI.SetETA)
+			orders.DELETE("/:id/eta", orderAPI.ClearETA)
+			orders.POST("/:id/items", handlers.RequireStaff(), orderAPI.AddOrderItems)
+			orders.DELETE("/:id/items/:item_id", handlers.RequireStaff(), orderAPI.RemoveOrderItem)
+			orders.POST("/:id/items/:item_id/void", handlers.RequireStaff(), orderAPI.VoidOrderItem)
 		}
 
 		// Menu (QR view remains)
 		api.GET("/restaurant/:restaurant_id/table/:table_id/menu", menuAPI.GetQRMenu)
-		// Menu management (GORM-backed)
-		mm := handlers.NewMenuManagementAPI(gdb, hub)
+		api.GET("/restaurant/:restaurant_id/table/:table_id/menu/search", menuAPI.SearchMenu)
+		// Menu management, on the same menu service as the QR menu and ordering
+		mm := handlers.NewMenuManagementAPI(menuService, hub)
+		mm.PublishScheduledMenus(time.Minute)
+		menuImages := handlers.NewMenuImagesAPI(menuImageService, hub)
 		menuGroup := api.Group("/menu")
 		{
 			// categories
@@ -224,6 +248,8 @@ func main() {
 			menuGroup.GET("/categories", mm.ListCategories)
 			menuGroup.PUT("/categories/:id", handlers.RequireAdminOrManager(), mm.UpdateCategory)
 			menuGroup.DELETE("/categories/:id", handlers.RequireAdminOrManager(), mm.DeleteCategory)
+			menuGroup.POST("/categories/:id/image", handlers.RequireAdminOrManager(), menuImages.UploadCategoryImage)
+			menuGroup.DELETE("/categories/:id/image", handlers.RequireAdminOrManager(), menuImages.DeleteCategoryImage)
 			// items
 			menuGroup.POST("/items", handlers.RequireAdminOrManager(), mm.CreateItem)
 			menuGroup.GET("/items/:id", mm.GetItem)
@@ -231,6 +257,37 @@ func main() {
 			menuGroup.PUT("/items/:id", handlers.RequireAdminOrManager(), mm.UpdateItem)
 			menuGroup.PATCH("/items/:id/availability", handlers.RequireStaff(), mm.UpdateAvailability)
 			menuGroup.DELETE("/items/:id", handlers.RequireAdminOrManager(), mm.DeleteItem)
+			menuGroup.POST("/items/:id/image", handlers.RequireAdminOrManager(), menuImages.UploadItemImage)
+			menuGroup.DELETE("/items/:id/image", handlers.RequireAdminOrManager(), menuImages.DeleteItemImage)
+			// branch prices and availability
+			menuGroup.PUT("/items/:id/branches/:restaurant_id", handlers.RequireAdminOrManager(), mm.SetItemOverride)
+			menuGroup.DELETE("/items/:id/branches/:restaurant_id", handlers.RequireAdminOrManager(), mm.DeleteItemOverride)
+			menuGroup.GET("/branches/:restaurant_id/overrides", handlers.RequireAdminOrManager(), mm.ListItemOverrides)
+			menuGroup.GET("/branches/:restaurant_id/preview", handlers.RequireAdminOrManager(), mm.PreviewMenu)
+			menuGroup.POST("/branches/:restaurant_id/import", handlers.RequireAdminOrManager(), mm.ImportMenu)
+			menuGroup.GET("/branches/:restaurant_id/export", handlers.RequireAdminOrManager(), mm.ExportMenu)
+
+			menuGroup.POST("/branches/:restaurant_id/versions", handlers.RequireAdminOrManager(), mm.CreateMenuDraft)
+			menuGroup.GET("/branches/:restaurant_id/versions", handlers.RequireAdminOrManager(), mm.ListMenuVersions)
+			menuGroup.GET("/versions/:id", handlers.RequireAdminOrManager(), mm.GetMenuVersion)
+			menuGroup.PUT("/versions/:id", handlers.RequireAdminOrManager(), mm.UpdateMenuDraft)
+			menuGroup.DELETE("/versions/:id", handlers.RequireAdminOrManager(), mm.DeleteMenuVersion)
+			menuGroup.GET("/versions/:id/preview", handlers.RequireAdminOrManager(), mm.PreviewMenuVersion)
+			menuGroup.POST("/versions/:id/publish", handlers.RequireAdminOrManager(), mm.PublishMenuVersion)
+			menuGroup.POST("/versions/:id/rollback", handlers.RequireAdminOrManager(), mm.RollbackMenu)
+
+			menuGroup.PUT("/translations", handlers.RequireAdminOrManager(), mm.SetTranslations)
+			menuGroup.GET("/translations/:entity_type/:entity_id", handlers.RequireAdminOrManager(), mm.ListTranslations)
+			menuGroup.DELETE("/translations/:entity_type/:entity_id/:field/:locale", handlers.RequireAdminOrManager(), mm.DeleteTranslation)
+			menuGroup.GET("/branches/:restaurant_id/translations/missing", handlers.RequireAdminOrManager(), mm.MissingTranslations)
+			// schedules (dayparts) and holidays
+			menuGroup.POST("/schedules", handlers.RequireAdminOrManager(), mm.CreateSchedule)
+			menuGroup.GET("/schedules", handlers.RequireAdminOrManager(), mm.ListSchedules)
+			menuGroup.PUT("/schedules/:id", handlers.RequireAdminOrManager(), mm.UpdateSchedule)
+			menuGroup.DELETE("/schedules/:id", handlers.RequireAdminOrManager(), mm.DeleteSchedule)
+			menuGroup.POST("/branches/:restaurant_id/holidays", handlers.RequireAdminOrManager(), mm.AddHoliday)
+			menuGroup.GET("/branches/:restaurant_id/holidays", handlers.RequireAdminOrManager(), mm.ListHolidays)
+			menuGroup.DELETE("/branches/:restaurant_id/holidays/:date", handlers.RequireAdminOrManager(), mm.DeleteHoliday)
 			// variants
 			menuGroup.POST("/items/:id/variants", handlers.RequireAdminOrManager(), mm.CreateVariant)
 			menuGroup.PUT("/variants/:id", handlers.RequireAdminOrManager(), mm.UpdateVariant)
@@ -239,6 +296,20 @@ func main() {
 			menuGroup.POST("/items/:id/addons", handlers.RequireAdminOrManager(), mm.CreateAddon)
 			menuGroup.PUT("/addons/:id", handlers.RequireAdminOrManager(), mm.UpdateAddon)
 			menuGroup.DELETE("/addons/:id", handlers.RequireAdminOrManager(), mm.DeleteAddon)
+			// modifier groups and their options
+			menuGroup.GET("/items/:id/modifier-groups", mm.ListModifierGroups)
+			menuGroup.POST("/items/:id/modifier-groups", handlers.RequireAdminOrManager(), mm.CreateModifierGroup)
+			menuGroup.PUT("/modifier-groups/:id", handlers.RequireAdminOrManager(), mm.UpdateModifierGroup)
+			menuGroup.DELETE("/modifier-groups/:id", handlers.RequireAdminOrManager(), mm.DeleteModifierGroup)
+			menuGroup.POST("/modifier-groups/:id/options", handlers.RequireAdminOrManager(), mm.CreateModifierOption)
+			menuGroup.PUT("/modifier-options/:id", handlers.RequireAdminOrManager(), mm.UpdateModifierOption)
+			menuGroup.DELETE("/modifier-options/:id", handlers.RequireAdminOrManager(), mm.DeleteModifierOption)
+
+			menuGroup.POST("/combos", handlers.RequireAdminOrManager(), mm.CreateCombo)
+			menuGroup.GET("/combos", mm.ListCombos)
+			menuGroup.GET("/combos/:id", mm.GetCombo)
+			menuGroup.PUT("/combos/:id", handlers.RequireAdminOrManager(), mm.UpdateCombo)
+			menuGroup.DELETE("/combos/:id", handlers.RequireAdminOrManager(), mm.DeleteCombo)
 		}
 		// Protect admin routes
 		admin := api.Group("")
@@ -287,6 +358,8 @@ func main() {
 			// profile
 			customer.GET("/me", customerAPI.Me)
 			customer.PATCH("/me", customerAPI.UpdateMe)
+			customer.GET("/me/preferences", accountHandler.GetPreferences)
+			customer.PUT("/me/preferences", accountHandler.UpdatePreferences)
 			// loyalty & promo
 			customer.GET("/loyalty", customerAPI.GetLoyalty)
 			customer.POST("/loyalty/redeem", customerAPI.RedeemPoints)
@@ -316,6 +389,9 @@ func main() {
 			staff.POST("/branches/:branchId/orders/:orderId/split", staffAPI.SplitOrder)
 			staff.POST("/branches/:branchId/orders/merge", staffAPI.MergeOrders)
 			staff.POST("/branches/:branchId/orders/:orderId/tip", staffAPI.AddTip)
+			// courses
+			staff.POST("/branches/:branchId/orders/:orderId/courses/:course/fire", staffAPI.FireCourse)
+			staff.POST("/branches/:branchId/orders/:orderId/courses/:course/hold", staffAPI.HoldCourse)
 		}
 
 		// Kitchen (admin/staff scoped)
@@ -324,6 +400,17 @@ func main() {
 		{
 			kitchen.GET("/orders", kitchenAPI.ListPending)
 			kitchen.PUT("/orders/:id/status", kitchenAPI.UpdateStatus)
+			kitchen.GET("/stations", kitchenAPI.ListStations)
+			kitchen.POST("/stations", auth.RequireAnyRole("manager", "admin"), kitchenAPI.CreateStation)
+			kitchen.GET("/stations/:id/routes", kitchenAPI.ListStationRoutes)
+			kitchen.PUT("/stations/:id/routes", auth.RequireAnyRole("manager", "admin"), kitchenAPI.SetStationRoute)
+			kitchen.DELETE("/stations/:id/routes/:route_id", auth.RequireAnyRole("manager", "admin"), kitchenAPI.DeleteStationRoute)
+			kitchen.GET("/tickets", kitchenAPI.ListTickets)
+			kitchen.PUT("/tickets/:id/status", kitchenAPI.UpdateTicketStatus)
+			kitchen.POST("/tickets/:id/bump", kitchenAPI.BumpTicket)
+			kitchen.POST("/tickets/:id/recall", kitchenAPI.RecallTicket)
+			kitchen.GET("/metrics", auth.RequireAnyRole("manager", "admin"), kitchenAPI.Metrics)
+			kitchen.GET("/expo", kitchenAPI.Expo)
 			kitchen.POST("/notifications/send", notificationsAPI.SendNotification)
 		}
 
@@ -414,7 +501,8 @@ func main() {
 		}
 	}
 
-	// Websocket endpoint (simple)
+	// Websocket endpoint (simple). Kitchen displays connect with ?role=kitchen&station=<id> to get only
+	// their station's tickets; the pass connects with ?role=expo.
 	router.GET("/ws", func(c *gin.Context) {
 		websocket.HandleWebSocket(hub, c.Writer, c.Request)
 	})
@@ -432,7 +520,7 @@ func main() {
 		websocket.HandleWebSocket(hub, c.Writer, c.Request)
 	})
 
-	// Serve static files (optional; Next.js runs separately)
+	// Serve static files (optional; Next.js runs separately), uploaded menu images included
 	router.Static("/static", "./web/static")
 
 	// Swagger documentation
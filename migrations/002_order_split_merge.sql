@@ -0,0 +1,8 @@
+-- Links between split/merged orders
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS parent_order_id TEXT REFERENCES orders(id);
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS split_type TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS merged_into_order_id TEXT REFERENCES orders(id);
+
+CREATE INDEX IF NOT EXISTS idx_orders_parent_order_id ON orders(parent_order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_merged_into_order_id ON orders(merged_into_order_id);
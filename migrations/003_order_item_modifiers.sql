@@ -0,0 +1,6 @@
+-- Variant and add-on snapshots on order lines
+
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS variant_id TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS variant_name TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS variant_price_delta REAL DEFAULT 0;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS addons JSONB DEFAULT '[]';
@@ -0,0 +1,19 @@
+-- Pricing breakdown on orders and the restaurant settings that drive it
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS restaurant_id TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS discount_id TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS subtotal REAL DEFAULT 0;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS discount_amount REAL DEFAULT 0;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS service_charge REAL DEFAULT 0;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS tax_amount REAL DEFAULT 0;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN DEFAULT FALSE;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS rounding_adjustment REAL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant_id ON orders(restaurant_id);
+
+-- existing orders had no adjustments, so their subtotal is their total
+UPDATE orders SET subtotal = total_amount WHERE subtotal = 0;
+
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN DEFAULT FALSE;
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS service_charge_rate REAL DEFAULT 0;
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS rounding_increment REAL DEFAULT 0;
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS rounding_mode TEXT;
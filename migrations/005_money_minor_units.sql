@@ -0,0 +1,34 @@
+-- Store every monetary amount as integer minor units (cents) and record the currency
+-- of orders, payments and refunds. Only columns that still hold major units
+-- (REAL/NUMERIC) are converted, so the script is safe to run more than once.
+
+DO $$
+DECLARE c RECORD;
+BEGIN
+    FOR c IN
+        SELECT table_name, column_name FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type IN ('real', 'double precision', 'numeric')
+          AND (table_name, column_name) IN (
+            ('orders', 'total_amount'), ('orders', 'subtotal'), ('orders', 'discount_amount'),
+            ('orders', 'service_charge'), ('orders', 'tax_amount'), ('orders', 'rounding_adjustment'),
+            ('order_items', 'price'), ('order_items', 'total_price'), ('order_items', 'variant_price_delta'),
+            ('payments', 'amount'), ('payments', 'refunded_amount'), ('refunds', 'amount'),
+            ('payment_tips', 'amount'), ('discount_usages', 'amount'), ('restaurants', 'rounding_increment'),
+            ('telebirr_orders', 'amount'), ('telebirr_notifications', 'total_amount'),
+            ('telebirr_c2_b_orders', 'total_amount'), ('telebirr_c2_b_notifications', 'total_amount'))
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE BIGINT USING ROUND(%I * 100)::BIGINT',
+            c.table_name, c.column_name, c.column_name);
+    END LOOP;
+END $$;
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS currency TEXT;
+UPDATE orders o SET currency = r.currency FROM restaurants r WHERE o.restaurant_id = r.id AND o.currency IS NULL;
+UPDATE orders SET currency = 'ETB' WHERE currency IS NULL;
+
+ALTER TABLE IF EXISTS payments ADD COLUMN IF NOT EXISTS currency TEXT;
+UPDATE payments p SET currency = o.currency FROM orders o WHERE p.order_id = o.id AND p.currency IS NULL;
+
+ALTER TABLE IF EXISTS refunds ADD COLUMN IF NOT EXISTS currency TEXT;
+UPDATE refunds f SET currency = p.currency FROM payments p WHERE f.payment_id = p.id AND f.currency IS NULL;
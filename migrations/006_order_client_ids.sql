@@ -0,0 +1,6 @@
+-- Client-generated identifiers for orders synced from offline devices
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS client_order_id TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS client_created_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_customer_client_order_id ON orders(customer_id, client_order_id) WHERE client_order_id IS NOT NULL;
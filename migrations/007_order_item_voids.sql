@@ -0,0 +1,7 @@
+-- Voided order lines stay on the order for the record but no longer count towards totals
+
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS voided_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS void_reason TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS void_note TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS voided_by TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS void_approved_by TEXT;
@@ -0,0 +1,13 @@
+-- Fulfilment type per order: dine-in at a table, takeaway (optionally scheduled) or delivery
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS type TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS table_id TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS pickup_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS delivery_address TEXT;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS delivery_fee BIGINT NOT NULL DEFAULT 0;
+
+UPDATE orders SET type = CASE WHEN session_id IS NOT NULL THEN 'dine_in' ELSE 'takeaway' END WHERE type IS NULL;
+
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS delivery_fee BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_orders_type_pickup_at ON orders(type, pickup_at);
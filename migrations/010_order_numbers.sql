@@ -0,0 +1,14 @@
+-- Short ticket numbers that restart every business day per restaurant
+
+CREATE TABLE IF NOT EXISTS order_number_counters (
+    restaurant_id TEXT NOT NULL,
+    business_date DATE NOT NULL,
+    last_number INTEGER NOT NULL,
+    PRIMARY KEY (restaurant_id, business_date)
+);
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS order_number INTEGER;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS business_date DATE;
+
+-- split children share their parent's number, so this is a lookup index rather than a unique one
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant_day_number ON orders(restaurant_id, business_date, order_number);
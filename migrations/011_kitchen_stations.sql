@@ -0,0 +1,38 @@
+-- Kitchen stations, menu routing and per-station tickets
+
+CREATE TABLE IF NOT EXISTS kitchen_stations (
+    id TEXT PRIMARY KEY,
+    restaurant_id TEXT,
+    name TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_kitchen_stations_restaurant_id ON kitchen_stations(restaurant_id);
+
+-- each route sets exactly one of category and menu_item_id
+CREATE TABLE IF NOT EXISTS kitchen_station_routes (
+    id TEXT PRIMARY KEY,
+    station_id TEXT NOT NULL REFERENCES kitchen_stations(id) ON DELETE CASCADE,
+    category TEXT,
+    menu_item_id TEXT,
+    CHECK ((category IS NULL) <> (menu_item_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_kitchen_station_routes_station_id ON kitchen_station_routes(station_id);
+CREATE INDEX IF NOT EXISTS idx_kitchen_station_routes_category ON kitchen_station_routes(category);
+CREATE INDEX IF NOT EXISTS idx_kitchen_station_routes_menu_item_id ON kitchen_station_routes(menu_item_id);
+
+CREATE TABLE IF NOT EXISTS kitchen_tickets (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id),
+    station_id TEXT REFERENCES kitchen_stations(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'queued',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    bumped_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_kitchen_tickets_order_id ON kitchen_tickets(order_id);
+CREATE INDEX IF NOT EXISTS idx_kitchen_tickets_station_status ON kitchen_tickets(station_id, status);
+
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS ticket_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_order_items_ticket_id ON order_items(ticket_id);
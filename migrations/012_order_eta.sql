@@ -0,0 +1,34 @@
+-- Automatic ETAs: status timestamps, learned per-item prep times and kitchen capacity
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS estimated_ready_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS eta_manual BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS preparing_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS ready_at TIMESTAMPTZ;
+
+ALTER TABLE IF EXISTS restaurants ADD COLUMN IF NOT EXISTS kitchen_capacity INTEGER NOT NULL DEFAULT 4;
+
+CREATE TABLE IF NOT EXISTS menu_item_prep_profiles (
+    menu_item_id TEXT PRIMARY KEY,
+    avg_seconds DOUBLE PRECISION NOT NULL,
+    samples INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- backfill status times from the audit trail so existing history seeds the profiles
+UPDATE orders o SET confirmed_at = a.at FROM (
+    SELECT order_id, MIN(created_at) AS at FROM order_audits
+    WHERE action = 'status_changed' AND details::jsonb->>'to' = 'confirmed' GROUP BY order_id
+) a WHERE o.id = a.order_id AND o.confirmed_at IS NULL;
+
+UPDATE orders o SET ready_at = a.at FROM (
+    SELECT order_id, MIN(created_at) AS at FROM order_audits
+    WHERE action = 'status_changed' AND details::jsonb->>'to' = 'ready' GROUP BY order_id
+) a WHERE o.id = a.order_id AND o.ready_at IS NULL;
+
+INSERT INTO menu_item_prep_profiles (menu_item_id, avg_seconds, samples, updated_at)
+SELECT oi.menu_item_id, AVG(EXTRACT(EPOCH FROM o.ready_at - o.confirmed_at)), COUNT(*), NOW()
+FROM orders o JOIN order_items oi ON oi.order_id = o.id
+WHERE o.confirmed_at IS NOT NULL AND o.ready_at > o.confirmed_at AND oi.voided_at IS NULL
+GROUP BY oi.menu_item_id
+ON CONFLICT (menu_item_id) DO NOTHING;
@@ -0,0 +1,11 @@
+-- Dine-in courses: lines and tickets carry a course, and tickets are held until fired
+
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS course TEXT;
+
+ALTER TABLE IF EXISTS kitchen_tickets ADD COLUMN IF NOT EXISTS course TEXT;
+ALTER TABLE IF EXISTS kitchen_tickets ADD COLUMN IF NOT EXISTS fired_at TIMESTAMPTZ;
+
+-- tickets created before courses existed all went straight to the kitchen
+UPDATE kitchen_tickets SET fired_at = created_at WHERE fired_at IS NULL AND course IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_kitchen_tickets_order_course ON kitchen_tickets(order_id, course);
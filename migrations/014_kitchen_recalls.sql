@@ -0,0 +1,6 @@
+-- Ticket recalls and indexes for kitchen metrics read from the order audit log
+
+ALTER TABLE IF EXISTS kitchen_tickets ADD COLUMN IF NOT EXISTS recalled_at TIMESTAMPTZ;
+ALTER TABLE IF EXISTS kitchen_tickets ADD COLUMN IF NOT EXISTS recall_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_order_audits_action_created_at ON order_audits(action, created_at);
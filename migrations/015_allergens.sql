@@ -0,0 +1,18 @@
+-- Allergen and dietary tags on menu items, variants and add-ons, saved customer allergies,
+-- and the allergens snapshotted onto order lines. Tag lists are JSON arrays of strings.
+
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS dietary TEXT;
+
+ALTER TABLE IF EXISTS menu_variants ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS menu_variants ADD COLUMN IF NOT EXISTS dietary TEXT;
+
+ALTER TABLE IF EXISTS menu_addons ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS menu_addons ADD COLUMN IF NOT EXISTS dietary TEXT;
+
+ALTER TABLE IF EXISTS accounts ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS accounts ADD COLUMN IF NOT EXISTS dietary TEXT;
+
+-- allergen_alerts are the line's allergens the customer declared and acknowledged
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS allergen_alerts TEXT;
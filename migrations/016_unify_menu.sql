@@ -0,0 +1,56 @@
+-- One menu: menu_items becomes restaurant-scoped and linked to menu_categories, and the items
+-- managers created through the old GORM model (menu_item_gorms) are merged into it so they
+-- can be ordered. Items with no restaurant stay shared by every restaurant.
+
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS restaurant_id TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS category_id TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS allergens TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS dietary TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS menu_categories (
+    id TEXT PRIMARY KEY,
+    restaurant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_menu_categories_restaurant_id ON menu_categories(restaurant_id);
+
+-- manager-created items keep their ids, so variants, add-ons and favorites still point at them
+DO $$
+BEGIN
+    IF to_regclass('menu_item_gorms') IS NOT NULL THEN
+        INSERT INTO menu_items (id, restaurant_id, category_id, name, description, price, category, available, image_url, created_at, updated_at, deleted_at)
+        SELECT g.id, g.restaurant_id, g.category_id, g.name, g.description, g.price, c.name, g.available, g.image_url, g.created_at, g.updated_at, g.deleted_at
+        FROM menu_item_gorms g
+        LEFT JOIN menu_categories c ON c.id = g.category_id
+        ON CONFLICT (id) DO NOTHING;
+
+        IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'menu_item_gorms' AND column_name = 'allergens') THEN
+            UPDATE menu_items m SET allergens = g.allergens, dietary = g.dietary
+            FROM menu_item_gorms g
+            WHERE m.id = g.id AND m.allergens IS NULL;
+        END IF;
+    END IF;
+END $$;
+
+-- restaurant-scoped items that only had a free-text category get a category row to link to
+INSERT INTO menu_categories (id, restaurant_id, name)
+SELECT md5(m.restaurant_id || ':' || m.category), m.restaurant_id, m.category
+FROM (SELECT DISTINCT restaurant_id, category FROM menu_items
+      WHERE restaurant_id IS NOT NULL AND category IS NOT NULL AND category <> '' AND category_id IS NULL) m
+WHERE NOT EXISTS (
+    SELECT 1 FROM menu_categories c
+    WHERE c.restaurant_id = m.restaurant_id AND c.name = m.category AND c.deleted_at IS NULL
+);
+
+UPDATE menu_items m SET category_id = c.id
+FROM menu_categories c
+WHERE m.category_id IS NULL AND m.restaurant_id = c.restaurant_id AND m.category = c.name AND c.deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_restaurant_id ON menu_items(restaurant_id);
+CREATE INDEX IF NOT EXISTS idx_menu_items_category_id ON menu_items(category_id);
@@ -0,0 +1,11 @@
+-- Per-branch price and availability for the menu items a branch offers. NULL follows the item.
+
+CREATE TABLE IF NOT EXISTS menu_item_overrides (
+    restaurant_id TEXT NOT NULL,
+    menu_item_id TEXT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    price NUMERIC,
+    available BOOLEAN,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (restaurant_id, menu_item_id)
+);
+CREATE INDEX IF NOT EXISTS idx_menu_item_overrides_menu_item_id ON menu_item_overrides(menu_item_id);
@@ -0,0 +1,26 @@
+-- Menu schedules (dayparts) for categories and items, and restaurant holidays. Dates are
+-- YYYY-MM-DD in the restaurant's time zone; windows are a JSON array of {days, start, end}.
+
+CREATE TABLE IF NOT EXISTS menu_schedules (
+    id TEXT PRIMARY KEY,
+    restaurant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    windows TEXT NOT NULL DEFAULT '[]',
+    start_date TEXT,
+    end_date TEXT,
+    holidays TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_menu_schedules_restaurant_id ON menu_schedules(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS restaurant_holidays (
+    restaurant_id TEXT NOT NULL,
+    date TEXT NOT NULL,
+    name TEXT,
+    PRIMARY KEY (restaurant_id, date)
+);
+
+ALTER TABLE IF EXISTS menu_categories ADD COLUMN IF NOT EXISTS schedule_id TEXT;
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS schedule_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_menu_items_schedule_id ON menu_items(schedule_id);
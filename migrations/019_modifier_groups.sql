@@ -0,0 +1,38 @@
+-- Modifier groups with selection rules on menu items, their options, and the modifiers
+-- snapshotted onto order lines. A group nested under an option keeps its item's item_id.
+
+CREATE TABLE IF NOT EXISTS modifier_groups (
+    id TEXT PRIMARY KEY,
+    item_id TEXT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    parent_option_id TEXT,
+    name TEXT NOT NULL,
+    min_select INTEGER NOT NULL DEFAULT 0,
+    max_select INTEGER NOT NULL DEFAULT 0,
+    free_quantity INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_modifier_groups_item_id ON modifier_groups(item_id);
+
+CREATE TABLE IF NOT EXISTS modifier_options (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    price_delta NUMERIC NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    allergens TEXT,
+    dietary TEXT,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_modifier_options_group_id ON modifier_options(group_id);
+
+-- deleting an option removes the groups nested under it
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'modifier_groups_parent_option_id_fkey') THEN
+        ALTER TABLE modifier_groups ADD CONSTRAINT modifier_groups_parent_option_id_fkey
+            FOREIGN KEY (parent_option_id) REFERENCES modifier_options(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_modifier_groups_parent_option_id ON modifier_groups(parent_option_id);
+
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS modifiers TEXT;
@@ -0,0 +1,40 @@
+-- Combo meals: slots filled with one of their menu item choices. An ordered combo is one priced
+-- line whose components are order lines pointing back at it with their share of the price.
+
+CREATE TABLE IF NOT EXISTS menu_combos (
+    id TEXT PRIMARY KEY,
+    restaurant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    price NUMERIC NOT NULL,
+    available BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_menu_combos_restaurant_id ON menu_combos(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS combo_slots (
+    id TEXT PRIMARY KEY,
+    combo_id TEXT NOT NULL REFERENCES menu_combos(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_combo_slots_combo_id ON combo_slots(combo_id);
+
+CREATE TABLE IF NOT EXISTS combo_slot_items (
+    slot_id TEXT NOT NULL REFERENCES combo_slots(id) ON DELETE CASCADE,
+    menu_item_id TEXT NOT NULL REFERENCES menu_items(id),
+    upcharge NUMERIC NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (slot_id, menu_item_id)
+);
+
+-- combo lines have no menu item of their own
+ALTER TABLE IF EXISTS order_items ALTER COLUMN menu_item_id DROP NOT NULL;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS combo_id TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS parent_item_id TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS combo_slot TEXT;
+ALTER TABLE IF EXISTS order_items ADD COLUMN IF NOT EXISTS allocated_total BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_order_items_parent_item_id ON order_items(parent_item_id);
@@ -0,0 +1,22 @@
+-- Menu versions: numbered snapshots of a restaurant's own menu, held as import rows (JSON).
+-- At most one version per restaurant is published; orders record it.
+
+CREATE TABLE IF NOT EXISTS menu_versions (
+    id TEXT PRIMARY KEY,
+    restaurant_id TEXT NOT NULL,
+    number INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'draft',
+    note TEXT,
+    based_on TEXT,
+    rows TEXT NOT NULL DEFAULT '[]',
+    publish_at TIMESTAMPTZ,
+    published_at TIMESTAMPTZ,
+    created_by TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (restaurant_id, number)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_menu_versions_published ON menu_versions(restaurant_id) WHERE status = 'published';
+CREATE INDEX IF NOT EXISTS idx_menu_versions_scheduled ON menu_versions(publish_at) WHERE status = 'scheduled';
+
+ALTER TABLE IF EXISTS orders ADD COLUMN IF NOT EXISTS menu_version_id TEXT;
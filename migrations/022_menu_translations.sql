@@ -0,0 +1,21 @@
+-- Menu translations keyed by entry, field and locale, replacing the Amharic-only name_am and
+-- description_am columns. The columns stay for older clients; their text is copied in as "am".
+
+CREATE TABLE IF NOT EXISTS menu_translations (
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    field TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity_type, entity_id, field, locale)
+);
+CREATE INDEX IF NOT EXISTS idx_menu_translations_locale ON menu_translations(locale);
+
+INSERT INTO menu_translations (entity_type, entity_id, field, locale, value)
+SELECT 'item', id, 'name', 'am', name_am FROM menu_items WHERE COALESCE(name_am, '') <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO menu_translations (entity_type, entity_id, field, locale, value)
+SELECT 'item', id, 'description', 'am', description_am FROM menu_items WHERE COALESCE(description_am, '') <> ''
+ON CONFLICT DO NOTHING;
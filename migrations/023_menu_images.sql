@@ -0,0 +1,6 @@
+-- Uploaded menu images: the URL of each stored size, with the full size also in image_url.
+-- Categories get images too.
+
+ALTER TABLE IF EXISTS menu_items ADD COLUMN IF NOT EXISTS image_variants TEXT;
+ALTER TABLE IF EXISTS menu_categories ADD COLUMN IF NOT EXISTS image_url TEXT;
+ALTER TABLE IF EXISTS menu_categories ADD COLUMN IF NOT EXISTS image_variants TEXT;
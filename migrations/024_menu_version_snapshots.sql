@@ -0,0 +1,5 @@
+-- Menu versions keep the whole branch menu they publish, not only its import rows: schedules,
+-- holidays, modifier groups, combos, branch prices and translations too, each table's rows as
+-- JSON. Versions saved before this have no snapshot and publish from their rows.
+
+ALTER TABLE IF EXISTS menu_versions ADD COLUMN IF NOT EXISTS snapshot TEXT;